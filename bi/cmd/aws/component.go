@@ -0,0 +1,20 @@
+package aws
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var componentCmd = &cobra.Command{
+	Use:   "component",
+	Short: "Create or destroy individual components of a cluster on AWS",
+	Long: `A cluster on AWS EKS is made up of separate component stacks
+(vpc, gateway, peers, cluster, lbcontroller, karpenter and postgres) that
+are normally all created or destroyed together.
+
+These commands work on individual components, reusing the outputs
+of the other components from their existing stacks.`,
+}
+
+func init() {
+	awsCmd.AddCommand(componentCmd)
+}
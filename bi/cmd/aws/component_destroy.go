@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"log/slog"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var componentDestroyCmd = &cobra.Command{
+	Use:   "destroy [install-slug|install-spec-url|install-spec-file] [component...]",
+	Short: "Destroy components of a cluster on AWS",
+	Long: `Destroy only the named components. A component can't be destroyed
+while any component that depends on it still exists, e.g. the vpc
+can't be destroyed while the cluster exists.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+		names := args[1:]
+
+		ctx := cmd.Context()
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		var progressReporter *util.ProgressReporter
+		if log.Level == slog.LevelWarn {
+			progressReporter = util.NewProgressReporter()
+			defer progressReporter.Shutdown()
+		}
+
+		return env.DestroyKubeComponents(ctx, names, progressReporter)
+	},
+}
+
+func init() {
+	componentCmd.AddCommand(componentDestroyCmd)
+}
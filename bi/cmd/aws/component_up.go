@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"log/slog"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var componentUpCmd = &cobra.Command{
+	Use:   "up [install-slug|install-spec-url|install-spec-file] [component...]",
+	Short: "Create or update components of a cluster on AWS",
+	Long: `Create or update only the named components. Every component that
+they depend on must already exist.
+
+This only changes the AWS resources. Use "bi start --component" to
+also update the installation running in the cluster.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+		names := args[1:]
+
+		ctx := cmd.Context()
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		var progressReporter *util.ProgressReporter
+		if log.Level == slog.LevelWarn {
+			progressReporter = util.NewProgressReporter()
+			defer progressReporter.Shutdown()
+		}
+
+		return env.CreateKubeComponents(ctx, names, progressReporter)
+	},
+}
+
+func init() {
+	componentCmd.AddCommand(componentUpCmd)
+}
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"bi/pkg/cluster/eks"
+	"bi/pkg/installs"
+
+	"github.com/spf13/cobra"
+)
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate [install-slug|install-spec-url|install-spec-file]",
+	Short: "Estimate the monthly cost of a cluster on AWS",
+	Long: `Estimate the monthly cost of the AWS resources that starting the
+install would create, broken down by component. Nothing is created and
+AWS isn't contacted, the config is priced using a price table built
+into bi.
+
+The prices are on-demand list prices in USD. Spot node groups are
+priced at on-demand rates, and usage based charges (data transfer, NAT
+gateway processing, logs) and nodes launched by karpenter aren't
+included.
+
+Use --prices to supply an up to date price table, any regions in it
+replace the built in ones.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		if err := checkChangeSummaryFormat(format); err != nil {
+			return err
+		}
+
+		pricesPath, err := cmd.Flags().GetString("prices")
+		if err != nil {
+			return err
+		}
+
+		backupGB, err := cmd.Flags().GetFloat64("backup-gb")
+		if err != nil {
+			return err
+		}
+
+		prices, err := eks.LoadPriceTable(pricesPath)
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		estimates, err := env.EstimateKubeProvider(prices, eks.EstimateOptions{BackupGB: backupGB})
+		if err != nil {
+			return err
+		}
+
+		return printCostEstimates(estimates, format)
+	},
+}
+
+func printCostEstimates(estimates []eks.CostEstimate, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(estimates)
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(w, "COMPONENT\tRESOURCE\tQUANTITY\tMONTHLY")
+		fmt.Fprintln(w, "---------\t--------\t--------\t-------")
+
+		var total float64
+		for _, e := range estimates {
+			for _, item := range e.Items {
+				fmt.Fprintf(w, "%s\t%s\t%g %s\t$%.2f\n", e.Component, item.Description, item.Quantity, item.Unit, item.Monthly)
+			}
+			fmt.Fprintf(w, "%s\ttotal\t\t$%.2f\n", e.Component, e.Monthly)
+			total += e.Monthly
+		}
+
+		fmt.Fprintf(w, "\t\t\t\nTOTAL\t\t\t$%.2f\n", total)
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format: %s (supported: text, json)", format)
+	}
+}
+
+func init() {
+	estimateCmd.Flags().StringP("format", "f", "text", "Output format: 'text' for human-readable table or 'json' for machine-readable JSON")
+	estimateCmd.Flags().String("prices", "", "Path to a JSON price table to use instead of the built in one")
+	estimateCmd.Flags().Float64("backup-gb", 10, "Expected size of the postgres backups in GB")
+	awsCmd.AddCommand(estimateCmd)
+}
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"fmt"
+
+	"bi/pkg/cluster"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	biviper "bi/pkg/viper"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateStateCmd = &cobra.Command{
+	Use:   "migrate-state [install-slug|install-spec-url|install-spec-file]",
+	Short: "Move cluster state to a different pulumi backend",
+	Long: `Move the pulumi state for a cluster created on AWS EKS
+from one backend to another e.g. from the local state directory
+to an S3 bucket.
+
+The destination defaults to the configured backend (pulumi.backend
+in bi.yaml). If the install state is still encrypted with the legacy
+passphrase, it is re-encrypted with the configured or a generated one.
+A remote destination needs a configured passphrase (pulumi.passphrase
+or pulumi.passphrase-file), a generated one is only kept locally.
+
+A configured passphrase is also used to read the source state.
+If the install predates passphrase support but a passphrase has
+since been configured, pass --from-passphrase-file pointing at a
+file containing the legacy passphrase.
+
+Once migrated, point pulumi.backend at the destination so that
+other commands use it.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		fromURL, err := cmd.Flags().GetString("from")
+		if err != nil {
+			return err
+		}
+
+		fromPassphraseFile, err := cmd.Flags().GetString("from-passphrase-file")
+		if err != nil {
+			return err
+		}
+
+		to := cluster.PulumiBackendConfig(biviper.PulumiBackend())
+		if cmd.Flags().Changed("to") {
+			to.URL, err = cmd.Flags().GetString("to")
+			if err != nil {
+				return err
+			}
+		}
+
+		from := cluster.PulumiBackendConfig{URL: fromURL, PassphraseFile: fromPassphraseFile}
+		if from.PassphraseFile == "" {
+			from.Passphrase = to.Passphrase
+			from.PassphraseFile = to.PassphraseFile
+		}
+
+		if err := cluster.MigratePulumiState(ctx, env.Spec, biviper.EKSConfig(), from, to); err != nil {
+			return fmt.Errorf("failed to migrate state: %w", err)
+		}
+
+		fmt.Printf("Migrated state for %s to %s\n", env.Slug, displayBackendURL(to.URL))
+
+		return nil
+	},
+}
+
+func displayBackendURL(url string) string {
+	if url == "" {
+		return "the local state directory"
+	}
+	return url
+}
+
+func init() {
+	migrateStateCmd.Flags().String("from", "", "Backend URL to migrate from (default is the local state directory)")
+	migrateStateCmd.Flags().String("from-passphrase-file", "", "File containing the passphrase for the source backend (default is the configured passphrase)")
+	migrateStateCmd.Flags().String("to", "", "Backend URL to migrate to (default is the configured backend)")
+	awsCmd.AddCommand(migrateStateCmd)
+}
@@ -7,6 +7,7 @@ import (
 	"bi/pkg/cluster"
 	"bi/pkg/installs"
 	"bi/pkg/log"
+	biviper "bi/pkg/viper"
 
 	"github.com/spf13/cobra"
 )
@@ -34,7 +35,7 @@ var outputsCmd = &cobra.Command{
 			return err
 		}
 
-		p := cluster.NewPulumiProvider(env.Spec)
+		p := cluster.NewPulumiProvider(env.Spec, biviper.EKSConfig(), cluster.PulumiBackendConfig(biviper.PulumiBackend()), env.WireGuardGatewayStatePath())
 
 		if err := p.Init(ctx); err != nil {
 			return err
@@ -0,0 +1,58 @@
+package aws
+
+import (
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview [install-slug|install-spec-url|install-spec-file]",
+	Short: "Preview the changes to a cluster on AWS",
+	Long: `Show what starting the installation would create, update,
+replace or delete in each component stack of a cluster created
+on AWS EKS. Nothing is changed.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		if err := checkChangeSummaryFormat(format); err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		summaries, err := env.PreviewKubeProvider(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printChangeSummaries(cmd.OutOrStdout(), summaries, format)
+	},
+}
+
+func init() {
+	previewCmd.Flags().StringP("format", "f", "text", "Output format: 'text' for human-readable table or 'json' for machine-readable JSON")
+	awsCmd.AddCommand(previewCmd)
+}
@@ -0,0 +1,58 @@
+package aws
+
+import (
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh [install-slug|install-spec-url|install-spec-file]",
+	Short: "Detect drift in a cluster on AWS",
+	Long: `Refresh the state of each component stack of a cluster created
+on AWS EKS from what actually exists in AWS, and show what had drifted
+since the last run.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		if err := checkChangeSummaryFormat(format); err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		summaries, err := env.RefreshKubeProvider(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printChangeSummaries(cmd.OutOrStdout(), summaries, format)
+	},
+}
+
+func init() {
+	refreshCmd.Flags().StringP("format", "f", "text", "Output format: 'text' for human-readable table or 'json' for machine-readable JSON")
+	awsCmd.AddCommand(refreshCmd)
+}
@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"bi/pkg/cluster/eks"
+)
+
+var changeSummaryFormats = []string{"text", "json"}
+
+func checkChangeSummaryFormat(format string) error {
+	if !slices.Contains(changeSummaryFormats, strings.ToLower(format)) {
+		return fmt.Errorf("unsupported output format: %s (supported: text, json)", format)
+	}
+	return nil
+}
+
+func printChangeSummaries(out io.Writer, summaries []eks.ChangeSummary, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	case "text":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(w, "COMPONENT\tCREATE\tUPDATE\tREPLACE\tDELETE\tSAME")
+		fmt.Fprintln(w, "---------\t------\t------\t-------\t------\t----")
+
+		for _, s := range summaries {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n",
+				s.Component, s.Create, s.Update, s.Replace, s.Delete, s.Same)
+		}
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format: %s (supported: text, json)", format)
+	}
+}
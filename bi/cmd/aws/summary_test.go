@@ -0,0 +1,75 @@
+package aws
+
+import (
+	"bytes"
+	"testing"
+
+	"bi/pkg/cluster/eks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_printChangeSummaries(t *testing.T) {
+	summaries := []eks.ChangeSummary{
+		{Component: "vpc", Same: 12},
+		{Component: "cluster", Create: 1, Update: 2, Replace: 3, Delete: 4, Same: 5},
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "text",
+			format: "text",
+			want: "COMPONENT  CREATE  UPDATE  REPLACE  DELETE  SAME\n" +
+				"---------  ------  ------  -------  ------  ----\n" +
+				"vpc        0       0       0        0       12\n" +
+				"cluster    1       2       3        4       5\n",
+		},
+		{
+			name:   "json",
+			format: "JSON",
+			want: `[
+  {
+    "component": "vpc",
+    "create": 0,
+    "update": 0,
+    "replace": 0,
+    "delete": 0,
+    "same": 12
+  },
+  {
+    "component": "cluster",
+    "create": 1,
+    "update": 2,
+    "replace": 3,
+    "delete": 4,
+    "same": 5
+  }
+]
+`,
+		},
+		{
+			name:    "unsupported",
+			format:  "yaml",
+			wantErr: "unsupported output format: yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := printChangeSummaries(&out, summaries, tt.format)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, out.String())
+		})
+	}
+}
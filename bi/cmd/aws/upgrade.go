@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"log/slog"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [install-slug|install-spec-url|install-spec-file] --to <version>",
+	Short: "Upgrade the kubernetes version of a cluster on AWS",
+	Long: `Upgrade a cluster created on AWS EKS to the next kubernetes minor
+version. EKS only supports moving up one minor version at a time.
+
+The control plane is upgraded first, then the managed node groups are
+rolled onto the new version (new nodes are added before the old ones
+are drained) and the EKS addons are updated. Finally the version of
+every managed node is checked.`,
+	Example: `bi aws upgrade my-install --to 1.35`,
+	Args:    cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		to, err := cmd.Flags().GetString("to")
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		var progressReporter *util.ProgressReporter
+		if log.Level == slog.LevelWarn {
+			progressReporter = util.NewProgressReporter()
+			defer progressReporter.Shutdown()
+		}
+
+		return env.UpgradeKubeProvider(ctx, to, progressReporter)
+	},
+}
+
+func init() {
+	upgradeCmd.Flags().String("to", "", "Kubernetes minor version to upgrade to e.g. 1.35")
+	_ = upgradeCmd.MarkFlagRequired("to")
+	awsCmd.AddCommand(upgradeCmd)
+}
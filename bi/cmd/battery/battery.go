@@ -0,0 +1,16 @@
+package battery
+
+import (
+	"bi/cmd"
+
+	"github.com/spf13/cobra"
+)
+
+var batteryCmd = &cobra.Command{
+	Use:   "battery",
+	Short: "Manage the batteries of a batteries included environment",
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(batteryCmd)
+}
@@ -0,0 +1,85 @@
+package battery
+
+import (
+	"log/slog"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/installs"
+	"bi/pkg/kube"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall [install-slug|install-spec-url|install-spec-file] battery-type",
+	Short: "Uninstall a single battery from a running installation",
+	Long: `Remove the kubernetes resources of one battery, e.g. to back out
+a misbehaving battery, without touching the rest of the installation.
+The resources are found by the battery's labels and removed in the same
+order as "bi stop" uses.
+
+The control server keeps its own copy of the installed batteries and
+reinstalls the battery, right away if it is running or else when it is
+started again. While it is deployed the stored target summary is left
+as is so that it keeps matching the control server, remove the battery
+with the control server to uninstall it for good. Without a control
+server the battery is removed from the stored target summary too.
+
+Resources made for a battery's sub-objects, like postgres clusters or
+ferret services, are labelled with the sub-object rather than the
+battery and are left behind.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+		batteryType := args[1]
+
+		ctx := cmd.Context()
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+
+		deleteVolumes, err := cmd.Flags().GetBool("delete-volumes")
+		if err != nil {
+			return err
+		}
+
+		opts := kube.RemoveAllOptions{
+			DryRun:        dryRun,
+			Out:           cmd.OutOrStdout(),
+			Force:         force,
+			DeleteVolumes: deleteVolumes,
+		}
+
+		if log.Level != slog.LevelDebug {
+			opts.ProgressReporter = util.NewProgressReporter()
+			defer opts.ProgressReporter.Shutdown()
+		}
+
+		return env.UninstallBattery(ctx, batteryType, opts)
+	},
+}
+
+func init() {
+	uninstallCmd.Flags().Bool("dry-run", false, "List the resources that would be removed without removing them")
+	uninstallCmd.Flags().Bool("force", false, "Strip finalizers that block deleting resources after a timeout")
+	uninstallCmd.Flags().Bool("delete-volumes", false, "Delete retained persistent volumes (and their backing volumes) with their claims")
+
+	batteryCmd.AddCommand(uninstallCmd)
+}
@@ -4,7 +4,11 @@ Copyright © 2024 Elliott Clark elliott@batteriesincl.com
 package debug
 
 import (
+	"log/slog"
+
+	"bi/pkg/cluster/util"
 	"bi/pkg/installs"
+	"bi/pkg/kube"
 	"bi/pkg/log"
 
 	"github.com/spf13/cobra"
@@ -28,13 +32,47 @@ var cleanKubeCmd = &cobra.Command{
 			return err
 		}
 
-		kubeClient, err := env.NewBatteryKubeClient()
+		kubeClient, err := env.NewBatteryKubeClient(ctx)
 		if err != nil {
 			return err
 		}
 		defer kubeClient.Close()
 
-		if err := kubeClient.RemoveAll(ctx); err != nil {
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+
+		finalizerTimeout, err := cmd.Flags().GetDuration("finalizer-timeout")
+		if err != nil {
+			return err
+		}
+
+		deleteVolumes, err := cmd.Flags().GetBool("delete-volumes")
+		if err != nil {
+			return err
+		}
+
+		opts := kube.RemoveAllOptions{
+			DryRun:           dryRun,
+			Out:              cmd.OutOrStdout(),
+			Force:            force,
+			FinalizerTimeout: finalizerTimeout,
+			FailAfterTimeout: cmd.Flags().Changed("finalizer-timeout"),
+			DeleteVolumes:    deleteVolumes,
+		}
+
+		if log.Level != slog.LevelDebug {
+			opts.ProgressReporter = util.NewProgressReporter()
+			defer opts.ProgressReporter.Shutdown()
+		}
+
+		if err := kubeClient.RemoveAll(ctx, opts); err != nil {
 			return err
 		}
 
@@ -43,5 +81,9 @@ var cleanKubeCmd = &cobra.Command{
 }
 
 func init() {
+	cleanKubeCmd.Flags().Bool("dry-run", false, "List the resources that would be removed without removing them")
+	cleanKubeCmd.Flags().Bool("force", false, "Strip finalizers that block deleting resources after the finalizer timeout")
+	cleanKubeCmd.Flags().Duration("finalizer-timeout", kube.DefaultFinalizerTimeout, "How long to wait on finalizers before warning (or with --force stripping them), fails the removal if set without --force")
+	cleanKubeCmd.Flags().Bool("delete-volumes", false, "Delete retained persistent volumes (and their backing volumes) with their claims")
 	debugCmd.AddCommand(cleanKubeCmd)
 }
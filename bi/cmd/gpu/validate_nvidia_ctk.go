@@ -34,7 +34,7 @@ If validation fails, it provides helpful instructions to fix the setup.`,
 		fmt.Println("🔍 Validating NVIDIA Container Toolkit setup...")
 		fmt.Println()
 		// Create a provider to get Docker client access if available
-		provider := kind.NewClusterProvider(slog.Default(), "validation-test", false, false) // Disable GPU auto-discovery
+		provider := kind.NewClusterProvider(slog.Default(), "validation-test", kind.ClusterProviderOptions{}) // Disable GPU auto-discovery
 		var dockerClient *dockerclient.Client
 		if err := provider.Init(ctx); err == nil && provider.HasDockerClient() {
 			dockerClient = provider.GetDockerClient()
@@ -0,0 +1,139 @@
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+
+	"bi/cmd"
+	"bi/pkg/installs"
+	"bi/pkg/kube"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var kubeCmd = &cobra.Command{
+	Use:   "kube",
+	Short: "Access the kubernetes API of a batteries included environment",
+}
+
+// apiProxy serves the API proxy of an install on a local listener.
+type apiProxy struct {
+	kubeClient kube.KubeClient
+	ln         net.Listener
+	srv        *http.Server
+	// caData is the proxy's self-signed certificate.
+	caData []byte
+	// token is the bearer token requests to the proxy need.
+	token string
+}
+
+// openAPIProxy connects to the install, through its WireGuard network if it
+// has one, and listens on listenAddr. Unless remote is set only requests for
+// localhost are accepted.
+func openAPIProxy(ctx context.Context, cmd *cobra.Command, installURL, listenAddr string, remote bool) (*apiProxy, *installs.InstallEnv, error) {
+	eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+	env, err := eb.Build(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+		return nil, nil, err
+	}
+
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := kube.NewAPIProxyToken()
+
+	handler, err := kubeClient.APIProxyHandler(token)
+	if err != nil {
+		kubeClient.Close()
+		return nil, nil, err
+	}
+
+	if !remote {
+		handler = kube.LocalhostOnly(handler)
+	}
+
+	// The certificate is also valid for a specific remote listen address.
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(listenAddr); err == nil && !slices.Contains(hosts, host) {
+		if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+			hosts = append(hosts, host)
+		}
+	}
+
+	cert, caData, err := kube.NewAPIProxyCert(hosts)
+	if err != nil {
+		kubeClient.Close()
+		return nil, nil, err
+	}
+
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		kubeClient.Close()
+		return nil, nil, fmt.Errorf("failed to listen for the api proxy: %w", err)
+	}
+
+	// exec, attach and port-forward upgrade the connection which can't be
+	// done over HTTP/2.
+	ln = tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	return &apiProxy{
+		kubeClient: kubeClient,
+		ln:         ln,
+		srv:        &http.Server{Handler: handler},
+		caData:     caData,
+		token:      token,
+	}, env, nil
+}
+
+// URL is where the proxy is served.
+func (p *apiProxy) URL() string {
+	return "https://" + p.ln.Addr().String()
+}
+
+// WriteKubeConfig writes a kubeconfig with the proxy's URL, certificate and
+// token.
+func (p *apiProxy) WriteKubeConfig(w io.Writer, name string) error {
+	return kube.WriteProxyKubeConfig(w, name, p.URL(), p.caData, p.token)
+}
+
+// Serve serves the proxy until ctx is done.
+func (p *apiProxy) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		slog.Debug("Stopping api proxy")
+		_ = p.srv.Shutdown(context.Background())
+	}()
+
+	if err := p.srv.Serve(p.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (p *apiProxy) Close() error {
+	_ = p.srv.Close()
+	return p.kubeClient.Close()
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(kubeCmd)
+}
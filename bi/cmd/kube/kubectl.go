@@ -0,0 +1,98 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+
+	"bi/cmd"
+
+	"github.com/spf13/cobra"
+)
+
+var kubectlCmd = &cobra.Command{
+	Use:   "kubectl [install-slug|install-spec-url|install-spec-file] -- [kubectl args...]",
+	Short: "Run kubectl against a batteries included environment",
+	Long: `Run kubectl against a batteries included environment through a local
+API proxy, so it works for clusters that are only reachable over the
+WireGuard network, e.g.
+
+  bi kubectl my-install -- get pods -A
+
+kubectl must be on the PATH.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := runKubectl(cmd, args[0], args[1:])
+
+		// Exit with kubectl's code so scripts can rely on it.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+
+		return err
+	},
+}
+
+func runKubectl(cmd *cobra.Command, installURL string, kubectlArgs []string) error {
+	kubectlPath, err := exec.LookPath("kubectl")
+	if err != nil {
+		return fmt.Errorf("kubectl not found: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	proxy, env, err := openAPIProxy(ctx, cmd, installURL, "127.0.0.1:0", false)
+	if err != nil {
+		return err
+	}
+	defer proxy.Close()
+
+	go func() {
+		if err := proxy.Serve(ctx); err != nil {
+			slog.Error("Error serving api proxy", slog.Any("error", err))
+		}
+	}()
+
+	dir, err := os.MkdirTemp("", "bi-kubectl-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeConfigPath := filepath.Join(dir, "kubeconfig")
+	f, err := os.OpenFile(kubeConfigPath, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create kubeconfig: %w", err)
+	}
+
+	err = proxy.WriteKubeConfig(f, "bi-"+env.Slug)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// kubectl handles CTRL-C itself (e.g. to stop following logs), the proxy
+	// has to keep running until it exits.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	kubectl := exec.CommandContext(ctx, kubectlPath, kubectlArgs...)
+	kubectl.Env = append(os.Environ(), "KUBECONFIG="+kubeConfigPath)
+	kubectl.Stdin, kubectl.Stdout, kubectl.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	slog.Debug("Running kubectl", slog.Any("args", kubectlArgs))
+
+	return kubectl.Run()
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(kubectlCmd)
+}
@@ -0,0 +1,115 @@
+package kube
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+var kubeProxyCmd = &cobra.Command{
+	Use:   "proxy [install-slug|install-spec-url|install-spec-file]",
+	Short: "Serve a local proxy to the kubernetes API",
+	Long: `Serve a local proxy to the kubernetes API of a batteries included
+environment, through its WireGuard network when it has one.
+
+The proxy adds the install's credentials, so tools like kubectl, k9s
+and helm only need to be pointed at it, e.g.
+
+  bi kube proxy my-install --kubeconfig /tmp/my-install.yaml
+  KUBECONFIG=/tmp/my-install.yaml k9s
+
+The proxy is served over TLS with a certificate generated for the
+session, and requests need the session's bearer token. Both are written
+to the kubeconfig, the token is printed when there is no --kubeconfig.
+
+The proxy only listens on loopback addresses and accepts requests for
+localhost unless --allow-remote is passed. Anyone who can reach it with
+the token has the install's credentials.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		listenAddr, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return err
+		}
+
+		kubeConfigPath, err := cmd.Flags().GetString("kubeconfig")
+		if err != nil {
+			return err
+		}
+
+		allowRemote, err := cmd.Flags().GetBool("allow-remote")
+		if err != nil {
+			return err
+		}
+
+		if !allowRemote {
+			if err := checkLoopback(listenAddr); err != nil {
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		proxy, env, err := openAPIProxy(ctx, cmd, installURL, listenAddr, allowRemote)
+		if err != nil {
+			return err
+		}
+		defer proxy.Close()
+
+		fmt.Printf("Kubernetes API proxy listening on %s\n", proxy.URL())
+
+		if kubeConfigPath != "" {
+			f, err := os.OpenFile(kubeConfigPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				return fmt.Errorf("failed to create kubeconfig: %w", err)
+			}
+
+			err = proxy.WriteKubeConfig(f, "bi-"+env.Slug)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Wrote kubeconfig to %s\n", kubeConfigPath)
+		} else {
+			fmt.Printf("Bearer token: %s\n", proxy.token)
+		}
+
+		fmt.Println("Connected...[CTRL-C to exit]")
+
+		return proxy.Serve(ctx)
+	},
+}
+
+// checkLoopback returns an error unless the listen address is on loopback.
+func checkLoopback(listenAddr string) error {
+	host, _, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", listenAddr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && addr.IsLoopback() {
+		return nil
+	}
+
+	return fmt.Errorf("listen address %s isn't a loopback address, use --allow-remote to serve the proxy on it", listenAddr)
+}
+
+func init() {
+	kubeProxyCmd.Flags().String("listen", "127.0.0.1:8001", "Address to serve the proxy on")
+	kubeProxyCmd.Flags().Bool("allow-remote", false, "Allow listening on non-loopback addresses and requests for other hosts")
+	kubeProxyCmd.Flags().String("kubeconfig", "", "Path to write a kubeconfig for the proxy to")
+
+	kubeCmd.AddCommand(kubeProxyCmd)
+}
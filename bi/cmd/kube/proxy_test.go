@@ -0,0 +1,33 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkLoopback(t *testing.T) {
+	tests := []struct {
+		listenAddr string
+		wantErr    bool
+	}{
+		{listenAddr: "127.0.0.1:8001"},
+		{listenAddr: "[::1]:8001"},
+		{listenAddr: "localhost:8001"},
+		{listenAddr: "0.0.0.0:8001", wantErr: true},
+		{listenAddr: ":8001", wantErr: true},
+		{listenAddr: "192.168.1.10:8001", wantErr: true},
+		{listenAddr: "127.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listenAddr, func(t *testing.T) {
+			err := checkLoopback(tt.listenAddr)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/pause"
+
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [install-slug|install-spec-url|install-spec-file]",
+	Short: "Pause a local Batteries Included Installation",
+	Long: `Stop the containers of a local (kind) installation
+without deleting the cluster. Use "bi resume" to
+start it again.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		return pause.PauseInstall(ctx, env)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(pauseCmd)
+}
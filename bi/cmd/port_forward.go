@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+
+	"bi/pkg/installs"
+	"bi/pkg/kube"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/portforward"
+)
+
+var portForwardCmd = &cobra.Command{
+	Use:   "port-forward [install-slug|install-spec-url|install-spec-file] namespace/service [local:]remote...",
+	Short: "Forward local ports to a service",
+	Long: `Forward local ports to a ready pod of a service. The remote port
+is the service port's number or name, e.g.
+
+  bi port-forward my-install battery-core/grafana 3000:http
+  bi port-forward my-install battery-core/redis :6379
+
+When the pod goes away or stops being ready the ports are
+forwarded to another ready pod of the service.`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		namespace, service, ok := strings.Cut(args[1], "/")
+		if !ok || namespace == "" || service == "" {
+			return fmt.Errorf("service %q must be namespace/service", args[1])
+		}
+
+		var ports []kube.PortMapping
+		for _, arg := range args[2:] {
+			m, err := kube.ParsePortMapping(arg)
+			if err != nil {
+				return err
+			}
+			ports = append(ports, m)
+		}
+
+		addresses, err := cmd.Flags().GetStringSlice("address")
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		kubeClient, err := env.NewBatteryKubeClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer kubeClient.Close()
+
+		return kubeClient.ForwardService(ctx, kube.ServicePortForward{
+			Namespace: namespace,
+			Service:   service,
+			Ports:     ports,
+			Addresses: addresses,
+			Ready: func(pod string, forwarded []portforward.ForwardedPort) {
+				slog.Debug("Port forward ready", slog.String("pod", pod))
+				for _, p := range forwarded {
+					fmt.Printf("Forwarding %s port %d to %s:%d\n", strings.Join(addresses, ","), p.Local, pod, p.Remote)
+				}
+				fmt.Println("Connected...[CTRL-C to exit]")
+			},
+		})
+	},
+}
+
+func init() {
+	portForwardCmd.Flags().StringSlice("address", []string{"localhost"}, "Addresses to listen on (comma separated)")
+
+	RootCmd.AddCommand(portForwardCmd)
+}
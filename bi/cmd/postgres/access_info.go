@@ -28,7 +28,7 @@ var accessInfoCmd = &cobra.Command{
 			return fmt.Errorf("failed to collect debug logs: %v", err)
 		}
 
-		kubeClient, err := env.NewBatteryKubeClient()
+		kubeClient, err := env.NewBatteryKubeClient(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to create kube client: %v", err)
 		}
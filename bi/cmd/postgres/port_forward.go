@@ -8,11 +8,14 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 
 	"bi/pkg/installs"
+	"bi/pkg/kube"
 	"bi/pkg/log"
 
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/portforward"
 )
 
 const POSTGRES_PORT = 5432
@@ -44,7 +47,7 @@ var portForwardCmd = &cobra.Command{
 			return err
 		}
 
-		kubeClient, err := env.NewBatteryKubeClient()
+		kubeClient, err := env.NewBatteryKubeClient(ctx)
 		if err != nil {
 			return err
 		}
@@ -57,37 +60,18 @@ var portForwardCmd = &cobra.Command{
 			return err
 		}
 
-		stopChannel := make(chan struct{}, 1)
-		readyChannel := make(chan struct{})
-
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, os.Interrupt)
-		defer signal.Stop(signals)
-
-		forwarder, err := kubeClient.PortForwardService(ctx, namespce, serviceName, POSTGRES_PORT, localPort, stopChannel, readyChannel)
-		if err != nil {
-			return err
-		}
-
-		go func() {
-			<-signals
-			if stopChannel != nil {
-				slog.Debug("Stopping port forward")
-				close(stopChannel)
-			}
-		}()
-
-		go func() {
-			<-readyChannel
-			slog.Debug("Port forward ready")
-			fmt.Println("Starting proxy...[CTRL-C to exit]")
-		}()
-
-		if err := forwarder.ForwardPorts(); err != nil {
-			return err
-		}
-
-		return nil
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+
+		return kubeClient.ForwardService(ctx, kube.ServicePortForward{
+			Namespace: namespce,
+			Service:   serviceName,
+			Ports:     []kube.PortMapping{{Local: localPort, Remote: strconv.Itoa(POSTGRES_PORT)}},
+			Ready: func(pod string, _ []portforward.ForwardedPort) {
+				slog.Debug("Port forward ready", slog.String("pod", pod))
+				fmt.Println("Starting proxy...[CTRL-C to exit]")
+			},
+		})
 	},
 }
 
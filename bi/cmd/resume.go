@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/pause"
+
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [install-slug|install-spec-url|install-spec-file]",
+	Short: "Resume a paused Batteries Included Installation",
+	Long: `Start the containers of a paused local (kind)
+installation and wait for the cluster and the
+control server to become healthy.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		return pause.ResumeInstall(ctx, env)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(resumeCmd)
+}
@@ -0,0 +1,43 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"bi/pkg/installs"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list [install-slug|install-spec-url|install-spec-file]",
+	Short: "List the saved snapshots for a local installation",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := env.ListSnapshots()
+		if err != nil {
+			return err
+		}
+
+		for _, s := range snapshots {
+			// print tab separated values
+			// name, created at, size in bytes
+			fmt.Printf("%s\t%s\t%d\n", s.Name, s.CreatedAt.Format(time.RFC3339), s.Size)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(listCmd)
+}
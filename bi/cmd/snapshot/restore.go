@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [install-slug|install-spec-url|install-spec-file] [name]",
+	Short: "Restore a local kind installation from a snapshot",
+	Long: `Recreate the installation's kind cluster and replace its state with
+the contents of a previously saved snapshot. Anything that has changed since
+the snapshot was taken will be lost.
+
+The snapshot is checked before the current cluster is removed, but a restore
+that fails part way through can't be rolled back. Save a snapshot of the
+current state first if it might be needed.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+		name := args[1]
+
+		ctx := cmd.Context()
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		return snapshot.RestoreSnapshot(ctx, env, name)
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(restoreCmd)
+}
@@ -0,0 +1,43 @@
+package snapshot
+
+import (
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+var saveCmd = &cobra.Command{
+	Use:   "save [install-slug|install-spec-url|install-spec-file] [name]",
+	Short: "Save a snapshot of a local kind installation",
+	Long: `Pause the installation, save its etcd data, certificates and local
+persistent volumes to a named snapshot, then resume it.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+		name := args[1]
+
+		ctx := cmd.Context()
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		return snapshot.SaveSnapshot(ctx, env, name)
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(saveCmd)
+}
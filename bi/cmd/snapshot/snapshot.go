@@ -0,0 +1,16 @@
+package snapshot
+
+import (
+	"bi/cmd"
+
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore snapshots of a local batteries included environment",
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(snapshotCmd)
+}
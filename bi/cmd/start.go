@@ -6,7 +6,10 @@ package cmd
 import (
 	"context"
 	"log/slog"
+	"strings"
 
+	"bi/pkg/cluster/eks"
+	"bi/pkg/cluster/kind"
 	"bi/pkg/installs"
 	"bi/pkg/log"
 	"bi/pkg/start"
@@ -48,6 +51,8 @@ func init() {
 	// Define local flags
 	startCmd.Flags().Bool("skip-bootstrap", false, "Skip bootstrapping the cluster")
 	startCmd.Flags().Bool("nvidia-auto-discovery", true, "Enable NVIDIA GPU auto-discovery for Kind clusters")
+	startCmd.Flags().String("kubernetes-version", "", "Kubernetes minor version for the cluster (defaults to the spec or "+kind.DefaultKubernetesVersion+")")
+	startCmd.Flags().StringSlice("component", []string{}, "Only create or update these AWS cluster components (one of "+strings.Join(eks.ComponentNames(), ", ")+")")
 	startCmd.Flags().Bool("allow-test-keys", false, "Allow test keys for JWT verification when fetching specs (default: production keys only)")
 	startCmd.Flags().MarkHidden("allow-test-keys")
 	startCmd.Flags().StringSlice("additional-insecure-hosts", []string{}, "Additional hosts that will be allowed to be insecure - HTTP")
@@ -56,6 +61,8 @@ func init() {
 	// Bind flags to Viper
 	viper.BindPFlag("skip-bootstrap", startCmd.Flags().Lookup("skip-bootstrap"))
 	viper.BindPFlag("nvidia-auto-discovery", startCmd.Flags().Lookup("nvidia-auto-discovery"))
+	viper.BindPFlag("kubernetes-version", startCmd.Flags().Lookup("kubernetes-version"))
+	viper.BindPFlag("component", startCmd.Flags().Lookup("component"))
 	viper.BindPFlag("allow-test-keys", startCmd.Flags().Lookup("allow-test-keys"))
 	viper.BindPFlag("additional-insecure-hosts", startCmd.Flags().Lookup("additional-insecure-hosts"))
 }
@@ -74,12 +81,20 @@ func runStart(cmd *cobra.Command, args []string) error {
 	nvidiaAutoDiscovery := viper.GetBool("nvidia-auto-discovery")
 	allowTestKeys := viper.GetBool("allow-test-keys")
 	skipBootstrap := viper.GetBool("skip-bootstrap")
+	kubernetesVersion := viper.GetString("kubernetes-version")
+	components := viper.GetStringSlice("component")
+
+	if err := eks.ValidateComponentNames(components); err != nil {
+		return err
+	}
 
 	eb := installs.NewEnvBuilder(
 		installs.WithSlugOrURL(installURL),
 		installs.WithAdditionalInsecureHosts(additionalHosts),
 		installs.WithNvidiaAutoDiscovery(nvidiaAutoDiscovery),
 		installs.WithAllowTestKeys(allowTestKeys),
+		installs.WithKubernetesVersion(kubernetesVersion),
+		installs.WithComponents(components),
 	)
 	env, err := eb.Build(ctx)
 	if err != nil {
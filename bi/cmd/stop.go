@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"bi/pkg/installs"
+	"bi/pkg/kube"
 	"bi/pkg/log"
 	"bi/pkg/stop"
 
@@ -40,11 +41,42 @@ var stopCmd = &cobra.Command{
 			return err
 		}
 
-		return stop.StopInstall(ctx, env, skipCleanKube)
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+
+		deleteVolumes, err := cmd.Flags().GetBool("delete-volumes")
+		if err != nil {
+			return err
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		finalizerTimeout, err := cmd.Flags().GetDuration("finalizer-timeout")
+		if err != nil {
+			return err
+		}
+
+		return stop.StopInstall(ctx, env, skipCleanKube, kube.RemoveAllOptions{
+			DryRun:           dryRun,
+			Out:              cmd.OutOrStdout(),
+			Force:            force,
+			FinalizerTimeout: finalizerTimeout,
+			FailAfterTimeout: cmd.Flags().Changed("finalizer-timeout"),
+			DeleteVolumes:    deleteVolumes,
+		})
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(stopCmd)
 	stopCmd.Flags().Bool("skip-clean-kube", false, "Skip deleting kubernetes resources")
+	stopCmd.Flags().Bool("dry-run", false, "List the kubernetes resources that would be removed without stopping anything")
+	stopCmd.Flags().Bool("force", false, "Strip finalizers that block deleting kubernetes resources after the finalizer timeout")
+	stopCmd.Flags().Duration("finalizer-timeout", kube.DefaultFinalizerTimeout, "How long to wait on finalizers before warning (or with --force stripping them), fails the removal if set without --force")
+	stopCmd.Flags().Bool("delete-volumes", false, "Delete retained persistent volumes (and their cloud volumes) with their claims")
 }
@@ -0,0 +1,21 @@
+package vpn
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var vpnClientCmd = &cobra.Command{
+	Use:   "client",
+	Short: "Manage the WireGuard client configs issued for a batteries included environment",
+	Long: `Issue, list and revoke a WireGuard client config per person so that
+access can be revoked without affecting anyone else.
+
+The gateway keys and the clients, including their private keys, are
+saved in the install's state directory and the gateway is reconfigured
+whenever a client is added or revoked. Supported for kind and aws
+installs. Peers added with bi vpn peer can't be managed as clients.`,
+}
+
+func init() {
+	vpnCmd.AddCommand(vpnClientCmd)
+}
@@ -0,0 +1,63 @@
+package vpn
+
+import (
+	"bytes"
+
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var vpnClientAddCmd = &cobra.Command{
+	Use:   "add [install-slug|install-spec-url|install-spec-file] [name]",
+	Short: "Issue a WireGuard client config",
+	Long: `Add a client to the WireGuard gateway and write its WireGuard config.
+
+Each client gets its own key and address on the WireGuard network.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL, name := args[0], args[1]
+
+		ctx := cmd.Context()
+
+		outputPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		format, err := configFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := env.AddWireGuardClient(ctx, name, &buf); err != nil {
+			return err
+		}
+
+		return writeConfig(&buf, outputPath, format, "bi-"+env.Slug+"-"+name)
+	},
+}
+
+func init() {
+	vpnClientAddCmd.Flags().StringP("output", "o", "-", "Path to write the client's wireguard config to")
+	addFormatFlag(vpnClientAddCmd)
+
+	vpnClientCmd.AddCommand(vpnClientAddCmd)
+}
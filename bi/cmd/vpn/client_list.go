@@ -0,0 +1,92 @@
+package vpn
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/wireguard"
+
+	"github.com/spf13/cobra"
+)
+
+var vpnClientListCmd = &cobra.Command{
+	Use:   "list [install-slug|install-spec-url|install-spec-file]",
+	Short: "List the WireGuard client configs",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		clients, err := env.ListWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printClients(clients, format)
+	},
+}
+
+type clientInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// printClients prints the clients without their private keys.
+func printClients(clients []wireguard.Client, format string) error {
+	infos := make([]clientInfo, 0, len(clients))
+	for _, c := range clients {
+		infos = append(infos, clientInfo{Name: c.Name, Address: c.Address.String()})
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(w, "NAME\tADDRESS")
+		fmt.Fprintln(w, "----\t-------")
+
+		for _, c := range infos {
+			fmt.Fprintf(w, "%s\t%s\n", c.Name, c.Address)
+		}
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format: %s (supported: text, json)", format)
+	}
+}
+
+func init() {
+	vpnClientListCmd.Flags().StringP("format", "f", "text", "Output format: 'text' for human-readable table or 'json' for machine-readable JSON")
+
+	vpnClientCmd.AddCommand(vpnClientListCmd)
+}
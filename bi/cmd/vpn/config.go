@@ -3,17 +3,25 @@ package vpn
 import (
 	"bi/pkg/installs"
 	"bi/pkg/log"
+	"bi/pkg/wireguard"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 
-	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
 	"github.com/spf13/cobra"
 )
 
 var vpnConfigCmd = &cobra.Command{
 	Use:   "config [install-slug|install-spec-url|install-spec-file]",
 	Short: "Get the wireguard config for a batteries included environment",
-	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Long: `Get the wireguard config for a batteries included environment.
+
+The config can be written as a wg-quick config, a NetworkManager keyfile,
+the noisysockets YAML used by bi, or shown as a QR code to scan with the
+WireGuard mobile apps.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		installURL := args[0]
 
@@ -34,39 +42,64 @@ var vpnConfigCmd = &cobra.Command{
 		}
 		defer wireGuardConfigFile.Close()
 
-		conf, err := noisysocketsconfig.FromYAML(wireGuardConfigFile)
+		outputPath, err := cmd.Flags().GetString("output")
 		if err != nil {
 			return err
 		}
 
-		outputPath, err := cmd.Flags().GetString("output")
+		format, err := configFormat(cmd)
 		if err != nil {
 			return err
 		}
 
-		slog.Info("Writing wireguard config", slog.String("outputFilePath", outputPath))
-
-		w := os.Stdout
-		if outputPath != "-" {
-			outputFile, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
-			if err != nil {
-				return err
-			}
-			defer outputFile.Close()
+		return writeConfig(wireGuardConfigFile, outputPath, format, "bi-"+env.Slug)
+	},
+}
 
-			w = outputFile
-		}
+// writeConfig converts a noisysockets config to the format and writes it to
+// outputPath, or stdout if it is "-". The name is used for the connection
+// where the format has one.
+func writeConfig(r io.Reader, outputPath string, format wireguard.Format, name string) error {
+	slog.Info("Writing wireguard config", slog.String("outputFilePath", outputPath), slog.String("format", string(format)))
 
-		if err := noisysocketsconfig.ToINI(w, conf); err != nil {
+	w := os.Stdout
+	if outputPath != "-" {
+		outputFile, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
 			return err
 		}
+		defer outputFile.Close()
 
-		return nil
-	},
+		w = outputFile
+	}
+
+	return wireguard.ConvertConfig(w, r, format, name)
+}
+
+// addFormatFlag adds the --format flag used by the commands that write a
+// wireguard config.
+func addFormatFlag(cmd *cobra.Command) {
+	formats := make([]string, 0, len(wireguard.Formats))
+	for _, f := range wireguard.Formats {
+		formats = append(formats, string(f))
+	}
+
+	cmd.Flags().StringP("format", "f", string(wireguard.FormatWGQuick),
+		fmt.Sprintf("Format of the wireguard config (%s)", strings.Join(formats, ", ")))
+}
+
+func configFormat(cmd *cobra.Command) (wireguard.Format, error) {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return "", err
+	}
+
+	return wireguard.ParseFormat(format)
 }
 
 func init() {
 	vpnConfigCmd.Flags().StringP("output", "o", "-", "Path to write the wireguard config to")
+	addFormatFlag(vpnConfigCmd)
 
 	vpnCmd.AddCommand(vpnConfigCmd)
 }
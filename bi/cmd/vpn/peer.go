@@ -0,0 +1,22 @@
+package vpn
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var vpnPeerCmd = &cobra.Command{
+	Use:   "peer",
+	Short: "Manage the peers of the WireGuard gateway for a cluster on AWS",
+	Long: `Add, remove and list WireGuard peers on a running AWS gateway.
+
+Peers are stored in SSM Parameter Store and an agent on each gateway
+instance picks up changes within a minute, so the gateway isn't
+replaced and existing connections aren't dropped.
+
+On aws the peers of clients issued with bi vpn client share the same
+list, they are left out here and can only be changed with bi vpn client.`,
+}
+
+func init() {
+	vpnCmd.AddCommand(vpnPeerCmd)
+}
@@ -0,0 +1,64 @@
+package vpn
+
+import (
+	"bytes"
+
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var vpnPeerAddCmd = &cobra.Command{
+	Use:   "add [install-slug|install-spec-url|install-spec-file] [name]",
+	Short: "Add a peer to the WireGuard gateway and print its config",
+	Long: `Add a peer to the WireGuard gateway and write its WireGuard config.
+
+The peer's private key is only written to the config, keep it safe as
+it can't be retrieved again. Remove and re-add the peer to replace it.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL, name := args[0], args[1]
+
+		ctx := cmd.Context()
+
+		outputPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		format, err := configFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := env.AddWireGuardPeer(ctx, name, &buf); err != nil {
+			return err
+		}
+
+		return writeConfig(&buf, outputPath, format, "bi-"+env.Slug+"-"+name)
+	},
+}
+
+func init() {
+	vpnPeerAddCmd.Flags().StringP("output", "o", "-", "Path to write the peer's wireguard config to")
+	addFormatFlag(vpnPeerAddCmd)
+
+	vpnPeerCmd.AddCommand(vpnPeerAddCmd)
+}
@@ -0,0 +1,81 @@
+package vpn
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"bi/pkg/cluster/eks"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var vpnPeerListCmd = &cobra.Command{
+	Use:   "list [install-slug|install-spec-url|install-spec-file]",
+	Short: "List the peers of the WireGuard gateway",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		peers, err := env.ListWireGuardPeers(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printPeers(peers, format)
+	},
+}
+
+func printPeers(peers []eks.Peer, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(peers)
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(w, "NAME\tADDRESS\tPUBLIC KEY")
+		fmt.Fprintln(w, "----\t-------\t----------")
+
+		for _, p := range peers {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Address, p.PublicKey)
+		}
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format: %s (supported: text, json)", format)
+	}
+}
+
+func init() {
+	vpnPeerListCmd.Flags().StringP("format", "f", "text", "Output format: 'text' for human-readable table or 'json' for machine-readable JSON")
+
+	vpnPeerCmd.AddCommand(vpnPeerListCmd)
+}
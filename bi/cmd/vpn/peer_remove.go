@@ -0,0 +1,48 @@
+package vpn
+
+import (
+	"log/slog"
+
+	"bi/pkg/installs"
+	"bi/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var vpnPeerRemoveCmd = &cobra.Command{
+	Use:   "remove [install-slug|install-spec-url|install-spec-file] [name]",
+	Short: "Remove a peer from the WireGuard gateway",
+	Args:  cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL, name := args[0], args[1]
+
+		ctx := cmd.Context()
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		if err := env.RemoveWireGuardPeer(ctx, name); err != nil {
+			return err
+		}
+
+		slog.Info("Removed wireguard peer", slog.String("peer", name))
+
+		return nil
+	},
+}
+
+func init() {
+	vpnPeerCmd.AddCommand(vpnPeerRemoveCmd)
+}
@@ -0,0 +1,171 @@
+package vpn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"bi/pkg/cluster/eks"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/wireguard"
+
+	"github.com/spf13/cobra"
+)
+
+var vpnRotateCmd = &cobra.Command{
+	Use:   "rotate [install-slug|install-spec-url|install-spec-file]",
+	Short: "Rotate the WireGuard keys of a batteries included environment",
+	Long: `Generate new WireGuard keys, reconfigure the gateway so the old keys can
+no longer connect and rewrite the install's wireguard config.
+
+By default the installer's keys are rotated. Use --client to rotate the
+keys of a client issued with 'bi vpn client add', its new config is
+written to --output. Peers added with 'bi vpn peer add' can't be rotated,
+remove and add them again instead.
+
+Use --gateway to rotate the gateway's own keys. Every client then loses
+access until its keys are rotated to get a config with the new gateway
+key, and every peer until it is removed and added again, so --all is
+needed to rotate the gateway of an install with clients or peers.
+
+On aws rotating the gateway or installer keys replaces the gateway
+instances, which interrupts connections for a few minutes.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		ctx := cmd.Context()
+
+		clientName, err := cmd.Flags().GetString("client")
+		if err != nil {
+			return err
+		}
+
+		rotateGateway, err := cmd.Flags().GetBool("gateway")
+		if err != nil {
+			return err
+		}
+
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return err
+		}
+
+		outputPath, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		format, err := configFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = env.Init(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		if rotateGateway {
+			if err := rotateGatewayKeys(ctx, env, env.Spec.KubeCluster.Provider, all); err != nil {
+				return err
+			}
+
+			slog.Info("Rotated wireguard gateway keys", slog.String("path", env.WireGuardConfigPath()))
+
+			return nil
+		}
+
+		// The installer's config is the install's own, it isn't printed.
+		if clientName == "" || clientName == "installer" {
+			if err := env.RotateWireGuardClient(ctx, "installer", io.Discard); err != nil {
+				return err
+			}
+
+			slog.Info("Rotated wireguard installer keys", slog.String("path", env.WireGuardConfigPath()))
+
+			return nil
+		}
+
+		var buf bytes.Buffer
+		if err := env.RotateWireGuardClient(ctx, clientName, &buf); err != nil {
+			return err
+		}
+
+		slog.Info("Rotated wireguard client keys", slog.String("client", clientName))
+
+		return writeConfig(&buf, outputPath, format, "bi-"+env.Slug+"-"+clientName)
+	},
+}
+
+// gatewayRotator is the part of an install that rotating the gateway needs.
+type gatewayRotator interface {
+	ListWireGuardClients(ctx context.Context) ([]wireguard.Client, error)
+	ListWireGuardPeers(ctx context.Context) ([]eks.Peer, error)
+	RotateWireGuardGateway(ctx context.Context) error
+}
+
+// rotateGatewayKeys refuses to cut off clients and peers unless all is set,
+// and warns about each one that needs a new config afterwards. Only aws
+// gateways have peers.
+func rotateGatewayKeys(ctx context.Context, env gatewayRotator, provider string, all bool) error {
+	clients, err := env.ListWireGuardClients(ctx)
+	if err != nil {
+		return err
+	}
+	clients = slices.DeleteFunc(clients, func(c wireguard.Client) bool { return c.Name == "installer" })
+
+	var peers []eks.Peer
+	if provider == "aws" {
+		peers, err = env.ListWireGuardPeers(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !all && (len(clients) > 0 || len(peers) > 0) {
+		return fmt.Errorf("rotating the gateway keys cuts off %d clients and %d peers until they get new configs, use --all to rotate them anyway",
+			len(clients), len(peers))
+	}
+
+	if err := env.RotateWireGuardGateway(ctx); err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		slog.Warn("Client needs its keys rotated with --client for a config with the new gateway key",
+			slog.String("client", client.Name))
+	}
+
+	for _, peer := range peers {
+		slog.Warn("Peer needs to be removed and added again for a config with the new gateway key",
+			slog.String("peer", peer.Name))
+	}
+
+	return nil
+}
+
+func init() {
+	vpnRotateCmd.Flags().String("client", "", "Name of the client to rotate the keys of (default installer)")
+	vpnRotateCmd.Flags().Bool("gateway", false, "Rotate the gateway's keys")
+	vpnRotateCmd.MarkFlagsMutuallyExclusive("client", "gateway")
+	vpnRotateCmd.Flags().Bool("all", false, "Rotate the gateway's keys even though every client and peer needs a new config afterwards")
+	vpnRotateCmd.Flags().StringP("output", "o", "-", "Path to write the client's new wireguard config to")
+	addFormatFlag(vpnRotateCmd)
+
+	vpnCmd.AddCommand(vpnRotateCmd)
+}
@@ -0,0 +1,89 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bi/pkg/cluster/eks"
+	"bi/pkg/wireguard"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGatewayRotator struct {
+	clients []wireguard.Client
+	peers   []eks.Peer
+	// peersErr is what listing the peers of a non aws install returns.
+	peersErr error
+	rotated  bool
+}
+
+func (f *fakeGatewayRotator) ListWireGuardClients(context.Context) ([]wireguard.Client, error) {
+	return f.clients, nil
+}
+
+func (f *fakeGatewayRotator) ListWireGuardPeers(context.Context) ([]eks.Peer, error) {
+	return f.peers, f.peersErr
+}
+
+func (f *fakeGatewayRotator) RotateWireGuardGateway(context.Context) error {
+	f.rotated = true
+	return nil
+}
+
+func Test_rotateGatewayKeys(t *testing.T) {
+	installer := wireguard.Client{Name: "installer"}
+	laptop := wireguard.Client{Name: "laptop"}
+
+	tests := []struct {
+		name     string
+		provider string
+		env      *fakeGatewayRotator
+		all      bool
+		wantErr  string
+	}{
+		{
+			name:     "kind",
+			provider: "kind",
+			env:      &fakeGatewayRotator{clients: []wireguard.Client{installer}, peersErr: errors.New("only supported for aws installs, not provider: kind")},
+		},
+		{
+			name:     "kind with clients",
+			provider: "kind",
+			env:      &fakeGatewayRotator{clients: []wireguard.Client{installer, laptop}, peersErr: errors.New("only supported for aws installs, not provider: kind")},
+			wantErr:  "cuts off 1 clients and 0 peers",
+		},
+		{
+			name:     "kind with clients and all",
+			provider: "kind",
+			env:      &fakeGatewayRotator{clients: []wireguard.Client{installer, laptop}, peersErr: errors.New("only supported for aws installs, not provider: kind")},
+			all:      true,
+		},
+		{
+			name:     "aws with peers",
+			provider: "aws",
+			env:      &fakeGatewayRotator{clients: []wireguard.Client{installer}, peers: []eks.Peer{{Name: "office"}}},
+			wantErr:  "cuts off 0 clients and 1 peers",
+		},
+		{
+			name:     "aws with peers and all",
+			provider: "aws",
+			env:      &fakeGatewayRotator{clients: []wireguard.Client{installer}, peers: []eks.Peer{{Name: "office"}}},
+			all:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rotateGatewayKeys(context.Background(), tt.env, tt.provider, tt.all)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				require.False(t, tt.env.rotated)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.env.rotated)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/proxy"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var vpnUpCmd = &cobra.Command{
+	Use:   "up [install-slug|install-spec-url|install-spec-file]",
+	Short: "Connect to the WireGuard network and serve a local proxy",
+	Long: `Connect to the WireGuard network of a batteries included environment
+in userspace and serve local SOCKS5 and HTTP proxies through it.
+
+Unlike loading the output of bi vpn config this doesn't need root or
+WireGuard installed. Point a browser or CLI at a proxy to reach cluster
+services and the control server, e.g.
+
+  export ALL_PROXY=socks5h://127.0.0.1:1080
+  export HTTPS_PROXY=http://127.0.0.1:8080
+
+Names are resolved through the tunnel. Set either listen address to an
+empty string to disable that proxy.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installURL := args[0]
+
+		socksAddr, err := cmd.Flags().GetString("socks5-listen")
+		if err != nil {
+			return err
+		}
+
+		httpAddr, err := cmd.Flags().GetString("http-listen")
+		if err != nil {
+			return err
+		}
+
+		if socksAddr == "" && httpAddr == "" {
+			return errors.New("at least one of --socks5-listen and --http-listen is required")
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		eb := installs.NewEnvBuilder(installs.WithSlugOrURL(installURL))
+		env, err := eb.Build(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := log.CollectDebugLogs(env.DebugLogPath(cmd.CommandPath())); err != nil {
+			return err
+		}
+
+		wgNet, err := env.OpenWireGuardNetwork(ctx)
+		if err != nil {
+			return err
+		}
+		defer wgNet.Close()
+
+		srv := proxy.New(wgNet.DialContext)
+
+		g, ctx := errgroup.WithContext(ctx)
+
+		if socksAddr != "" {
+			ln, err := net.Listen("tcp", socksAddr)
+			if err != nil {
+				return fmt.Errorf("failed to listen for SOCKS5: %w", err)
+			}
+
+			fmt.Printf("SOCKS5 proxy listening on %s\n", ln.Addr())
+
+			g.Go(func() error { return srv.ServeSOCKS5(ctx, ln) })
+		}
+
+		if httpAddr != "" {
+			ln, err := net.Listen("tcp", httpAddr)
+			if err != nil {
+				return fmt.Errorf("failed to listen for HTTP: %w", err)
+			}
+
+			fmt.Printf("HTTP proxy listening on %s\n", ln.Addr())
+
+			httpSrv := &http.Server{Handler: srv}
+
+			g.Go(func() error {
+				if err := httpSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+				return nil
+			})
+
+			g.Go(func() error {
+				<-ctx.Done()
+				slog.Debug("Stopping HTTP proxy")
+				return httpSrv.Shutdown(context.Background())
+			})
+		}
+
+		fmt.Println("Connected...[CTRL-C to exit]")
+
+		return g.Wait()
+	},
+}
+
+func init() {
+	vpnUpCmd.Flags().String("socks5-listen", "127.0.0.1:1080", "Address to serve the SOCKS5 proxy on")
+	vpnUpCmd.Flags().String("http-listen", "127.0.0.1:8080", "Address to serve the HTTP proxy on")
+
+	vpnCmd.AddCommand(vpnUpCmd)
+}
@@ -6,10 +6,13 @@ package main
 import (
 	"bi/cmd"
 	_ "bi/cmd/aws"
+	_ "bi/cmd/battery"
 	_ "bi/cmd/cli"
 	_ "bi/cmd/debug"
 	_ "bi/cmd/gpu"
+	_ "bi/cmd/kube"
 	_ "bi/cmd/postgres"
+	_ "bi/cmd/snapshot"
 	_ "bi/cmd/vpn"
 )
 
@@ -0,0 +1,211 @@
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"bi/pkg/cluster/eks"
+	"bi/pkg/specs"
+
+	"github.com/adrg/xdg"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// legacyPassphrase is what every install used before the passphrase was
+// configurable. It's only used to read existing state until it's migrated.
+const legacyPassphrase = "PASSWORD"
+
+// PulumiBackendConfig is where pulumi keeps the state of an install and how
+// the secrets within it are encrypted.
+type PulumiBackendConfig struct {
+	// URL of the state backend e.g. file:///some/path or s3://bucket/prefix.
+	// If empty the state is kept in the local XDG state dir.
+	URL string
+	// Passphrase used to encrypt secrets in the state.
+	Passphrase string
+	// PassphraseFile contains the passphrase, used if Passphrase isn't set.
+	// If neither are set, a passphrase is generated per install and saved
+	// locally, which is only allowed when URL is empty.
+	PassphraseFile string
+}
+
+// resolvePassphrase picks the passphrase for an install. Installs with state
+// from before passphrases were configurable keep using the legacy passphrase
+// if allowLegacy is set.
+func (p *pulumiProvider) resolvePassphrase(allowLegacy bool) (string, error) {
+	if p.backend.Passphrase != "" {
+		return p.backend.Passphrase, nil
+	}
+
+	if p.backend.PassphraseFile != "" {
+		b, err := os.ReadFile(p.backend.PassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+
+		passphrase := strings.TrimSpace(string(b))
+		if passphrase == "" {
+			return "", fmt.Errorf("passphrase file %s is empty", p.backend.PassphraseFile)
+		}
+		return passphrase, nil
+	}
+
+	passphrasePath, err := p.passphrasePath()
+	if err != nil {
+		return "", err
+	}
+
+	// The state of a remote backend is shared, a passphrase that only exists
+	// on this machine would lock everyone else out of it.
+	if p.backend.URL != "" {
+		if _, err := os.Stat(passphrasePath); err == nil {
+			return "", fmt.Errorf("a passphrase is required for the %s backend, set pulumi.passphrase-file to %s to keep using the one saved for this install", p.backend.URL, passphrasePath)
+		}
+		return "", fmt.Errorf("a passphrase is required for the %s backend, set pulumi.passphrase or pulumi.passphrase-file", p.backend.URL)
+	}
+
+	b, err := os.ReadFile(passphrasePath)
+	if err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	if allowLegacy && p.hasLegacyState() {
+		slog.Warn("Install state is encrypted with the legacy passphrase, run `bi aws migrate-state` to rotate it",
+			slog.String("slug", p.spec.Slug))
+		return legacyPassphrase, nil
+	}
+
+	passphrase, err := newPassphrase()
+	if err != nil {
+		return "", err
+	}
+
+	// When migrating, the passphrase is only saved once the state has been
+	// re-encrypted with it. Otherwise we'd lose the ability to decrypt it.
+	if p.deferPassphraseWrite {
+		p.pendingPassphrasePath = passphrasePath
+		return passphrase, nil
+	}
+
+	if err := writePassphrase(passphrasePath, passphrase); err != nil {
+		return "", err
+	}
+
+	return passphrase, nil
+}
+
+// passphrasePath is outside of the install directory so that it survives the
+// install being removed and re-fetched.
+func (p *pulumiProvider) passphrasePath() (string, error) {
+	passphrasePath, err := xdg.StateFile(path.Join(p.projectName, "secrets", p.spec.Slug, "pulumi-passphrase"))
+	if err != nil {
+		return "", fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	return passphrasePath, nil
+}
+
+// hasLegacyState checks for existing component state in the local work dir.
+func (p *pulumiProvider) hasLegacyState() bool {
+	matches, _ := filepath.Glob(filepath.Join(p.workDirRoot, "*", ".pulumi", "stacks", "*", p.spec.Slug+".json*"))
+	return len(matches) > 0
+}
+
+func newPassphrase() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate passphrase: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func writePassphrase(passphrasePath, passphrase string) error {
+	if err := os.MkdirAll(filepath.Dir(passphrasePath), 0o700); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	if err := os.WriteFile(passphrasePath, []byte(passphrase), 0o600); err != nil {
+		return fmt.Errorf("failed to write passphrase: %w", err)
+	}
+
+	slog.Debug("Wrote pulumi passphrase", slog.String("path", passphrasePath))
+
+	return nil
+}
+
+// MigratePulumiState moves the state of an install from one backend to
+// another, re-encrypting its secrets if the passphrase changes.
+func MigratePulumiState(ctx context.Context, spec *specs.InstallSpec, userConfig map[string]any, from, to PulumiBackendConfig) error {
+	src := &pulumiProvider{projectName: "bi", spec: spec, userConfig: userConfig, backend: from}
+	if err := src.Init(ctx); err != nil {
+		return fmt.Errorf("failed to initialize source backend: %w", err)
+	}
+
+	dst := &pulumiProvider{projectName: "bi", spec: spec, userConfig: userConfig, backend: to, deferPassphraseWrite: true}
+	if err := dst.Init(ctx); err != nil {
+		return fmt.Errorf("failed to initialize destination backend: %w", err)
+	}
+
+	return migrateState(ctx, src, dst, func(p *pulumiProvider) stateStore {
+		return eks.New(p.toEKSConfig())
+	})
+}
+
+// stateStore is what migrating needs from the stacks of an install.
+type stateStore interface {
+	ChangePassphrase(ctx context.Context, newPassphrase string) error
+	ExportState(ctx context.Context) (map[string]apitype.UntypedDeployment, error)
+	ImportState(ctx context.Context, states map[string]apitype.UntypedDeployment) error
+}
+
+// migrateState re-encrypts and moves the state between two initialized
+// providers. open returns the stacks for a provider's current backend and
+// passphrase.
+func migrateState(ctx context.Context, src, dst *pulumiProvider, open func(*pulumiProvider) stateStore) error {
+	slug := src.spec.Slug
+	srcState := open(src)
+
+	if src.passphrase != dst.passphrase {
+		slog.Info("Re-encrypting install state with new passphrase", slog.String("slug", slug))
+		if err := srcState.ChangePassphrase(ctx, dst.passphrase); err != nil {
+			return err
+		}
+
+		if dst.pendingPassphrasePath != "" {
+			if err := writePassphrase(dst.pendingPassphrasePath, dst.passphrase); err != nil {
+				return err
+			}
+		}
+
+		// The source stacks are now encrypted with the new passphrase.
+		src.setPassphrase(dst.passphrase)
+		srcState = open(src)
+	}
+
+	if src.backend.URL == dst.backend.URL {
+		return nil
+	}
+
+	slog.Info("Moving install state",
+		slog.String("slug", slug),
+		slog.String("from", src.backend.URL),
+		slog.String("to", dst.backend.URL))
+
+	states, err := srcState.ExportState(ctx)
+	if err != nil {
+		return err
+	}
+
+	return open(dst).ImportState(ctx, states)
+}
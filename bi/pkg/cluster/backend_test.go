@@ -0,0 +1,279 @@
+package cluster
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bi/pkg/specs"
+
+	"github.com/adrg/xdg"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePassphrase(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("from-file\n"), 0o600))
+
+	emptyFile := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(emptyFile, []byte("  \n"), 0o600))
+
+	tests := []struct {
+		name        string
+		slug        string
+		backend     PulumiBackendConfig
+		legacyState bool
+		allowLegacy bool
+		savedPhrase string
+		deferWrite  bool
+		want        string
+		wantErr     string
+		wantSaved   bool
+		wantPending bool
+	}{
+		{
+			name:    "env",
+			backend: PulumiBackendConfig{Passphrase: "from-env", PassphraseFile: passphraseFile},
+			want:    "from-env",
+		},
+		{
+			name:    "file",
+			backend: PulumiBackendConfig{PassphraseFile: passphraseFile},
+			want:    "from-file",
+		},
+		{
+			name:    "empty file",
+			backend: PulumiBackendConfig{PassphraseFile: emptyFile},
+			wantErr: "is empty",
+		},
+		{
+			name:    "missing file",
+			backend: PulumiBackendConfig{PassphraseFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: "failed to read passphrase file",
+		},
+		{
+			name:        "saved",
+			savedPhrase: "saved\n",
+			legacyState: true,
+			allowLegacy: true,
+			want:        "saved",
+		},
+		{
+			name:        "legacy",
+			legacyState: true,
+			allowLegacy: true,
+			want:        legacyPassphrase,
+		},
+		{
+			name:        "legacy state on a remote backend",
+			backend:     PulumiBackendConfig{URL: "s3://bucket/prefix"},
+			legacyState: true,
+			allowLegacy: true,
+			wantErr:     "set pulumi.passphrase or pulumi.passphrase-file",
+		},
+		{
+			name:    "remote",
+			backend: PulumiBackendConfig{URL: "s3://bucket/prefix"},
+			wantErr: "a passphrase is required for the s3://bucket/prefix backend",
+		},
+		{
+			name:        "saved on a remote backend",
+			backend:     PulumiBackendConfig{URL: "s3://bucket/prefix"},
+			savedPhrase: "saved\n",
+			wantErr:     "to keep using the one saved for this install",
+		},
+		{
+			name:    "remote with a passphrase",
+			backend: PulumiBackendConfig{URL: "s3://bucket/prefix", Passphrase: "from-env"},
+			want:    "from-env",
+		},
+		{
+			name:        "legacy not allowed",
+			legacyState: true,
+			deferWrite:  true,
+			wantPending: true,
+		},
+		{
+			name:      "generated",
+			wantSaved: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &pulumiProvider{
+				projectName:          "bi",
+				spec:                 &specs.InstallSpec{Slug: "test-" + filepath.Base(t.Name())},
+				backend:              tt.backend,
+				workDirRoot:          t.TempDir(),
+				deferPassphraseWrite: tt.deferWrite,
+			}
+
+			if tt.legacyState {
+				stackDir := filepath.Join(p.workDirRoot, "vpc", ".pulumi", "stacks", "bi-vpc")
+				require.NoError(t, os.MkdirAll(stackDir, 0o700))
+				require.NoError(t, os.WriteFile(filepath.Join(stackDir, p.spec.Slug+".json"), []byte("{}"), 0o600))
+			}
+
+			passphrasePath, err := p.passphrasePath()
+			require.NoError(t, err)
+
+			if tt.savedPhrase != "" {
+				require.NoError(t, writePassphrase(passphrasePath, tt.savedPhrase))
+			}
+
+			got, err := p.resolvePassphrase(tt.allowLegacy)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.want != "" {
+				require.Equal(t, tt.want, got)
+				return
+			}
+
+			// A new passphrase was generated.
+			require.NotEqual(t, legacyPassphrase, got)
+			require.Len(t, got, 43)
+
+			saved, err := os.ReadFile(passphrasePath)
+			if tt.wantSaved {
+				require.NoError(t, err)
+				require.Equal(t, got, string(saved))
+			} else {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			}
+
+			if tt.wantPending {
+				require.Equal(t, passphrasePath, p.pendingPassphrasePath)
+			}
+		})
+	}
+}
+
+// fakeStateStore is a backend's stacks, keyed by the backend URL.
+type fakeStateStore struct {
+	backends map[string]*fakeBackend
+	url      string
+	// passphrase the stacks were opened with
+	passphrase string
+}
+
+type fakeBackend struct {
+	passphrase string
+	states     map[string]apitype.UntypedDeployment
+	imported   bool
+}
+
+func (s *fakeStateStore) backend() *fakeBackend {
+	b, ok := s.backends[s.url]
+	if !ok {
+		b = &fakeBackend{passphrase: s.passphrase}
+		s.backends[s.url] = b
+	}
+	return b
+}
+
+func (s *fakeStateStore) ChangePassphrase(_ context.Context, newPassphrase string) error {
+	s.backend().passphrase = newPassphrase
+	return nil
+}
+
+func (s *fakeStateStore) ExportState(context.Context) (map[string]apitype.UntypedDeployment, error) {
+	return s.backend().states, nil
+}
+
+func (s *fakeStateStore) ImportState(_ context.Context, states map[string]apitype.UntypedDeployment) error {
+	b := s.backend()
+	b.states = states
+	b.passphrase = s.passphrase
+	b.imported = true
+	return nil
+}
+
+func TestMigrateState(t *testing.T) {
+	states := map[string]apitype.UntypedDeployment{"vpc": {Version: 3}}
+
+	tests := []struct {
+		name           string
+		from, to       PulumiBackendConfig
+		pending        bool
+		wantPassphrase map[string]string
+		wantImported   string
+	}{
+		{
+			name:           "no-op",
+			from:           PulumiBackendConfig{Passphrase: "same"},
+			to:             PulumiBackendConfig{Passphrase: "same"},
+			wantPassphrase: map[string]string{"": "same"},
+		},
+		{
+			name:           "re-encrypt",
+			from:           PulumiBackendConfig{Passphrase: legacyPassphrase},
+			to:             PulumiBackendConfig{Passphrase: "new"},
+			pending:        true,
+			wantPassphrase: map[string]string{"": "new"},
+		},
+		{
+			name:           "copy",
+			from:           PulumiBackendConfig{Passphrase: "same"},
+			to:             PulumiBackendConfig{URL: "s3://bucket/prefix", Passphrase: "same"},
+			wantPassphrase: map[string]string{"": "same", "s3://bucket/prefix": "same"},
+			wantImported:   "s3://bucket/prefix",
+		},
+		{
+			name:           "re-encrypt and copy",
+			from:           PulumiBackendConfig{Passphrase: legacyPassphrase},
+			to:             PulumiBackendConfig{URL: "s3://bucket/prefix", Passphrase: "new"},
+			wantPassphrase: map[string]string{"": "new", "s3://bucket/prefix": "new"},
+			wantImported:   "s3://bucket/prefix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &specs.InstallSpec{Slug: "test"}
+			src := &pulumiProvider{spec: spec, backend: tt.from}
+			src.setPassphrase(tt.from.Passphrase)
+			dst := &pulumiProvider{spec: spec, backend: tt.to}
+			dst.setPassphrase(tt.to.Passphrase)
+
+			pendingPath := filepath.Join(t.TempDir(), "pulumi-passphrase")
+			if tt.pending {
+				dst.pendingPassphrasePath = pendingPath
+			}
+
+			backends := map[string]*fakeBackend{
+				tt.from.URL: {passphrase: tt.from.Passphrase, states: states},
+			}
+			open := func(p *pulumiProvider) stateStore {
+				return &fakeStateStore{backends: backends, url: p.backend.URL, passphrase: p.passphrase}
+			}
+
+			require.NoError(t, migrateState(context.Background(), src, dst, open))
+
+			require.Len(t, backends, len(tt.wantPassphrase))
+			for url, passphrase := range tt.wantPassphrase {
+				require.Equal(t, passphrase, backends[url].passphrase, url)
+				require.Equal(t, states, backends[url].states, url)
+				require.Equal(t, tt.wantImported != "" && url == tt.wantImported, backends[url].imported, url)
+			}
+
+			saved, err := os.ReadFile(pendingPath)
+			if tt.pending {
+				require.NoError(t, err)
+				require.Equal(t, tt.to.Passphrase, string(saved))
+			} else {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			}
+		})
+	}
+}
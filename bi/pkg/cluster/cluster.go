@@ -1,7 +1,9 @@
 package cluster
 
 import (
+	"bi/pkg/cluster/eks"
 	"bi/pkg/cluster/util"
+	"bi/pkg/wireguard"
 	"context"
 	"io"
 )
@@ -28,3 +30,49 @@ type Provider interface {
 	// HasNvidiaRuntimeInstalled returns true if NVIDIA runtime was installed during cluster creation.
 	HasNvidiaRuntimeInstalled() bool
 }
+
+// StackProvider is implemented by providers that manage the cluster as a set
+// of pulumi component stacks.
+type StackProvider interface {
+	Provider
+	// Preview reports the changes that Create would make to each component.
+	Preview(context.Context) ([]eks.ChangeSummary, error)
+	// Refresh syncs the state of each component and reports any drift.
+	Refresh(context.Context) ([]eks.ChangeSummary, error)
+	// CreateComponents creates or updates only the named components.
+	CreateComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error
+	// DestroyComponents destroys only the named components.
+	DestroyComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error
+	// Upgrade moves the cluster to the next kubernetes minor version.
+	Upgrade(ctx context.Context, to string, progressReporter *util.ProgressReporter) error
+	// WireGuardGatewayEndpoints returns the endpoint of every gateway
+	// instance, the first is the one in the WireGuard configuration.
+	WireGuardGatewayEndpoints(ctx context.Context) ([]string, error)
+	// WireGuardPeers returns the peers added to the running gateway.
+	WireGuardPeers(ctx context.Context) ([]eks.Peer, error)
+	// AddWireGuardPeer adds a peer to the running gateway and writes its
+	// WireGuard configuration to w.
+	AddWireGuardPeer(ctx context.Context, name string, w io.Writer) error
+	// RemoveWireGuardPeer removes a peer from the running gateway.
+	RemoveWireGuardPeer(ctx context.Context, name string) error
+}
+
+// WireGuardClientProvider is implemented by providers whose gateway can
+// issue a separate client config to each person.
+type WireGuardClientProvider interface {
+	// WireGuardClients returns the clients of the gateway, including their
+	// private keys.
+	WireGuardClients(ctx context.Context) ([]wireguard.Client, error)
+	// AddWireGuardClient adds a client, reconfigures the gateway and writes
+	// the client's WireGuard configuration to w.
+	AddWireGuardClient(ctx context.Context, name string, w io.Writer) error
+	// RevokeWireGuardClient removes a client and reconfigures the gateway.
+	RevokeWireGuardClient(ctx context.Context, name string) error
+	// RotateWireGuardGateway replaces the gateway's key and reconfigures the
+	// gateway. Every client needs a new configuration afterwards.
+	RotateWireGuardGateway(ctx context.Context) error
+	// RotateWireGuardClient replaces a client's key, reconfigures the gateway
+	// so the old key can't connect and writes the client's new WireGuard
+	// configuration to w.
+	RotateWireGuardClient(ctx context.Context, name string, w io.Writer) error
+}
@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// userConfigKeys are the pulumi config keys that can be overridden from
+// bi.yaml or the install spec. Everything else is derived from the install.
+var userConfigKeys = []string{
+	"aws:region",
+	"cluster:amiType",
+	"cluster:capacityType",
+	"cluster:desiredSize",
+	"cluster:instanceType",
+	"cluster:maxSize",
+	"cluster:minSize",
+	"cluster:nodeGroups",
+	"cluster:version",
+	"cluster:volumeSize",
+	"cluster:volumeType",
+	"gateway:cidrBlock",
+	"gateway:count",
+	"gateway:generateSSHKey",
+	"gateway:instanceType",
+	"gateway:port",
+	"gateway:volumeSize",
+	"gateway:volumeType",
+	"vpc:cidrBlock",
+	"vpc:id",
+	"vpc:privateSubnetIDs",
+	"vpc:publicSubnetIDs",
+}
+
+// configKeyAliases are keys shared with other providers.
+var configKeyAliases = map[string]string{
+	"kubernetes_version": "cluster:version",
+}
+
+// applyConfigOverrides merges user supplied config into cfg. Overrides may
+// either be flat (cluster:instanceType: m5.large) or nested
+// (cluster: {instanceType: m5.large}). Keys are matched case insensitively
+// as viper lower cases everything.
+func applyConfigOverrides(cfg auto.ConfigMap, source string, overrides map[string]any) error {
+	flat := map[string]any{}
+	flattenConfig("", overrides, flat)
+
+	for _, key := range slices.Sorted(maps.Keys(flat)) {
+		canonical, ok := canonicalConfigKey(key)
+		if !ok {
+			return fmt.Errorf("unknown %s config key %q, valid keys are: %s",
+				source, key, strings.Join(userConfigKeys, ", "))
+		}
+
+		value, err := configValueString(flat[key])
+		if err != nil {
+			return fmt.Errorf("invalid %s config value for %s: %w", source, key, err)
+		}
+
+		// Floats lose trailing zeros (1.30 -> 1.3) so versions must be quoted.
+		if canonical == "cluster:version" {
+			if _, isString := flat[key].(string); !isString {
+				return fmt.Errorf("invalid %s config value for %s: must be a quoted string e.g. \"1.34\"", source, key)
+			}
+		}
+
+		cfg[canonical] = auto.ConfigValue{Value: value}
+	}
+
+	return nil
+}
+
+func flattenConfig(prefix string, in map[string]any, out map[string]any) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + ":" + k
+		}
+
+		if nested, ok := v.(map[string]any); ok {
+			flattenConfig(key, nested, out)
+			continue
+		}
+
+		out[key] = v
+	}
+}
+
+func canonicalConfigKey(key string) (string, bool) {
+	if alias, ok := configKeyAliases[strings.ToLower(key)]; ok {
+		return alias, true
+	}
+
+	for _, k := range userConfigKeys {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+func configValueString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		// e.g. cluster:nodeGroups
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
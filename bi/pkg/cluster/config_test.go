@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyConfigOverrides(t *testing.T) {
+	testCases := []struct {
+		name      string
+		overrides map[string]any
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "flat keys",
+			overrides: map[string]any{"aws:region": "eu-west-1", "cluster:instanceType": "m5.large"},
+			want:      map[string]string{"aws:region": "eu-west-1", "cluster:instanceType": "m5.large"},
+		},
+		{
+			name: "nested lower cased keys",
+			overrides: map[string]any{
+				"cluster": map[string]any{"instancetype": "m5.large", "maxsize": 6},
+				"gateway": map[string]any{"generatesshkey": true},
+			},
+			want: map[string]string{"cluster:instanceType": "m5.large", "cluster:maxSize": "6", "gateway:generateSSHKey": "true"},
+		},
+		{
+			name:      "numbers from json",
+			overrides: map[string]any{"cluster:desiredSize": float64(3)},
+			want:      map[string]string{"cluster:desiredSize": "3"},
+		},
+		{
+			name:      "kubernetes version alias",
+			overrides: map[string]any{"kubernetes_version": "1.33"},
+			want:      map[string]string{"cluster:version": "1.33"},
+		},
+		{
+			name:      "node groups list",
+			overrides: map[string]any{"cluster": map[string]any{"nodegroups": []any{map[string]any{"name": "system"}}}},
+			want:      map[string]string{"cluster:nodeGroups": `[{"name":"system"}]`},
+		},
+		{
+			name:      "unquoted version",
+			overrides: map[string]any{"cluster:version": 1.30},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown key",
+			overrides: map[string]any{"cluster:instanceSize": "large"},
+			wantErr:   true,
+		},
+		{
+			name:      "derived key",
+			overrides: map[string]any{"cluster:name": "foo"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := auto.ConfigMap{}
+			err := applyConfigOverrides(cfg, "test", tc.overrides)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			got := map[string]string{}
+			for k, v := range cfg {
+				got[k] = v.Value
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package eks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"bi/pkg/wireguard"
+
+	noisysocketstypes "github.com/noisysockets/noisysockets/types"
+)
+
+// Clients returns the installer client and the clients issued by AddClient,
+// including their private keys.
+func (e *eks) Clients(ctx context.Context) ([]wireguard.Client, error) {
+	gw, err := e.clientGateway(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return gw.Clients, nil
+}
+
+// AddClient issues a client config for a person. The client is added to the
+// gateway as a peer and its keys are saved to the wireguard state path so
+// that the config can be listed and revoked later.
+func (e *eks) AddClient(ctx context.Context, name string, w io.Writer) error {
+	if e.cfg.WireGuardStatePath == "" {
+		return errors.New("no wireguard state path to save clients to")
+	}
+
+	privateKey, err := noisysocketstypes.NewPrivateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	gw, err := e.clientGateway(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := gw.Client(name); ok {
+		return fmt.Errorf("client %s already exists", name)
+	}
+
+	peer, err := e.addPeer(ctx, name, privateKey.Public().String(), true)
+	if err != nil {
+		return err
+	}
+
+	gw.Clients = append(gw.Clients, wireguard.Client{
+		Gateway:    gw,
+		Name:       name,
+		PrivateKey: privateKey.String(),
+		Address:    peer.Address,
+	})
+
+	// Without the saved private key the peer is of no use to anyone.
+	if err := gw.Save(e.cfg.WireGuardStatePath); err != nil {
+		if removeErr := e.removePeer(ctx, name, true); removeErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to remove peer %s, remove it with bi vpn client revoke: %w", name, removeErr))
+		}
+		return err
+	}
+
+	client, _ := gw.Client(name)
+	if err := client.WriteConfig(w); err != nil {
+		return fmt.Errorf("error writing wireguard config: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeClient removes a client issued by AddClient from the gateway.
+func (e *eks) RevokeClient(ctx context.Context, name string) error {
+	if e.cfg.WireGuardStatePath == "" {
+		return errors.New("no wireguard state path to save clients to")
+	}
+
+	gw, err := e.clientGateway(ctx)
+	if err != nil {
+		return err
+	}
+
+	if name == "installer" {
+		return fmt.Errorf("the %s client can't be revoked", name)
+	}
+
+	if err := gw.RemoveClient(name); err != nil {
+		return err
+	}
+
+	if err := e.removePeer(ctx, name, true); err != nil {
+		return err
+	}
+
+	return gw.Save(e.cfg.WireGuardStatePath)
+}
+
+// clientGateway returns the gateway with the installer and any saved
+// clients. The gateway itself always comes from the outputs as its keys
+// live in the gateway stack.
+func (e *eks) clientGateway(ctx context.Context) (*wireguard.Gateway, error) {
+	if len(e.outputs) == 0 {
+		if err := e.Outputs(ctx, io.Discard); err != nil {
+			return nil, err
+		}
+	}
+
+	if !e.exists("gateway") {
+		return nil, errors.New("gateway component doesn't exist")
+	}
+
+	gw, err := e.wireGuardGateway()
+	if err != nil {
+		return nil, err
+	}
+
+	_, subnet, err := net.ParseCIDR(e.pConfig.Gateway.CIDRBlock.String())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing gateway cidr block: %w", err)
+	}
+
+	publicPort, ok := e.outputs["gateway"]["publicPort"].Value.(float64)
+	if !ok {
+		return nil, errors.New("gateway has no public port, run bi start to update it")
+	}
+
+	installerAddress, err := e.gatewayOutputAddr("wgClientAddress")
+	if err != nil {
+		return nil, err
+	}
+
+	gw.Subnet = subnet
+	gw.ListenPort = uint16(publicPort)
+
+	// The installer is part of the gateway stack, not saved.
+	gw.Clients = []wireguard.Client{{
+		Gateway:    gw,
+		Name:       "installer",
+		PrivateKey: e.outputs["gateway"]["wgClientPrivateKey"].Value.(string),
+		Address:    installerAddress,
+	}}
+
+	if e.cfg.WireGuardStatePath == "" {
+		return gw, nil
+	}
+
+	saved, err := wireguard.LoadGateway(e.cfg.WireGuardStatePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return gw, nil
+		}
+		return nil, err
+	}
+
+	for _, c := range saved.Clients {
+		if c.Name == "installer" {
+			continue
+		}
+
+		c.Gateway = gw
+		gw.Clients = append(gw.Clients, c)
+	}
+
+	return gw, nil
+}
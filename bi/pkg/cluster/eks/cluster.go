@@ -26,18 +26,10 @@ const AWS_OIDC_THUMBPRINT = "9e99a48a9960b14926bb7f3b02e22da2b0ab7280"
 
 type clusterConfig struct {
 	// config
-	baseName     string
-	version      string
-	defaultTags  map[string]string
-	amiType      string
-	capacityType string
-	// TODO(jdt): allow selecting multiple types
-	instanceType string
-	desiredSize  int
-	maxSize      int
-	minSize      int
-	volumeSize   int
-	volumeType   string
+	baseName    string
+	version     string
+	defaultTags map[string]string
+	nodeGroups  []util.NodeGroup
 
 	// outputs
 	vpcID                  string
@@ -46,30 +38,23 @@ type clusterConfig struct {
 	privateSubnetIDs       []string
 
 	// state
-	securityGroupIDs map[string]pulumi.IDOutput
-	logGroup         *cloudwatch.LogGroup
-	key              *kms.Key
-	managedPolicies  []string
-	roles            map[string]*iam.Role
-	inlinePolicies   []pulumi.Resource
-	cluster          *peks.Cluster
-	provider         *iam.OpenIdConnectProvider
-	template         *ec2.LaunchTemplate
-	managedNodeGroup *peks.NodeGroup
+	securityGroupIDs  map[string]pulumi.IDOutput
+	logGroup          *cloudwatch.LogGroup
+	key               *kms.Key
+	managedPolicies   []string
+	roles             map[string]*iam.Role
+	inlinePolicies    []pulumi.Resource
+	cluster           *peks.Cluster
+	provider          *iam.OpenIdConnectProvider
+	templates         map[string]*ec2.LaunchTemplate
+	managedNodeGroups []pulumi.Resource
 }
 
 func (c *clusterConfig) withConfig(cfg *util.PulumiConfig) error {
 	c.baseName = cfg.Cluster.Name
 	c.version = cfg.Cluster.Version
 	c.defaultTags = cfg.AWS.DefaultTags
-	c.amiType = cfg.Cluster.AmiType
-	c.capacityType = cfg.Cluster.CapacityType
-	c.instanceType = cfg.Cluster.InstanceType
-	c.desiredSize = cfg.Cluster.DesiredSize
-	c.maxSize = cfg.Cluster.MaxSize
-	c.minSize = cfg.Cluster.MinSize
-	c.volumeSize = cfg.Cluster.VolumeSize
-	c.volumeType = cfg.Cluster.VolumeType
+	c.nodeGroups = cfg.Cluster.NodeGroups
 
 	return nil
 }
@@ -99,6 +84,8 @@ func (c *clusterConfig) run(ctx *pulumi.Context) error {
 	c.roles = make(map[string]*iam.Role)
 	c.managedPolicies = []string{}
 	c.inlinePolicies = []pulumi.Resource{}
+	c.templates = make(map[string]*ec2.LaunchTemplate)
+	c.managedNodeGroups = []pulumi.Resource{}
 
 	for _, fn := range []func(*pulumi.Context) error{
 		c.buildSecurityGroups,
@@ -113,8 +100,7 @@ func (c *clusterConfig) run(ctx *pulumi.Context) error {
 		c.buildEKSCluster,
 		c.buildKMSKeyPolicy,
 		c.buildManagedNodeRole,
-		c.buildLaunchTemplate,
-		c.buildManagedNodeGroup,
+		c.buildNodeGroups,
 		c.buildOIDCProvider,
 		c.buildEBSCSIRole,
 		c.buildAddons,
@@ -132,6 +118,7 @@ func (c *clusterConfig) run(ctx *pulumi.Context) error {
 	ctx.Export("nodeRoleName", c.roles["node"].Name)
 	ctx.Export("oidcProviderURL", c.provider.Url)
 	ctx.Export("oidcProviderARN", c.provider.Arn)
+	ctx.Export("version", c.cluster.Version)
 
 	return nil
 }
@@ -523,8 +510,22 @@ func (c *clusterConfig) buildManagedNodeRole(ctx *pulumi.Context) error {
 	return nil
 }
 
-func (c *clusterConfig) buildLaunchTemplate(ctx *pulumi.Context) error {
-	name := fmt.Sprintf("%s-bootstrap", c.baseName)
+func (c *clusterConfig) buildNodeGroups(ctx *pulumi.Context) error {
+	for _, ng := range c.nodeGroups {
+		if err := c.buildLaunchTemplate(ctx, ng); err != nil {
+			return err
+		}
+
+		if err := c.buildManagedNodeGroup(ctx, ng); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *clusterConfig) buildLaunchTemplate(ctx *pulumi.Context, ng util.NodeGroup) error {
+	name := fmt.Sprintf("%s-%s", c.baseName, ng.Name)
 
 	// merge the tags that we want applied with the default tags
 	tags := map[string]string{"Name": name}
@@ -538,8 +539,8 @@ func (c *clusterConfig) buildLaunchTemplate(ctx *pulumi.Context) error {
 				Ebs: &ec2.LaunchTemplateBlockDeviceMappingEbsArgs{
 					Encrypted:           P_STR_TRUE,
 					DeleteOnTermination: P_STR_TRUE,
-					VolumeSize:          pulumi.Int(c.volumeSize),
-					VolumeType:          pulumi.String(c.volumeType),
+					VolumeSize:          pulumi.Int(ng.VolumeSize),
+					VolumeType:          pulumi.String(ng.VolumeType),
 				},
 			},
 		},
@@ -564,50 +565,68 @@ func (c *clusterConfig) buildLaunchTemplate(ctx *pulumi.Context) error {
 		return fmt.Errorf("error registering EC2 launch template %s: %w", name, err)
 	}
 
-	c.template = template
+	c.templates[ng.Name] = template
 
 	return nil
 }
 
-func (c *clusterConfig) buildManagedNodeGroup(ctx *pulumi.Context) error {
-	vsn := c.template.DefaultVersion.ApplyT(func(i int) string {
+func (c *clusterConfig) buildManagedNodeGroup(ctx *pulumi.Context, ng util.NodeGroup) error {
+	template := c.templates[ng.Name]
+	vsn := template.DefaultVersion.ApplyT(func(i int) string {
 		return strconv.Itoa(i)
 	}).(pulumi.StringOutput)
 
-	name := fmt.Sprintf("%s-bootstrap", c.baseName)
-	ng, err := peks.NewNodeGroup(ctx, name, &peks.NodeGroupArgs{
-		AmiType:       pulumi.String(c.amiType),
-		CapacityType:  pulumi.String(c.capacityType),
+	taints := peks.NodeGroupTaintArray{}
+	for _, t := range ng.Taints {
+		var value pulumi.StringPtrInput
+		if t.Value != "" {
+			value = pulumi.String(t.Value)
+		}
+
+		taints = append(taints, &peks.NodeGroupTaintArgs{
+			Effect: pulumi.String(t.Effect),
+			Key:    pulumi.String(t.Key),
+			Value:  value,
+		})
+	}
+
+	var labels pulumi.StringMapInput
+	if len(ng.Labels) > 0 {
+		labels = pulumi.ToStringMap(ng.Labels)
+	}
+
+	name := fmt.Sprintf("%s-%s", c.baseName, ng.Name)
+	nodeGroup, err := peks.NewNodeGroup(ctx, name, &peks.NodeGroupArgs{
+		AmiType:       pulumi.String(ng.AmiType),
+		CapacityType:  pulumi.String(ng.CapacityType),
 		ClusterName:   c.cluster.Name,
-		InstanceTypes: pulumi.ToStringArray([]string{c.instanceType}),
+		InstanceTypes: pulumi.ToStringArray(ng.InstanceTypes),
+		Labels:        labels,
 		NodeRoleArn:   c.roles["node"].Arn,
 		SubnetIds:     pulumi.ToStringArray(c.privateSubnetIDs),
 		Version:       pulumi.String(c.version),
 		LaunchTemplate: &peks.NodeGroupLaunchTemplateArgs{
-			Id:      c.template.ID(),
+			Id:      template.ID(),
 			Version: vsn,
 		},
 		ScalingConfig: &peks.NodeGroupScalingConfigArgs{
-			DesiredSize: pulumi.Int(c.desiredSize),
-			MaxSize:     pulumi.Int(c.maxSize),
-			MinSize:     pulumi.Int(c.minSize),
-		},
-		Taints: &peks.NodeGroupTaintArray{
-			&peks.NodeGroupTaintArgs{
-				Effect: pulumi.String("NO_SCHEDULE"),
-				Key:    pulumi.String("CriticalAddonsOnly"),
-				Value:  P_STR_TRUE,
-			},
+			DesiredSize: pulumi.Int(ng.DesiredSize),
+			MaxSize:     pulumi.Int(ng.MaxSize),
+			MinSize:     pulumi.Int(ng.MinSize),
 		},
+		Taints: taints,
+		// Version updates surge new nodes and drain the old ones one at a
+		// time, respecting pod disruption budgets.
+		ForceUpdateVersion: P_BOOL_PTR_FALSE,
 		UpdateConfig: &peks.NodeGroupUpdateConfigArgs{
 			MaxUnavailable: pulumi.Int(1),
 		},
-	}, pulumi.DependsOn([]pulumi.Resource{c.template}))
+	}, pulumi.DependsOn([]pulumi.Resource{template}))
 	if err != nil {
 		return fmt.Errorf("error registering EKS node group %s: %w", name, err)
 	}
 
-	c.managedNodeGroup = ng
+	c.managedNodeGroups = append(c.managedNodeGroups, nodeGroup)
 
 	return nil
 }
@@ -717,7 +736,9 @@ func (c *clusterConfig) buildAddons(ctx *pulumi.Context) error {
 			ClusterName:           c.cluster.Name,
 			ConfigurationValues:   config.config,
 			ServiceAccountRoleArn: config.irsaARN,
-		}, pulumi.DependsOn([]pulumi.Resource{c.managedNodeGroup}))
+			// Upgrades would otherwise fail if anything was changed in cluster.
+			ResolveConflictsOnUpdate: pulumi.String("OVERWRITE"),
+		}, pulumi.DependsOn(c.managedNodeGroups))
 		if err != nil {
 			return fmt.Errorf("error registering EKS addon %s: %w", name, err)
 		}
@@ -0,0 +1,125 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"bi/pkg/cluster/util"
+)
+
+// ComponentNames returns the names of the components in the order they are
+// created.
+func ComponentNames() []string {
+	names := make([]string, 0, len(components))
+	for _, cmpnt := range components {
+		names = append(names, cmpnt.name)
+	}
+	return names
+}
+
+// ValidateComponentNames checks that all the names are known components.
+func ValidateComponentNames(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(ComponentNames(), name) {
+			return fmt.Errorf("unknown component %q, valid components are: %s", name, strings.Join(ComponentNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// UpComponents creates or updates only the named components. The outputs of
+// the other components are read from their existing stacks and every
+// dependency of a named component must already exist or be named too.
+func (e *eks) UpComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error {
+	if err := ValidateComponentNames(names); err != nil {
+		return err
+	}
+
+	pConfig, err := util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	stacks, err := e.loadStacks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, cmpnt := range components {
+		if !slices.Contains(names, cmpnt.name) {
+			continue
+		}
+
+		for _, dep := range cmpnt.dependsOn {
+			if !e.exists(dep) {
+				return fmt.Errorf("component %s depends on %s which doesn't exist", cmpnt.name, dep)
+			}
+		}
+
+		slog.Info("Updating component", slog.String("component", cmpnt.name))
+
+		if err := e.up(ctx, stacks[cmpnt.name], cmpnt, progressReporter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DestroyComponents destroys only the named components. A component can't be
+// destroyed while anything that depends on it still exists.
+func (e *eks) DestroyComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error {
+	if err := ValidateComponentNames(names); err != nil {
+		return err
+	}
+
+	pConfig, err := util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	stacks, err := e.loadStacks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, cmpnt := range components {
+		if slices.Contains(names, cmpnt.name) {
+			continue
+		}
+
+		for _, dep := range cmpnt.dependsOn {
+			if slices.Contains(names, dep) && e.exists(cmpnt.name) {
+				return fmt.Errorf("can't destroy %s while %s exists", dep, cmpnt.name)
+			}
+		}
+	}
+
+	for i := range components {
+		cmpnt := components[len(components)-1-i]
+		if !slices.Contains(names, cmpnt.name) {
+			continue
+		}
+
+		slog.Info("Destroying component", slog.String("component", cmpnt.name))
+
+		if err := e.destroy(ctx, stacks[cmpnt.name], cmpnt, progressReporter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exists returns true if the component stack has been created, going by
+// whether it has any outputs.
+func (e *eks) exists(name string) bool {
+	return len(e.outputs[name]) > 0
+}
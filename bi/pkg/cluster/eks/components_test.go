@@ -0,0 +1,25 @@
+package eks
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_componentDependencies(t *testing.T) {
+	// Dependencies must come before the component so that Up can create
+	// everything in order.
+	for i, cmpnt := range components {
+		for _, dep := range cmpnt.dependsOn {
+			j := slices.IndexFunc(components, func(c component) bool { return c.name == dep })
+			require.NotEqual(t, -1, j, "%s depends on unknown component %s", cmpnt.name, dep)
+			require.Less(t, j, i, "%s depends on %s which comes after it", cmpnt.name, dep)
+		}
+	}
+}
+
+func TestValidateComponentNames(t *testing.T) {
+	require.NoError(t, ValidateComponentNames([]string{"gateway", "karpenter"}))
+	require.Error(t, ValidateComponentNames([]string{"gateway", "nope"}))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -59,6 +60,11 @@ type Config struct {
 	ProjectBaseName,
 	Slug,
 	WorkDirRoot string
+	// BackendURL is the pulumi state backend shared by all the component
+	// stacks. If empty each component keeps its state in its work dir.
+	BackendURL string
+	// WireGuardStatePath is where the clients issued by AddClient are saved.
+	WireGuardStatePath string
 
 	Config     auto.ConfigMap
 	PulumiHome auto.LocalWorkspaceOption
@@ -86,17 +92,20 @@ type runnable interface {
 type component struct {
 	name string
 	runnable
+	// dependsOn are the components whose outputs this component uses
+	dependsOn []string
 }
 
 // this kind of stinks a map would be more convenient but they are specifically
 // unordered in go and we need these to run in order
 var components = []component{
-	{"vpc", &vpcConfig{}},
-	{"gateway", &gatewayConfig{}},
-	{"cluster", &clusterConfig{}},
-	{"lbcontroller", &lbControllerConfig{}},
-	{"karpenter", &karpenterConfig{}},
-	{"postgres", &cnpgConfig{}},
+	{"vpc", &vpcConfig{}, nil},
+	{"gateway", &gatewayConfig{}, []string{"vpc"}},
+	{"peers", &peersConfig{}, []string{"gateway"}},
+	{"cluster", &clusterConfig{}, []string{"vpc", "gateway"}},
+	{"lbcontroller", &lbControllerConfig{}, []string{"vpc", "cluster"}},
+	{"karpenter", &karpenterConfig{}, []string{"cluster"}},
+	{"postgres", &cnpgConfig{}, []string{"cluster"}},
 }
 
 func (e *eks) Up(ctx context.Context, progressReporter *util.ProgressReporter) error {
@@ -117,31 +126,57 @@ func (e *eks) Up(ctx context.Context, progressReporter *util.ProgressReporter) e
 			return fmt.Errorf("failed to configure component %s: %w", cmpnt.name, err)
 		}
 
-		if err := cmpnt.withOutputs(e.outputs); err != nil {
-			return fmt.Errorf("failed to set outputs for component %s: %w", cmpnt.name, err)
+		if err := e.up(ctx, stack, cmpnt, progressReporter); err != nil {
+			return err
 		}
+	}
 
-		if _, err := stack.Refresh(ctx); err != nil {
-			return fmt.Errorf("failed to refresh stack: %w", err)
-		}
+	return nil
+}
 
-		upOpts := []optup.Option{
-			optup.ProgressStreams(util.DebugLogWriter(ctx, slog.Default())),
-			optup.SuppressProgress(), // No progress dots
+// up creates or updates the resources of a single component stack using
+// the outputs of the components before it.
+func (e *eks) up(ctx context.Context, stack auto.Stack, cmpnt component, progressReporter *util.ProgressReporter) error {
+	// Components can also see their own current outputs, eg. so that the
+	// gateway keeps its keys.
+	if _, ok := e.outputs[cmpnt.name]; !ok {
+		out, err := stack.Outputs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get outputs for component %s: %w", cmpnt.name, err)
 		}
+		e.outputs[cmpnt.name] = out
+	}
 
-		if progressReporter != nil {
-			upOpts = append(upOpts, optup.EventStreams(progressReporter.ForPulumiEvents(cmpnt.name, false)))
-		}
+	if err := cmpnt.withOutputs(e.outputs); err != nil {
+		return fmt.Errorf("failed to set outputs for component %s: %w", cmpnt.name, err)
+	}
 
-		res, err := stack.Up(ctx, upOpts...)
-		if err != nil {
-			return fmt.Errorf("failed to create or update resources: %w", err)
+	if cmpnt.name == "vpc" {
+		if err := e.checkExistingVPCSwitch(ctx, stack); err != nil {
+			return err
 		}
+	}
+
+	if _, err := stack.Refresh(ctx); err != nil {
+		return fmt.Errorf("failed to refresh stack: %w", err)
+	}
+
+	upOpts := []optup.Option{
+		optup.ProgressStreams(util.DebugLogWriter(ctx, slog.Default())),
+		optup.SuppressProgress(), // No progress dots
+	}
+
+	if progressReporter != nil {
+		upOpts = append(upOpts, optup.EventStreams(progressReporter.ForPulumiEvents(cmpnt.name, false)))
+	}
 
-		e.outputs[cmpnt.name] = res.Outputs
+	res, err := stack.Up(ctx, upOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create or update resources: %w", err)
 	}
 
+	e.outputs[cmpnt.name] = res.Outputs
+
 	return nil
 }
 
@@ -154,55 +189,75 @@ func (e *eks) Destroy(ctx context.Context, progressReporter *util.ProgressReport
 	e.pConfig = pConfig
 
 	// we need to get the outputs for the previous components first
-	// so create all the stacks and get all of the outputs
+	stacks, err := e.loadStacks(ctx)
+	if err != nil {
+		return err
+	}
+
+	// then work backwards to destroy each stack
+	for i := range components {
+		cmpnt := components[len(components)-1-i]
+		stack := stacks[cmpnt.name]
+
+		if err := e.destroy(ctx, stack, cmpnt, progressReporter); err != nil {
+			return err
+		}
+	}
+
+	// TODO(jdt): do a final destroy on a new stack that cleans up any dangling
+	// resources (looking at you karpenter!)
+
+	return nil
+}
+
+// loadStacks creates all the component stacks and loads their current outputs.
+func (e *eks) loadStacks(ctx context.Context) (map[string]auto.Stack, error) {
 	stacks := make(map[string]auto.Stack)
 	for _, cmpnt := range components {
 		stack, err := e.createStack(ctx, cmpnt.name, cmpnt.run)
 		if err != nil {
-			return fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
+			return nil, fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
 		}
 
 		if err := e.configure(ctx, stack, cmpnt); err != nil {
-			return fmt.Errorf("failed to configure component %s: %w", cmpnt.name, err)
+			return nil, fmt.Errorf("failed to configure component %s: %w", cmpnt.name, err)
 		}
 
 		out, err := stack.Outputs(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get outputs for component %s: %w", cmpnt.name, err)
+			return nil, fmt.Errorf("failed to get outputs for component %s: %w", cmpnt.name, err)
 		}
 		e.outputs[cmpnt.name] = out
 		stacks[cmpnt.name] = stack
 	}
 
-	// then work backwards to destroy each stack
-	for i := range components {
-		cmpnt := components[len(components)-1-i]
-		stack := stacks[cmpnt.name]
+	return stacks, nil
+}
 
-		if err := cmpnt.withOutputs(e.outputs); err != nil {
-			return fmt.Errorf("failed to set outputs for component %s: %w", cmpnt.name, err)
-		}
+// destroy deletes the resources of a single component stack.
+func (e *eks) destroy(ctx context.Context, stack auto.Stack, cmpnt component, progressReporter *util.ProgressReporter) error {
+	if err := cmpnt.withOutputs(e.outputs); err != nil {
+		return fmt.Errorf("failed to set outputs for component %s: %w", cmpnt.name, err)
+	}
 
-		if _, err := stack.Refresh(ctx); err != nil {
-			return fmt.Errorf("failed to refresh stack: %w", err)
-		}
+	if _, err := stack.Refresh(ctx); err != nil {
+		return fmt.Errorf("failed to refresh stack: %w", err)
+	}
 
-		destroyOpts := []optdestroy.Option{
-			optdestroy.ProgressStreams(util.DebugLogWriter(ctx, slog.Default())),
-			optdestroy.SuppressProgress(), // No progress dots
-		}
+	destroyOpts := []optdestroy.Option{
+		optdestroy.ProgressStreams(util.DebugLogWriter(ctx, slog.Default())),
+		optdestroy.SuppressProgress(), // No progress dots
+	}
 
-		if progressReporter != nil {
-			destroyOpts = append(destroyOpts, optdestroy.EventStreams(progressReporter.ForPulumiEvents(cmpnt.name, true)))
-		}
+	if progressReporter != nil {
+		destroyOpts = append(destroyOpts, optdestroy.EventStreams(progressReporter.ForPulumiEvents(cmpnt.name, true)))
+	}
 
-		if _, err := stack.Destroy(ctx, destroyOpts...); err != nil {
-			return fmt.Errorf("failed to delete resources: %w", err)
-		}
+	if _, err := stack.Destroy(ctx, destroyOpts...); err != nil {
+		return fmt.Errorf("failed to delete resources: %w", err)
 	}
 
-	// TODO(jdt): do a final destroy on a new stack that cleans up any dangling
-	// resources (looking at you karpenter!)
+	delete(e.outputs, cmpnt.name)
 
 	return nil
 }
@@ -323,17 +378,43 @@ func (e *eks) WireGuardConfig(ctx context.Context, w io.Writer) (bool, error) {
 		}
 	}
 
+	gw, err := e.wireGuardGateway()
+	if err != nil {
+		return true, err
+	}
+
+	installerClient := wireguard.Client{
+		Gateway:    gw,
+		Name:       "installer",
+		PrivateKey: e.outputs["gateway"]["wgClientPrivateKey"].Value.(string),
+		Address:    netip.MustParseAddr(e.outputs["gateway"]["wgClientAddress"].Value.(string)),
+	}
+
+	if err := installerClient.WriteConfig(w); err != nil {
+		return true, fmt.Errorf("error writing wireguard config: %w", err)
+	}
+
+	return true, nil
+}
+
+// wireGuardGateway returns the gateway as seen by clients.
+func (e *eks) wireGuardGateway() (*wireguard.Gateway, error) {
 	gwEndpoint := net.JoinHostPort(e.outputs["gateway"]["publicIP"].Value.(string),
 		strconv.Itoa(int(e.outputs["gateway"]["publicPort"].Value.(float64))))
 
 	_, vpcSubnet, err := net.ParseCIDR(e.outputs["vpc"]["cidrBlock"].Value.(string))
 	if err != nil {
-		return true, fmt.Errorf("error parsing vpc subnet: %w", err)
+		return nil, fmt.Errorf("error parsing vpc subnet: %w", err)
+	}
+
+	address, err := e.gatewayOutputAddr("wgGatewayAddress")
+	if err != nil {
+		return nil, err
 	}
 
-	gw := wireguard.Gateway{
+	return &wireguard.Gateway{
 		PrivateKey: e.outputs["gateway"]["wgGatewayPrivateKey"].Value.(string),
-		Address:    netip.MustParseAddr(e.outputs["gateway"]["wgGatewayAddress"].Value.(string)),
+		Address:    address,
 		Endpoint:   gwEndpoint,
 		// Route53 static resolver addresses.
 		// See: https://docs.aws.amazon.com/vpc/latest/userguide/vpc-dns.html#AmazonDNS
@@ -341,20 +422,49 @@ func (e *eks) WireGuardConfig(ctx context.Context, w io.Writer) (bool, error) {
 			netip.MustParseAddr("169.254.169.253"),
 		},
 		VPCSubnets: []*net.IPNet{vpcSubnet},
+	}, nil
+}
+
+// WireGuardGatewayEndpoints returns the endpoint of every gateway instance,
+// the first being the one used in the wireguard config.
+func (e *eks) WireGuardGatewayEndpoints(ctx context.Context) ([]string, error) {
+	// Fetch the outputs from the Pulumi state (if needed).
+	if len(e.outputs) == 0 {
+		if err := e.Outputs(ctx, io.Discard); err != nil {
+			return nil, err
+		}
 	}
 
-	installerClient := wireguard.Client{
-		Gateway:    &gw,
-		Name:       "installer",
-		PrivateKey: e.outputs["gateway"]["wgClientPrivateKey"].Value.(string),
-		Address:    netip.MustParseAddr(e.outputs["gateway"]["wgClientAddress"].Value.(string)),
+	publicPort, ok := e.outputs["gateway"]["publicPort"].Value.(float64)
+	if !ok {
+		return nil, errors.New("gateway has no public port, run bi start to update it")
+	}
+	port := strconv.Itoa(int(publicPort))
+
+	// Stacks from before there could be more than one gateway only have publicIP.
+	var ips []string
+	if publicIPs, ok := e.outputs["gateway"]["publicIPs"].Value.([]interface{}); ok {
+		for _, v := range publicIPs {
+			ip, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected gateway public ip %v", v)
+			}
+			ips = append(ips, ip)
+		}
+	} else if ip, ok := e.outputs["gateway"]["publicIP"].Value.(string); ok {
+		ips = []string{ip}
 	}
 
-	if err := installerClient.WriteConfig(w); err != nil {
-		return true, fmt.Errorf("error writing wireguard config: %w", err)
+	if len(ips) == 0 {
+		return nil, errors.New("gateway has no public ips, run bi start to update it")
 	}
 
-	return true, nil
+	var endpoints []string
+	for _, ip := range ips {
+		endpoints = append(endpoints, net.JoinHostPort(ip, port))
+	}
+
+	return endpoints, nil
 }
 
 // createStack creates the stack with the given program
@@ -375,7 +485,7 @@ func (e *eks) createStack(ctx context.Context, name string, prog pulumi.RunFunc)
 		auto.Project(workspace.Project{
 			Name:    tokens.PackageName(projectName),
 			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
-			Backend: &workspace.ProjectBackend{URL: fmt.Sprintf("file://%s", workDir)},
+			Backend: &workspace.ProjectBackend{URL: e.backendURL(workDir)},
 		}),
 		auto.Program(prog),
 	)
@@ -392,6 +502,13 @@ func (e *eks) createStack(ctx context.Context, name string, prog pulumi.RunFunc)
 	return s, nil
 }
 
+func (e *eks) backendURL(workDir string) string {
+	if e.cfg.BackendURL != "" {
+		return e.cfg.BackendURL
+	}
+	return fmt.Sprintf("file://%s", workDir)
+}
+
 func (e *eks) configure(ctx context.Context, s auto.Stack, c component) error {
 	// we need the config set in the stack for e.g. providers
 	if err := s.SetAllConfig(ctx, e.cfg.Config); err != nil {
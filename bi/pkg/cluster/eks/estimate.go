@@ -0,0 +1,247 @@
+package eks
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"bi/pkg/cluster/util"
+)
+
+// HoursPerMonth is the number of hours AWS uses to turn hourly prices into
+// monthly ones.
+const HoursPerMonth = 730
+
+//go:embed prices.json
+var embeddedPrices []byte
+
+// PriceTable is the on-demand pricing for each region.
+type PriceTable map[string]RegionPrices
+
+// RegionPrices is the pricing for a single region in USD.
+type RegionPrices struct {
+	// Updated is the date the prices were last checked
+	Updated string `json:"updated"`
+	// AvailabilityZones is the number of AZs that support EKS, we create a
+	// public and private subnet in each.
+	AvailabilityZones int                `json:"availabilityZones"`
+	EKSClusterHourly  float64            `json:"eksClusterHourly"`
+	NATGatewayHourly  float64            `json:"natGatewayHourly"`
+	PublicIPv4Hourly  float64            `json:"publicIPv4Hourly"`
+	KMSKeyMonthly     float64            `json:"kmsKeyMonthly"`
+	S3GBMonthly       float64            `json:"s3GBMonthly"`
+	EBSGBMonthly      map[string]float64 `json:"ebsGBMonthly"`
+	EC2Hourly         map[string]float64 `json:"ec2Hourly"`
+}
+
+// LoadPriceTable returns the embedded price table. If path is set, the
+// regions in that file (in the same format) replace the embedded ones so the
+// prices can be updated without a new release.
+func LoadPriceTable(path string) (PriceTable, error) {
+	prices := PriceTable{}
+	if err := json.Unmarshal(embeddedPrices, &prices); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded price table: %w", err)
+	}
+
+	if path == "" {
+		return prices, nil
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table: %w", err)
+	}
+
+	overrides := PriceTable{}
+	if err := json.Unmarshal(bs, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+	}
+
+	maps.Copy(prices, overrides)
+
+	return prices, nil
+}
+
+// CostItem is a single priced resource.
+type CostItem struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit"`
+	Monthly     float64 `json:"monthly"`
+}
+
+// CostEstimate is the monthly cost of a single component.
+type CostEstimate struct {
+	Component string     `json:"component"`
+	Items     []CostItem `json:"items"`
+	Monthly   float64    `json:"monthly"`
+}
+
+// EstimateOptions are the usage based inputs that can't be worked out from
+// the config.
+type EstimateOptions struct {
+	// BackupGB is the expected size of the postgres backups
+	BackupGB float64
+}
+
+// Estimate prices the resources that Up would create for the config. Usage
+// based charges (data transfer, NAT processing, logs) and nodes launched by
+// karpenter aren't included as they depend on the workload.
+func Estimate(cfg *util.PulumiConfig, prices PriceTable, opts EstimateOptions) ([]CostEstimate, error) {
+	region := cfg.AWS.Region
+	rp, ok := prices[region]
+	if !ok {
+		return nil, fmt.Errorf("no prices for region %s, known regions are: %s",
+			region, strings.Join(slices.Sorted(maps.Keys(prices)), ", "))
+	}
+
+	e := &estimator{prices: rp, region: region}
+
+	estimates := []CostEstimate{
+		e.vpc(cfg),
+		e.gateway(cfg),
+		e.cluster(cfg),
+		e.lbController(cfg),
+		{Component: "karpenter"},
+		e.postgres(opts),
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	for i := range estimates {
+		for _, item := range estimates[i].Items {
+			estimates[i].Monthly += item.Monthly
+		}
+	}
+
+	return estimates, nil
+}
+
+// estimator keeps the first missing price so that each component doesn't
+// need to check.
+type estimator struct {
+	prices RegionPrices
+	region string
+	err    error
+}
+
+func (e *estimator) hourly(description, unit string, quantity, price float64) CostItem {
+	return CostItem{Description: description, Quantity: quantity, Unit: unit, Monthly: quantity * price * HoursPerMonth}
+}
+
+func (e *estimator) instance(description, instanceType string, count int) CostItem {
+	price, ok := e.prices.EC2Hourly[instanceType]
+	if !ok && e.err == nil {
+		e.err = fmt.Errorf("no price for instance type %s in region %s", instanceType, e.region)
+	}
+
+	return CostItem{
+		Description: fmt.Sprintf("%s (%s)", description, instanceType),
+		Quantity:    float64(count),
+		Unit:        "instances",
+		Monthly:     float64(count) * price * HoursPerMonth,
+	}
+}
+
+func (e *estimator) volume(description, volumeType string, sizeGB, count int) CostItem {
+	price, ok := e.prices.EBSGBMonthly[volumeType]
+	if !ok && e.err == nil {
+		e.err = fmt.Errorf("no price for volume type %s in region %s", volumeType, e.region)
+	}
+
+	return CostItem{
+		Description: fmt.Sprintf("%s (%s)", description, volumeType),
+		Quantity:    float64(sizeGB * count),
+		Unit:        "GB",
+		Monthly:     float64(sizeGB*count) * price,
+	}
+}
+
+func (e *estimator) publicSubnets(cfg *util.PulumiConfig) int {
+	if cfg.VPC.Existing() {
+		return len(cfg.VPC.PublicSubnetIDs)
+	}
+	return e.prices.AvailabilityZones
+}
+
+func (e *estimator) vpc(cfg *util.PulumiConfig) CostEstimate {
+	est := CostEstimate{Component: "vpc"}
+
+	// Nothing is created when using an existing VPC.
+	if cfg.VPC.Existing() {
+		return est
+	}
+
+	est.Items = []CostItem{
+		e.hourly("NAT gateway", "gateways", 1, e.prices.NATGatewayHourly),
+		e.hourly("NAT gateway elastic IP", "IPs", 1, e.prices.PublicIPv4Hourly),
+	}
+
+	return est
+}
+
+func (e *estimator) gateway(cfg *util.PulumiConfig) CostEstimate {
+	return CostEstimate{
+		Component: "gateway",
+		Items: []CostItem{
+			e.instance("wireguard gateway", cfg.Gateway.InstanceType, cfg.Gateway.Count),
+			e.volume("wireguard gateway root volume", cfg.Gateway.VolumeType, cfg.Gateway.VolumeSize, cfg.Gateway.Count),
+			e.hourly("wireguard gateway elastic IP", "IPs", float64(cfg.Gateway.Count), e.prices.PublicIPv4Hourly),
+		},
+	}
+}
+
+func (e *estimator) cluster(cfg *util.PulumiConfig) CostEstimate {
+	est := CostEstimate{
+		Component: "cluster",
+		Items: []CostItem{
+			e.hourly("EKS control plane", "clusters", 1, e.prices.EKSClusterHourly),
+			{Description: "KMS key", Quantity: 1, Unit: "keys", Monthly: e.prices.KMSKeyMonthly},
+		},
+	}
+
+	for _, ng := range cfg.Cluster.NodeGroups {
+		// Priced at the first (preferred) instance type and on-demand rates
+		// so spot groups are an upper bound.
+		description := fmt.Sprintf("node group %s", ng.Name)
+		if ng.CapacityType == "SPOT" {
+			description += " spot"
+		}
+
+		est.Items = append(est.Items,
+			e.instance(description, ng.InstanceTypes[0], ng.DesiredSize),
+			e.volume(fmt.Sprintf("node group %s root volumes", ng.Name), ng.VolumeType, ng.VolumeSize, ng.DesiredSize),
+		)
+	}
+
+	return est
+}
+
+func (e *estimator) lbController(cfg *util.PulumiConfig) CostEstimate {
+	subnets := e.publicSubnets(cfg)
+
+	return CostEstimate{
+		Component: "lbcontroller",
+		Items: []CostItem{
+			e.hourly("ingress elastic IPs", "IPs", float64(subnets), e.prices.PublicIPv4Hourly),
+		},
+	}
+}
+
+func (e *estimator) postgres(opts EstimateOptions) CostEstimate {
+	return CostEstimate{
+		Component: "postgres",
+		Items: []CostItem{{
+			Description: "backup bucket storage",
+			Quantity:    opts.BackupGB,
+			Unit:        "GB",
+			Monthly:     opts.BackupGB * e.prices.S3GBMonthly,
+		}},
+	}
+}
@@ -0,0 +1,101 @@
+package eks
+
+import (
+	"testing"
+
+	"bi/pkg/cluster/util"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/stretchr/testify/require"
+)
+
+func testPulumiConfig(t *testing.T, overrides map[string]string) *util.PulumiConfig {
+	cfg := auto.ConfigMap{
+		"aws:defaultTags":        {Value: `{"tags":{}}`},
+		"aws:region":             {Value: "us-east-2"},
+		"cluster:amiType":        {Value: "AL2023_x86_64_STANDARD"},
+		"cluster:capacityType":   {Value: "ON_DEMAND"},
+		"cluster:desiredSize":    {Value: "2"},
+		"cluster:instanceType":   {Value: "t3a.medium"},
+		"cluster:maxSize":        {Value: "4"},
+		"cluster:minSize":        {Value: "2"},
+		"cluster:name":           {Value: "test"},
+		"cluster:version":        {Value: "1.34"},
+		"cluster:volumeSize":     {Value: "20"},
+		"cluster:volumeType":     {Value: "gp3"},
+		"gateway:cidrBlock":      {Value: "100.64.250.0/24"},
+		"gateway:count":          {Value: "1"},
+		"gateway:generateSSHKey": {Value: "false"},
+		"gateway:instanceType":   {Value: "t3a.micro"},
+		"gateway:port":           {Value: "51820"},
+		"gateway:volumeSize":     {Value: "12"},
+		"gateway:volumeType":     {Value: "gp3"},
+		"karpenter:namespace":    {Value: "battery-base"},
+		"lbcontroller:namespace": {Value: "battery-base"},
+		"vpc:cidrBlock":          {Value: "100.64.0.0/16"},
+	}
+	for k, v := range overrides {
+		cfg[k] = auto.ConfigValue{Value: v}
+	}
+
+	pc, err := util.ParsePulumiConfig(cfg)
+	require.NoError(t, err)
+	return pc
+}
+
+func TestEstimate(t *testing.T) {
+	prices, err := LoadPriceTable("")
+	require.NoError(t, err)
+
+	monthly := func(estimates []CostEstimate) map[string]float64 {
+		out := map[string]float64{}
+		for _, e := range estimates {
+			out[e.Component] = e.Monthly
+		}
+		return out
+	}
+
+	estimates, err := Estimate(testPulumiConfig(t, nil), prices, EstimateOptions{BackupGB: 10})
+	require.NoError(t, err)
+
+	got := monthly(estimates)
+	// NAT gateway + its IP
+	require.InDelta(t, (0.045+0.005)*HoursPerMonth, got["vpc"], 0.001)
+	// t3a.micro + 12GB gp3 + IP
+	require.InDelta(t, (0.0094+0.005)*HoursPerMonth+12*0.08, got["gateway"], 0.001)
+	// control plane + KMS key + 2 * (t3a.medium + 20GB gp3)
+	require.InDelta(t, 0.10*HoursPerMonth+1+2*(0.0376*HoursPerMonth+20*0.08), got["cluster"], 0.001)
+	// an IP per AZ
+	require.InDelta(t, 3*0.005*HoursPerMonth, got["lbcontroller"], 0.001)
+	require.InDelta(t, 10*0.023, got["postgres"], 0.001)
+
+	t.Run("existing vpc", func(t *testing.T) {
+		estimates, err := Estimate(testPulumiConfig(t, map[string]string{
+			"vpc:id":               "vpc-1",
+			"vpc:publicSubnetIDs":  `["subnet-a","subnet-b"]`,
+			"vpc:privateSubnetIDs": `["subnet-c","subnet-d"]`,
+		}), prices, EstimateOptions{})
+		require.NoError(t, err)
+
+		got := monthly(estimates)
+		require.Zero(t, got["vpc"])
+		require.InDelta(t, 2*0.005*HoursPerMonth, got["lbcontroller"], 0.001)
+	})
+
+	t.Run("multiple gateways", func(t *testing.T) {
+		estimates, err := Estimate(testPulumiConfig(t, map[string]string{"gateway:count": "3"}), prices, EstimateOptions{})
+		require.NoError(t, err)
+
+		require.InDelta(t, 3*((0.0094+0.005)*HoursPerMonth+12*0.08), monthly(estimates)["gateway"], 0.001)
+	})
+
+	t.Run("unknown instance type", func(t *testing.T) {
+		_, err := Estimate(testPulumiConfig(t, map[string]string{"gateway:instanceType": "x9.huge"}), prices, EstimateOptions{})
+		require.ErrorContains(t, err, "no price for instance type x9.huge in region us-east-2")
+	})
+
+	t.Run("unknown region", func(t *testing.T) {
+		_, err := Estimate(testPulumiConfig(t, map[string]string{"aws:region": "ap-south-2"}), prices, EstimateOptions{})
+		require.ErrorContains(t, err, "no prices for region ap-south-2")
+	})
+}
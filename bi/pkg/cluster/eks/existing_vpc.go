@@ -0,0 +1,119 @@
+package eks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// minSubnetAZs is the number of availability zones each type of subnet must
+// span. EKS requires subnets in at least two AZs for the control plane.
+const minSubnetAZs = 2
+
+// existingSubnet is what we need to know about a subnet to check it can be used.
+type existingSubnet struct {
+	ID               string
+	VpcID            string
+	AvailabilityZone string
+	Tags             map[string]string
+}
+
+// lookupExisting reads an existing VPC and its subnets instead of creating
+// them. No resources are registered so destroying the stack leaves the VPC
+// alone. The outputs are the same as those of a created VPC.
+func (v *vpcConfig) lookupExisting(ctx *pulumi.Context) error {
+	vpc, err := ec2.LookupVpc(ctx, &ec2.LookupVpcArgs{Id: pulumi.StringRef(v.existingID)})
+	if err != nil {
+		return fmt.Errorf("error looking up EC2 VPC %s: %w", v.existingID, err)
+	}
+
+	subnets := make(map[string][]existingSubnet)
+	for _, t := range subnetTypes {
+		for _, id := range v.existingSubnetIDs[t] {
+			subnet, err := ec2.LookupSubnet(ctx, &ec2.LookupSubnetArgs{Id: pulumi.StringRef(id)})
+			if err != nil {
+				return fmt.Errorf("error looking up EC2 subnet %s: %w", id, err)
+			}
+
+			subnets[t] = append(subnets[t], existingSubnet{
+				ID:               subnet.Id,
+				VpcID:            subnet.VpcId,
+				AvailabilityZone: subnet.AvailabilityZone,
+				Tags:             subnet.Tags,
+			})
+		}
+	}
+
+	if err := validateExistingSubnets(v.baseName, vpc.Id, subnets); err != nil {
+		return fmt.Errorf("existing VPC %s can't be used: %w", vpc.Id, err)
+	}
+
+	ctx.Export("vpcID", pulumi.String(vpc.Id))
+	ctx.Export("publicSubnetIDs", pulumi.ToStringArray(v.existingSubnetIDs["public"]))
+	ctx.Export("privateSubnetIDs", pulumi.ToStringArray(v.existingSubnetIDs["private"]))
+	ctx.Export("cidrBlock", pulumi.String(vpc.CidrBlock))
+
+	return nil
+}
+
+// validateExistingSubnets checks the subnets are in the VPC, are spread over
+// enough AZs and carry the tags that the load balancer controller and
+// karpenter use to discover them (the same tags we put on subnets we create).
+func validateExistingSubnets(clusterName, vpcID string, subnets map[string][]existingSubnet) error {
+	requiredTags := map[string]map[string]string{
+		"public": {"kubernetes.io/role/elb": "1"},
+		"private": {
+			"kubernetes.io/role/internal-elb": "1",
+			"karpenter.sh/discovery":          clusterName,
+		},
+	}
+
+	var errs []error
+	for _, t := range subnetTypes {
+		azs := map[string]struct{}{}
+		for _, subnet := range subnets[t] {
+			if subnet.VpcID != vpcID {
+				errs = append(errs, fmt.Errorf("%s subnet %s is in VPC %s", t, subnet.ID, subnet.VpcID))
+			}
+
+			for k, want := range requiredTags[t] {
+				if got, ok := subnet.Tags[k]; !ok || got != want {
+					errs = append(errs, fmt.Errorf("%s subnet %s must be tagged %s=%s", t, subnet.ID, k, want))
+				}
+			}
+
+			azs[subnet.AvailabilityZone] = struct{}{}
+		}
+
+		if len(azs) < minSubnetAZs {
+			errs = append(errs, fmt.Errorf("%s subnets must span at least %d availability zones, got %d", t, minSubnetAZs, len(azs)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkExistingVPCSwitch stops an install that created its own VPC from being
+// pointed at an existing one, as updating the stack would delete the VPC it
+// created out from under everything else.
+func (e *eks) checkExistingVPCSwitch(ctx context.Context, stack auto.Stack) error {
+	if !e.pConfig.VPC.Existing() {
+		return nil
+	}
+
+	out, err := stack.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get outputs for component vpc: %w", err)
+	}
+
+	id, _ := out["vpcID"].Value.(string)
+	if id != "" && id != e.pConfig.VPC.ID {
+		return fmt.Errorf("install already has VPC %s, destroy it before switching to existing VPC %s", id, e.pConfig.VPC.ID)
+	}
+
+	return nil
+}
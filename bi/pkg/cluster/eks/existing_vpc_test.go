@@ -0,0 +1,84 @@
+package eks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateExistingSubnets(t *testing.T) {
+	publicTags := map[string]string{"kubernetes.io/role/elb": "1"}
+	privateTags := map[string]string{
+		"kubernetes.io/role/internal-elb": "1",
+		"karpenter.sh/discovery":          "test",
+	}
+
+	valid := func() map[string][]existingSubnet {
+		return map[string][]existingSubnet{
+			"public": {
+				{ID: "subnet-pub-a", VpcID: "vpc-1", AvailabilityZone: "us-east-2a", Tags: publicTags},
+				{ID: "subnet-pub-b", VpcID: "vpc-1", AvailabilityZone: "us-east-2b", Tags: publicTags},
+			},
+			"private": {
+				{ID: "subnet-priv-a", VpcID: "vpc-1", AvailabilityZone: "us-east-2a", Tags: privateTags},
+				{ID: "subnet-priv-b", VpcID: "vpc-1", AvailabilityZone: "us-east-2b", Tags: privateTags},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(map[string][]existingSubnet)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(map[string][]existingSubnet) {},
+		},
+		{
+			name: "wrong vpc",
+			modify: func(s map[string][]existingSubnet) {
+				s["public"][1].VpcID = "vpc-2"
+			},
+			wantErr: "public subnet subnet-pub-b is in VPC vpc-2",
+		},
+		{
+			name: "single az",
+			modify: func(s map[string][]existingSubnet) {
+				s["private"][1].AvailabilityZone = "us-east-2a"
+			},
+			wantErr: "private subnets must span at least 2 availability zones, got 1",
+		},
+		{
+			name: "missing elb tag",
+			modify: func(s map[string][]existingSubnet) {
+				s["public"][0].Tags = map[string]string{}
+			},
+			wantErr: "public subnet subnet-pub-a must be tagged kubernetes.io/role/elb=1",
+		},
+		{
+			name: "karpenter discovery tag for another cluster",
+			modify: func(s map[string][]existingSubnet) {
+				s["private"][0].Tags = map[string]string{
+					"kubernetes.io/role/internal-elb": "1",
+					"karpenter.sh/discovery":          "other",
+				}
+			},
+			wantErr: "private subnet subnet-priv-a must be tagged karpenter.sh/discovery=test",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnets := valid()
+			tt.modify(subnets)
+
+			err := validateExistingSubnets("test", "vpc-1", subnets)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -21,9 +21,11 @@ import (
 type gatewayConfig struct {
 	// config
 	baseName         string
+	region           string
 	wireguardName    string
 	vpcCidrBlock     *net.IPNet
 	gatewayCIDRBlock *net.IPNet
+	count            int
 	generateSSHKey   bool
 	instanceType     string
 	port             int
@@ -34,19 +36,30 @@ type gatewayConfig struct {
 	vpcID            string
 	publicSubnetIDs  []string
 	privateSubnetIDs []string
+	// the keys from the last update, so that clients keep working
+	gatewayPrivateKey string
+	clientPrivateKey  string
+
+	// rotateGatewayKey and rotateClientKey replace the keys on the next
+	// update, they are set by RotateGateway and RotateClient.
+	rotateGatewayKey bool
+	rotateClientKey  bool
 
 	// state
-	securityGroupID, iamProfileID, ec2InstanceID pulumi.IDOutput
-	privateKey                                   *tls.PrivateKey
-	keypair                                      *ec2.KeyPair
-	publicIP                                     pulumi.StringOutput
-	wgGateway                                    *wireguard.Gateway
-	wgClient                                     *wireguard.Client
+	securityGroupID, iamProfileID pulumi.IDOutput
+	ec2InstanceIDs                []pulumi.IDOutput
+	privateKey                    *tls.PrivateKey
+	keypair                       *ec2.KeyPair
+	publicIPs                     []pulumi.StringOutput
+	wgGateway                     *wireguard.Gateway
+	wgClient                      *wireguard.Client
 }
 
 func (g *gatewayConfig) withConfig(cfg *util.PulumiConfig) error {
 	g.baseName = cfg.Cluster.Name
+	g.region = cfg.AWS.Region
 	g.gatewayCIDRBlock = cfg.Gateway.CIDRBlock
+	g.count = cfg.Gateway.Count
 	g.generateSSHKey = cfg.Gateway.GenerateSSHKey
 	g.instanceType = cfg.Gateway.InstanceType
 	g.port = cfg.Gateway.Port
@@ -72,6 +85,25 @@ func (g *gatewayConfig) withOutputs(outputs map[string]auto.OutputMap) error {
 		g.privateSubnetIDs = util.ToStringSlice(outputs["vpc"]["privateSubnetIDs"].Value)
 	}
 
+	g.gatewayPrivateKey, _ = outputs["gateway"]["wgGatewayPrivateKey"].Value.(string)
+	g.clientPrivateKey, _ = outputs["gateway"]["wgClientPrivateKey"].Value.(string)
+
+	if g.rotateGatewayKey {
+		g.gatewayPrivateKey = ""
+	}
+	if g.rotateClientKey {
+		g.clientPrivateKey = ""
+	}
+
+	// An existing VPC may not use the configured CIDR.
+	if cidrBlock, ok := outputs["vpc"]["cidrBlock"].Value.(string); ok {
+		_, vpcCidrBlock, err := net.ParseCIDR(cidrBlock)
+		if err != nil {
+			return fmt.Errorf("error parsing vpc cidr block: %w", err)
+		}
+		g.vpcCidrBlock = vpcCidrBlock
+	}
+
 	return nil
 }
 
@@ -89,7 +121,9 @@ func (g *gatewayConfig) run(ctx *pulumi.Context) error {
 		}
 	}
 
-	ctx.Export("publicIP", g.publicIP)
+	// publicIP is the primary gateway, publicIPs are all of them in order.
+	ctx.Export("publicIP", g.publicIPs[0])
+	ctx.Export("publicIPs", pulumi.ToStringArrayOutput(g.publicIPs))
 	ctx.Export("publicPort", pulumi.Int(g.port))
 	ctx.Export("securityGroupID", g.securityGroupID)
 
@@ -229,6 +263,27 @@ func (g *gatewayConfig) buildIAMProfile(ctx *pulumi.Context) error {
 		return fmt.Errorf("error registering IAM role %s: %w", g.wireguardName, err)
 	}
 
+	peersPolicy, err := iam.GetPolicyDocument(ctx, &iam.GetPolicyDocumentArgs{
+		Statements: []iam.GetPolicyDocumentStatement{
+			{
+				Sid:       pulumi.StringRef("WireguardReadPeers"),
+				Actions:   []string{"ssm:GetParameter"},
+				Resources: []string{"arn:aws:ssm:*:*:parameter" + peersParameterName(g.baseName)},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error generating IAM policy WireguardReadPeers: %w", err)
+	}
+
+	_, err = iam.NewRolePolicy(ctx, g.wireguardName+"-peers", &iam.RolePolicyArgs{
+		Role:   role.Name,
+		Policy: pulumi.String(peersPolicy.Json),
+	})
+	if err != nil {
+		return fmt.Errorf("error registering IAM role policy %s-peers: %w", g.wireguardName, err)
+	}
+
 	profile, err := iam.NewInstanceProfile(ctx, g.wireguardName, &iam.InstanceProfileArgs{
 		Role: role.Name,
 		Tags: pulumi.StringMap{"Name": pulumi.String(g.wireguardName)},
@@ -285,54 +340,78 @@ func (g *gatewayConfig) buildEC2Instance(ctx *pulumi.Context) error {
 		keyName = g.keypair.KeyName
 	}
 
-	instance, err := ec2.NewInstance(ctx, g.wireguardName, &ec2.InstanceArgs{
-		Ami:                 pulumi.String(ami.Id),
-		SubnetId:            pulumi.String(g.publicSubnetIDs[0]),
-		InstanceType:        pulumi.String(g.instanceType),
-		VpcSecurityGroupIds: pulumi.StringArray{g.securityGroupID},
-		IamInstanceProfile:  g.iamProfileID,
-		KeyName:             keyName,
-		Tags:                pulumi.StringMap{"Name": pulumi.String(g.wireguardName)},
-		UserData:            conf.Rendered,
-		MetadataOptions: ec2.InstanceMetadataOptionsArgs{
-			HttpTokens: pulumi.String("required"),
-		},
+	// Every instance gets the same cloud config, and so the same wireguard
+	// keys and peers, so clients can use any of them.
+	for i := range g.count {
+		name := g.instanceName(i)
+
+		instance, err := ec2.NewInstance(ctx, name, &ec2.InstanceArgs{
+			Ami: pulumi.String(ami.Id),
+			// Spread the instances across the AZs.
+			SubnetId:            pulumi.String(g.publicSubnetIDs[i%len(g.publicSubnetIDs)]),
+			InstanceType:        pulumi.String(g.instanceType),
+			VpcSecurityGroupIds: pulumi.StringArray{g.securityGroupID},
+			IamInstanceProfile:  g.iamProfileID,
+			KeyName:             keyName,
+			Tags:                pulumi.StringMap{"Name": pulumi.String(name)},
+			UserData:            conf.Rendered,
+			MetadataOptions: ec2.InstanceMetadataOptionsArgs{
+				HttpTokens: pulumi.String("required"),
+			},
+			// Failed hosts are recovered by EC2 keeping the same instance and IP.
+			MaintenanceOptions: ec2.InstanceMaintenanceOptionsArgs{
+				AutoRecovery: pulumi.String("default"),
+			},
 
-		RootBlockDevice: ec2.InstanceRootBlockDeviceArgs{
-			VolumeType: pulumi.String(g.volumeType),
-			VolumeSize: pulumi.Int(g.volumeSize),
-		},
-	}, pulumi.ReplaceOnChanges([]string{"userData"}), pulumi.IgnoreChanges([]string{"ami"}))
-	if err != nil {
-		return fmt.Errorf("error registering EC2 instance %s: %w", g.wireguardName, err)
-	}
+			RootBlockDevice: ec2.InstanceRootBlockDeviceArgs{
+				VolumeType: pulumi.String(g.volumeType),
+				VolumeSize: pulumi.Int(g.volumeSize),
+			},
+		}, pulumi.ReplaceOnChanges([]string{"userData"}), pulumi.IgnoreChanges([]string{"ami"}))
+		if err != nil {
+			return fmt.Errorf("error registering EC2 instance %s: %w", name, err)
+		}
 
-	g.ec2InstanceID = instance.ID()
+		g.ec2InstanceIDs = append(g.ec2InstanceIDs, instance.ID())
+	}
 
 	return nil
 }
 
 func (g *gatewayConfig) buildEIP(ctx *pulumi.Context) error {
-	eip, err := ec2.NewEip(ctx, g.wireguardName, &ec2.EipArgs{
-		Tags: pulumi.StringMap{"Name": pulumi.String(g.wireguardName)},
-	})
-	if err != nil {
-		return fmt.Errorf("error registering EC2 elastic IP %s: %w", g.wireguardName, err)
-	}
+	for i, instanceID := range g.ec2InstanceIDs {
+		name := g.instanceName(i)
+
+		eip, err := ec2.NewEip(ctx, name, &ec2.EipArgs{
+			Tags: pulumi.StringMap{"Name": pulumi.String(name)},
+		})
+		if err != nil {
+			return fmt.Errorf("error registering EC2 elastic IP %s: %w", name, err)
+		}
 
-	g.publicIP = eip.PublicIp
+		g.publicIPs = append(g.publicIPs, eip.PublicIp)
 
-	_, err = ec2.NewEipAssociation(ctx, g.wireguardName, &ec2.EipAssociationArgs{
-		InstanceId:   g.ec2InstanceID,
-		AllocationId: eip.ID(),
-	})
-	if err != nil {
-		return fmt.Errorf("error registering EC2 elastic IP association %s: %w", g.wireguardName, err)
+		_, err = ec2.NewEipAssociation(ctx, name, &ec2.EipAssociationArgs{
+			InstanceId:   instanceID,
+			AllocationId: eip.ID(),
+		})
+		if err != nil {
+			return fmt.Errorf("error registering EC2 elastic IP association %s: %w", name, err)
+		}
 	}
 
 	return nil
 }
 
+// instanceName keeps the name of the first instance the same as when there
+// was only ever one gateway so that existing instances aren't replaced.
+func (g *gatewayConfig) instanceName(i int) string {
+	if i == 0 {
+		return g.wireguardName
+	}
+	return fmt.Sprintf("%s-%d", g.wireguardName, i)
+}
+
 func (g *gatewayConfig) buildWireGuardConfig(ctx *pulumi.Context) error {
 	var err error
 	g.wgGateway, err = wireguard.NewGateway(uint16(g.port), g.gatewayCIDRBlock)
@@ -340,6 +419,12 @@ func (g *gatewayConfig) buildWireGuardConfig(ctx *pulumi.Context) error {
 		return fmt.Errorf("failed to create wireguard gateway: %w", err)
 	}
 
+	// Peers added at runtime are only given the gateway public key, so it
+	// mustn't change when the gateway is updated.
+	if g.gatewayPrivateKey != "" {
+		g.wgGateway.PrivateKey = g.gatewayPrivateKey
+	}
+
 	g.wgGateway.PostUp = []string{
 		"iptables -A FORWARD -i wg0 -j ACCEPT",
 		"iptables -t nat -A POSTROUTING -o ens5 -j MASQUERADE",
@@ -355,6 +440,11 @@ func (g *gatewayConfig) buildWireGuardConfig(ctx *pulumi.Context) error {
 		return fmt.Errorf("failed to create wireguard client for installer: %w", err)
 	}
 
+	if g.clientPrivateKey != "" {
+		g.wgGateway.Clients[0].PrivateKey = g.clientPrivateKey
+		g.wgClient.PrivateKey = g.clientPrivateKey
+	}
+
 	return nil
 }
 
@@ -375,24 +465,98 @@ func (g *gatewayConfig) buildCloudConfig() (*cloudConfig, error) {
 			"software-properties-common",
 			"ufw",
 		},
-		RunCmd: [][]string{{"sudo", "sysctl", "-p"}},
+		RunCmd: [][]string{
+			{"sudo", "sysctl", "-p"},
+			{"systemctl", "daemon-reload"},
+			{"systemctl", "enable", "--now", "bi-wg-peers.timer"},
+		},
 		Snap: snap{Commands: [][]string{
 			{"snap", "install", "amazon-ssm-agent", "--classic"},
 			{"snap", "start", "amazon-ssm-agent"},
+			{"snap", "install", "aws-cli", "--classic"},
 		}},
 		Wireguard: wg{Interfaces: []wgInterface{{
 			Name:       "wg0",
 			ConfigPath: "/etc/wireguard/wg0.conf",
 			Content:    sb.String(),
 		}}},
-		WriteFiles: []writeFile{{
-			Append:  true,
-			Content: "net.ipv4.ip_forward=1",
-			Path:    "/etc/sysctl.d/99-sysctl.conf",
-		}},
+		WriteFiles: []writeFile{
+			{
+				Append:  true,
+				Content: "net.ipv4.ip_forward=1",
+				Path:    "/etc/sysctl.d/99-sysctl.conf",
+			},
+			// The agent appends the peers from SSM to the base config.
+			{
+				Content:     sb.String(),
+				Path:        "/etc/wireguard/wg0.base.conf",
+				Permissions: "0600",
+			},
+			{
+				Content:     fmt.Sprintf(peersAgentScript, g.region, peersParameterName(g.baseName)),
+				Path:        "/usr/local/bin/bi-wg-peers",
+				Permissions: "0755",
+			},
+			{
+				Content: peersAgentService,
+				Path:    "/etc/systemd/system/bi-wg-peers.service",
+			},
+			{
+				Content: peersAgentTimer,
+				Path:    "/etc/systemd/system/bi-wg-peers.timer",
+			},
+		},
 	}, nil
 }
 
+// peersAgentScript syncs the wireguard peers with the SSM parameter managed
+// by the peers component. The interface is updated in place so existing
+// connections aren't dropped.
+const peersAgentScript = `#!/bin/bash
+set -euo pipefail
+
+if ! peers=$(aws ssm get-parameter --region %s --name %s --query Parameter.Value --output text 2>&1); then
+  case "$peers" in
+    *ParameterNotFound*) peers="" ;;
+    # Keep the current peers if SSM can't be reached.
+    *) echo "$peers" >&2; exit 1 ;;
+  esac
+fi
+
+next=$(mktemp)
+trap 'rm -f "$next"' EXIT
+
+cat /etc/wireguard/wg0.base.conf > "$next"
+printf '\n%%s\n' "$peers" >> "$next"
+
+if cmp -s "$next" /etc/wireguard/wg0.conf; then
+  exit 0
+fi
+
+install -m 0600 "$next" /etc/wireguard/wg0.conf
+wg syncconf wg0 <(wg-quick strip wg0)
+`
+
+const peersAgentService = `[Unit]
+Description=Sync wireguard peers from SSM
+After=wg-quick@wg0.service
+
+[Service]
+Type=oneshot
+ExecStart=/usr/local/bin/bi-wg-peers
+`
+
+const peersAgentTimer = `[Unit]
+Description=Sync wireguard peers from SSM every 30 seconds
+
+[Timer]
+OnBootSec=30s
+OnUnitActiveSec=30s
+
+[Install]
+WantedBy=timers.target
+`
+
 type cloudConfig struct {
 	PackageRebootIfRequired bool        `json:"package_reboot_if_required,omitempty"`
 	PackageUpdate           bool        `json:"package_update,omitempty"`
@@ -419,7 +583,8 @@ type wgInterface struct {
 }
 
 type writeFile struct {
-	Append  bool   `json:"append"`
-	Content string `json:"content"`
-	Path    string `json:"path"`
+	Append      bool   `json:"append"`
+	Content     string `json:"content"`
+	Path        string `json:"path"`
+	Permissions string `json:"permissions,omitempty"`
 }
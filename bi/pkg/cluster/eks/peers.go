@@ -0,0 +1,306 @@
+package eks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/wireguard"
+
+	noisysocketstypes "github.com/noisysockets/noisysockets/types"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ssm"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Peer is a wireguard client added to a running gateway. Only the public key
+// is kept, the private key is handed to whoever the peer is for.
+type Peer struct {
+	Name      string     `json:"name"`
+	PublicKey string     `json:"publicKey"`
+	Address   netip.Addr `json:"address"`
+	// Client is set for the peers of clients issued by AddClient, which are
+	// managed with the client commands rather than as peers.
+	Client bool `json:"client,omitempty"`
+}
+
+// peersConfig manages the SSM parameter that the agent on each gateway
+// instance polls for extra peers, so they can be changed without replacing
+// the instances.
+type peersConfig struct {
+	// config
+	baseName string
+
+	// from outputs
+	peers []Peer
+
+	// update changes the peers before the stack is updated, it is set by
+	// AddPeer and RemovePeer.
+	update func([]Peer) ([]Peer, error)
+}
+
+func (p *peersConfig) withConfig(cfg *util.PulumiConfig) error {
+	p.baseName = cfg.Cluster.Name
+
+	return nil
+}
+
+func (p *peersConfig) withOutputs(outputs map[string]auto.OutputMap) error {
+	p.peers = nil
+
+	if s, ok := outputs["peers"]["peers"].Value.(string); ok {
+		if err := json.Unmarshal([]byte(s), &p.peers); err != nil {
+			return fmt.Errorf("error parsing peers: %w", err)
+		}
+	}
+
+	if p.update != nil {
+		peers, err := p.update(p.peers)
+		if err != nil {
+			return err
+		}
+		p.peers = peers
+	}
+
+	return nil
+}
+
+func (p *peersConfig) run(ctx *pulumi.Context) error {
+	name := peersParameterName(p.baseName)
+
+	_, err := ssm.NewParameter(ctx, p.baseName+"-wireguard-peers", &ssm.ParameterArgs{
+		Name:        pulumi.String(name),
+		Description: pulumi.String("Extra wireguard peers for the gateway"),
+		Type:        pulumi.String("String"),
+		// Automatically moves to the advanced tier if there are too many peers
+		// for a standard parameter.
+		Tier:  pulumi.String("Intelligent-Tiering"),
+		Value: pulumi.String(renderPeers(p.peers)),
+		Tags:  pulumi.StringMap{"Name": pulumi.String(name)},
+	})
+	if err != nil {
+		return fmt.Errorf("error registering SSM parameter %s: %w", name, err)
+	}
+
+	peers, err := json.Marshal(p.peers)
+	if err != nil {
+		return fmt.Errorf("error marshalling peers: %w", err)
+	}
+
+	ctx.Export("peers", pulumi.String(string(peers)))
+
+	return nil
+}
+
+// peersParameterName is the SSM parameter the gateway agent reads.
+func peersParameterName(baseName string) string {
+	return fmt.Sprintf("/bi/%s/wireguard/peers", baseName)
+}
+
+// renderPeers returns the [Peer] sections that the gateway agent appends to
+// its base config. SSM doesn't allow empty parameters.
+func renderPeers(peers []Peer) string {
+	if len(peers) == 0 {
+		return "# no peers\n"
+	}
+
+	var sb strings.Builder
+	for _, peer := range peers {
+		fmt.Fprintf(&sb, "# %s\n[Peer]\nPublicKey = %s\nAllowedIPs = %s\n\n",
+			peer.Name, peer.PublicKey, netip.PrefixFrom(peer.Address, peer.Address.BitLen()))
+	}
+
+	return sb.String()
+}
+
+// Peers returns the peers added to the gateway by AddPeer, the peers of
+// clients are left out.
+func (e *eks) Peers(ctx context.Context) ([]Peer, error) {
+	if len(e.outputs) == 0 {
+		if err := e.Outputs(ctx, io.Discard); err != nil {
+			return nil, err
+		}
+	}
+
+	var peers []Peer
+	if s, ok := e.outputs["peers"]["peers"].Value.(string); ok {
+		if err := json.Unmarshal([]byte(s), &peers); err != nil {
+			return nil, fmt.Errorf("error parsing peers: %w", err)
+		}
+	}
+
+	return slices.DeleteFunc(peers, func(p Peer) bool { return p.Client }), nil
+}
+
+// AddPeer adds a peer to the gateway and writes its wireguard config to w.
+// The gateway picks up the new peer within a minute.
+func (e *eks) AddPeer(ctx context.Context, name string, w io.Writer) error {
+	privateKey, err := noisysocketstypes.NewPrivateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	peer, err := e.addPeer(ctx, name, privateKey.Public().String(), false)
+	if err != nil {
+		return err
+	}
+
+	gw, err := e.wireGuardGateway()
+	if err != nil {
+		return err
+	}
+
+	client := wireguard.Client{
+		Gateway:    gw,
+		Name:       name,
+		PrivateKey: privateKey.String(),
+		Address:    peer.Address,
+	}
+
+	if err := client.WriteConfig(w); err != nil {
+		return fmt.Errorf("error writing wireguard config: %w", err)
+	}
+
+	return nil
+}
+
+// addPeer adds a peer with the public key at the next free address. Peers
+// and clients share names as they share the gateway's peer list.
+func (e *eks) addPeer(ctx context.Context, name, publicKey string, client bool) (Peer, error) {
+	var peer Peer
+
+	err := e.updatePeers(ctx, func(peers []Peer) ([]Peer, error) {
+		if idx := slices.IndexFunc(peers, func(p Peer) bool { return p.Name == name }); idx != -1 {
+			if peers[idx].Client {
+				return nil, fmt.Errorf("%s is already a vpn client", name)
+			}
+			return nil, fmt.Errorf("%s is already a vpn peer", name)
+		}
+
+		gatewayCIDR, err := netip.ParsePrefix(e.pConfig.Gateway.CIDRBlock.String())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing gateway cidr block: %w", err)
+		}
+
+		gatewayAddress, err := e.gatewayOutputAddr("wgGatewayAddress")
+		if err != nil {
+			return nil, err
+		}
+
+		// The installer is part of the gateway stack, not a peer.
+		installerAddress, err := e.gatewayOutputAddr("wgClientAddress")
+		if err != nil {
+			return nil, err
+		}
+
+		ipam, err := wireguard.NewIPAM(gatewayCIDR, gatewayAddress, installerAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range peers {
+			if err := ipam.Restore(p.Name, p.Address); err != nil {
+				return nil, err
+			}
+		}
+
+		address, err := ipam.Allocate(name)
+		if err != nil {
+			return nil, err
+		}
+
+		peer = Peer{Name: name, PublicKey: publicKey, Address: address, Client: client}
+
+		return append(peers, peer), nil
+	})
+
+	return peer, err
+}
+
+// gatewayOutputAddr returns a wireguard address exported by the gateway
+// component. Gateways from older versions of bi may not export it.
+func (e *eks) gatewayOutputAddr(key string) (netip.Addr, error) {
+	s, ok := e.outputs["gateway"][key].Value.(string)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("gateway has no %s output, run bi start (after updating bi if needed) to update it", key)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("error parsing gateway %s: %w", key, err)
+	}
+
+	return addr, nil
+}
+
+// RemovePeer removes a peer added by AddPeer from the gateway.
+func (e *eks) RemovePeer(ctx context.Context, name string) error {
+	return e.removePeer(ctx, name, false)
+}
+
+func (e *eks) removePeer(ctx context.Context, name string, client bool) error {
+	return e.updatePeers(ctx, func(peers []Peer) ([]Peer, error) {
+		return removeNamedPeer(peers, name, client)
+	})
+}
+
+// removeNamedPeer removes a peer, which must be a client's peer if client is
+// set and not otherwise.
+func removeNamedPeer(peers []Peer, name string, client bool) ([]Peer, error) {
+	idx := slices.IndexFunc(peers, func(p Peer) bool { return p.Name == name })
+	if idx == -1 {
+		return nil, fmt.Errorf("peer %s not found", name)
+	}
+
+	switch {
+	case peers[idx].Client && !client:
+		return nil, fmt.Errorf("%s is a vpn client, revoke it with bi vpn client revoke", name)
+	case !peers[idx].Client && client:
+		return nil, fmt.Errorf("%s is a vpn peer, remove it with bi vpn peer remove", name)
+	}
+
+	return slices.Delete(peers, idx, idx+1), nil
+}
+
+// updatePeers updates only the peers component, leaving the gateway
+// instances as they are.
+func (e *eks) updatePeers(ctx context.Context, update func([]Peer) ([]Peer, error)) error {
+	idx := slices.IndexFunc(components, func(c component) bool { return c.name == "peers" })
+
+	pc := components[idx].runnable.(*peersConfig)
+	pc.update = update
+	defer func() { pc.update = nil }()
+
+	return e.upGatewayComponent(ctx, "peers")
+}
+
+// upGatewayComponent updates a single component that needs the gateway to
+// exist.
+func (e *eks) upGatewayComponent(ctx context.Context, name string) error {
+	pConfig, err := util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	stacks, err := e.loadStacks(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !e.exists("gateway") {
+		return errors.New("gateway component doesn't exist")
+	}
+
+	idx := slices.IndexFunc(components, func(c component) bool { return c.name == name })
+	cmpnt := components[idx]
+
+	return e.up(ctx, stacks[cmpnt.name], cmpnt, nil)
+}
@@ -0,0 +1,129 @@
+package eks
+
+import (
+	"net"
+	"net/netip"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"bi/pkg/wireguard"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_renderPeers(t *testing.T) {
+	tests := []struct {
+		name  string
+		peers []Peer
+		want  string
+	}{
+		{
+			name: "no peers",
+			want: "# no peers\n",
+		},
+		{
+			name:  "one peer",
+			peers: []Peer{{Name: "alice", PublicKey: "abc=", Address: netip.MustParseAddr("100.64.250.3")}},
+			want:  "# alice\n[Peer]\nPublicKey = abc=\nAllowedIPs = 100.64.250.3/32\n\n",
+		},
+		{
+			name: "several peers",
+			peers: []Peer{
+				{Name: "alice", PublicKey: "abc=", Address: netip.MustParseAddr("100.64.250.3")},
+				{Name: "ci", PublicKey: "def=", Address: netip.MustParseAddr("fd00::4")},
+			},
+			want: "# alice\n[Peer]\nPublicKey = abc=\nAllowedIPs = 100.64.250.3/32\n\n" +
+				"# ci\n[Peer]\nPublicKey = def=\nAllowedIPs = fd00::4/128\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, renderPeers(tt.peers))
+		})
+	}
+}
+
+func Test_buildCloudConfigPeersAgent(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("100.64.250.0/24")
+	require.NoError(t, err)
+
+	gw, err := wireguard.NewGateway(51820, subnet)
+	require.NoError(t, err)
+
+	g := &gatewayConfig{baseName: "test", region: "us-east-2", wgGateway: gw}
+
+	cc, err := g.buildCloudConfig()
+	require.NoError(t, err)
+
+	files := map[string]writeFile{}
+	for _, f := range cc.WriteFiles {
+		files[f.Path] = f
+	}
+
+	// The base config is what the agent appends the peers to.
+	require.Equal(t, cc.Wireguard.Interfaces[0].Content, files["/etc/wireguard/wg0.base.conf"].Content)
+	require.Equal(t, "0600", files["/etc/wireguard/wg0.base.conf"].Permissions)
+
+	script := files["/usr/local/bin/bi-wg-peers"]
+	require.Equal(t, "0755", script.Permissions)
+	require.Contains(t, script.Content,
+		"aws ssm get-parameter --region us-east-2 --name /bi/test/wireguard/peers --query Parameter.Value --output text")
+	require.Contains(t, script.Content, `printf '\n%s\n' "$peers"`)
+	require.NotContains(t, script.Content, "%!")
+
+	require.Contains(t, files["/etc/systemd/system/bi-wg-peers.service"].Content, "ExecStart=/usr/local/bin/bi-wg-peers")
+	require.Contains(t, files["/etc/systemd/system/bi-wg-peers.timer"].Content, "OnUnitActiveSec=30s")
+	require.Contains(t, cc.RunCmd, []string{"systemctl", "enable", "--now", "bi-wg-peers.timer"})
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	cmd := exec.Command(bash, "-n")
+	cmd.Stdin = strings.NewReader(script.Content)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func Test_removeNamedPeer(t *testing.T) {
+	peers := func() []Peer {
+		return []Peer{
+			{Name: "ci", PublicKey: "abc=", Address: netip.MustParseAddr("100.64.250.3")},
+			{Name: "alice", PublicKey: "def=", Address: netip.MustParseAddr("100.64.250.4"), Client: true},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		peer    string
+		client  bool
+		want    []string
+		wantErr string
+	}{
+		{name: "peer", peer: "ci", want: []string{"alice"}},
+		{name: "client", peer: "alice", client: true, want: []string{"ci"}},
+		{name: "client as a peer", peer: "alice", wantErr: "alice is a vpn client, revoke it with bi vpn client revoke"},
+		{name: "peer as a client", peer: "ci", client: true, wantErr: "ci is a vpn peer, remove it with bi vpn peer remove"},
+		{name: "missing", peer: "bob", wantErr: "peer bob not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := removeNamedPeer(peers(), tt.peer, tt.client)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for _, p := range got {
+				names = append(names, p.Name)
+			}
+			require.Equal(t, tt.want, names)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package eks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	noisysocketstypes "github.com/noisysockets/noisysockets/types"
+)
+
+// RotateGateway replaces the gateway's key. The key is part of the cloud
+// config so the instances are replaced, and every client and peer needs a
+// new config with the new public key.
+func (e *eks) RotateGateway(ctx context.Context) error {
+	return e.rotateGatewayKeys(ctx, true, false)
+}
+
+// RotateClient replaces the key of a client and writes its new config to w.
+// The installer's key is part of the gateway stack and so replaces the
+// instances, other clients are updated in the peers the gateway polls for.
+func (e *eks) RotateClient(ctx context.Context, name string, w io.Writer) error {
+	if name == "installer" {
+		if err := e.rotateGatewayKeys(ctx, false, true); err != nil {
+			return err
+		}
+	} else if err := e.rotatePeerClient(ctx, name); err != nil {
+		return err
+	}
+
+	gw, err := e.clientGateway(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, ok := gw.Client(name)
+	if !ok {
+		return fmt.Errorf("client %s not found", name)
+	}
+
+	if err := client.WriteConfig(w); err != nil {
+		return fmt.Errorf("error writing wireguard config: %w", err)
+	}
+
+	return nil
+}
+
+func (e *eks) rotateGatewayKeys(ctx context.Context, gateway, client bool) error {
+	idx := slices.IndexFunc(components, func(c component) bool { return c.name == "gateway" })
+
+	gc := components[idx].runnable.(*gatewayConfig)
+	gc.rotateGatewayKey, gc.rotateClientKey = gateway, client
+	defer func() { gc.rotateGatewayKey, gc.rotateClientKey = false, false }()
+
+	return e.upGatewayComponent(ctx, "gateway")
+}
+
+// rotatePeerClient gives a client issued by AddClient a new key. The peer
+// keeps its address, only its public key changes. Peers added by AddPeer
+// can't be rotated as bi doesn't keep their private keys.
+func (e *eks) rotatePeerClient(ctx context.Context, name string) error {
+	if e.cfg.WireGuardStatePath == "" {
+		return errors.New("no wireguard state path to save clients to")
+	}
+
+	peers, err := e.Peers(ctx)
+	if err != nil {
+		return err
+	}
+
+	if slices.ContainsFunc(peers, func(p Peer) bool { return p.Name == name }) {
+		return peerNotRotatableError(name)
+	}
+
+	gw, err := e.clientGateway(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := gw.RotateClientKey(name)
+	if err != nil {
+		return err
+	}
+
+	var privateKey noisysocketstypes.NoisePrivateKey
+	if err := privateKey.UnmarshalText([]byte(client.PrivateKey)); err != nil {
+		return fmt.Errorf("failed to unmarshal client private key: %w", err)
+	}
+
+	err = e.updatePeers(ctx, func(peers []Peer) ([]Peer, error) {
+		return setClientPublicKey(peers, name, privateKey.Public().String())
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only saved once the gateway has the new key, so a failed update
+	// leaves the old config working.
+	return gw.Save(e.cfg.WireGuardStatePath)
+}
+
+// setClientPublicKey replaces the public key of a client's peer.
+func setClientPublicKey(peers []Peer, name, publicKey string) ([]Peer, error) {
+	idx := slices.IndexFunc(peers, func(p Peer) bool { return p.Name == name })
+	if idx == -1 {
+		return nil, fmt.Errorf("client %s not found", name)
+	}
+
+	if !peers[idx].Client {
+		return nil, peerNotRotatableError(name)
+	}
+
+	peers[idx].PublicKey = publicKey
+
+	return peers, nil
+}
+
+func peerNotRotatableError(name string) error {
+	return fmt.Errorf("%s is a vpn peer and its keys can't be rotated, remove it with bi vpn peer remove and add it again", name)
+}
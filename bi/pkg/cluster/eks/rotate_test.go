@@ -0,0 +1,42 @@
+package eks
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_setClientPublicKey(t *testing.T) {
+	peers := func() []Peer {
+		return []Peer{
+			{Name: "ci", PublicKey: "abc=", Address: netip.MustParseAddr("100.64.250.3")},
+			{Name: "alice", PublicKey: "def=", Address: netip.MustParseAddr("100.64.250.4"), Client: true},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		client  string
+		wantErr string
+	}{
+		{name: "client", client: "alice"},
+		{name: "peer", client: "ci", wantErr: "ci is a vpn peer and its keys can't be rotated, remove it with bi vpn peer remove and add it again"},
+		{name: "missing", client: "bob", wantErr: "client bob not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setClientPublicKey(peers(), tt.client, "new=")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			want := peers()
+			want[1].PublicKey = "new="
+			require.Equal(t, want, got)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ChangePassphrase re-encrypts the secrets of every component stack with a
+// new passphrase.
+func (e *eks) ChangePassphrase(ctx context.Context, newPassphrase string) error {
+	for _, cmpnt := range components {
+		stack, err := e.createStack(ctx, cmpnt.name, cmpnt.run)
+		if err != nil {
+			return fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
+		}
+
+		slog.Debug("Changing stack passphrase", slog.String("component", cmpnt.name))
+
+		if err := stack.ChangeSecretsProvider(ctx, "passphrase", &auto.ChangeSecretsProviderOptions{
+			NewPassphrase: &newPassphrase,
+		}); err != nil {
+			return fmt.Errorf("failed to change passphrase for component %s: %w", cmpnt.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ExportState returns the state of every component stack keyed by component.
+func (e *eks) ExportState(ctx context.Context) (map[string]apitype.UntypedDeployment, error) {
+	states := make(map[string]apitype.UntypedDeployment)
+	for _, cmpnt := range components {
+		stack, err := e.createStack(ctx, cmpnt.name, cmpnt.run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
+		}
+
+		slog.Debug("Exporting stack state", slog.String("component", cmpnt.name))
+
+		state, err := stack.Export(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export state for component %s: %w", cmpnt.name, err)
+		}
+		states[cmpnt.name] = state
+	}
+
+	return states, nil
+}
+
+// ImportState replaces the state of every component stack with states
+// previously returned by ExportState.
+func (e *eks) ImportState(ctx context.Context, states map[string]apitype.UntypedDeployment) error {
+	for _, cmpnt := range components {
+		state, ok := states[cmpnt.name]
+		if !ok {
+			continue
+		}
+
+		stack, err := e.createStack(ctx, cmpnt.name, cmpnt.run)
+		if err != nil {
+			return fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
+		}
+
+		slog.Debug("Importing stack state", slog.String("component", cmpnt.name))
+
+		if err := stack.Import(ctx, state); err != nil {
+			return fmt.Errorf("failed to import state for component %s: %w", cmpnt.name, err)
+		}
+	}
+
+	return nil
+}
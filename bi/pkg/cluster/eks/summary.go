@@ -0,0 +1,133 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"bi/pkg/cluster/util"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ChangeSummary counts the resources in a component stack by the operation
+// that a preview or refresh would perform (or performed) on them.
+type ChangeSummary struct {
+	Component string `json:"component"`
+	Create    int    `json:"create"`
+	Update    int    `json:"update"`
+	Replace   int    `json:"replace"`
+	Delete    int    `json:"delete"`
+	Same      int    `json:"same"`
+}
+
+// HasChanges returns true if any resources would be (or were) changed.
+func (cs ChangeSummary) HasChanges() bool {
+	return cs.Create+cs.Update+cs.Replace+cs.Delete > 0
+}
+
+func newChangeSummary(component string, changes map[string]int) ChangeSummary {
+	return ChangeSummary{
+		Component: component,
+		Create:    changes[string(apitype.OpCreate)],
+		Update:    changes[string(apitype.OpUpdate)],
+		Replace:   changes[string(apitype.OpReplace)],
+		Delete:    changes[string(apitype.OpDelete)],
+		Same:      changes[string(apitype.OpSame)],
+	}
+}
+
+// Preview reports what running Up would change for each component.
+func (e *eks) Preview(ctx context.Context) ([]ChangeSummary, error) {
+	pConfig, err := util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	var summaries []ChangeSummary
+	for _, cmpnt := range components {
+		stack, err := e.createStack(ctx, cmpnt.name, cmpnt.run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
+		}
+
+		if err := e.configure(ctx, stack, cmpnt); err != nil {
+			return nil, fmt.Errorf("failed to configure component %s: %w", cmpnt.name, err)
+		}
+
+		// Later components are previewed against the current outputs of
+		// earlier ones, not what they would be after an update.
+		out, err := stack.Outputs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get outputs for component %s: %w", cmpnt.name, err)
+		}
+		e.outputs[cmpnt.name] = out
+
+		if err := cmpnt.withOutputs(e.outputs); err != nil {
+			return nil, fmt.Errorf("failed to set outputs for component %s: %w", cmpnt.name, err)
+		}
+
+		slog.Info("Previewing component", slog.String("component", cmpnt.name))
+
+		res, err := stack.Preview(ctx,
+			optpreview.ProgressStreams(util.DebugLogWriter(ctx, slog.Default())),
+			optpreview.SuppressProgress(), // No progress dots
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to preview component %s: %w", cmpnt.name, err)
+		}
+
+		changes := make(map[string]int)
+		for op, count := range res.ChangeSummary {
+			changes[string(op)] = count
+		}
+		summaries = append(summaries, newChangeSummary(cmpnt.name, changes))
+	}
+
+	return summaries, nil
+}
+
+// Refresh updates the state of each component to match what actually exists
+// in AWS and reports what had drifted.
+func (e *eks) Refresh(ctx context.Context) ([]ChangeSummary, error) {
+	pConfig, err := util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	var summaries []ChangeSummary
+	for _, cmpnt := range components {
+		stack, err := e.createStack(ctx, cmpnt.name, cmpnt.run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create component stack %s: %w", cmpnt.name, err)
+		}
+
+		if err := e.configure(ctx, stack, cmpnt); err != nil {
+			return nil, fmt.Errorf("failed to configure component %s: %w", cmpnt.name, err)
+		}
+
+		slog.Info("Refreshing component", slog.String("component", cmpnt.name))
+
+		res, err := stack.Refresh(ctx,
+			optrefresh.ProgressStreams(util.DebugLogWriter(ctx, slog.Default())),
+			optrefresh.SuppressProgress(), // No progress dots
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh component %s: %w", cmpnt.name, err)
+		}
+
+		var changes map[string]int
+		if res.Summary.ResourceChanges != nil {
+			changes = *res.Summary.ResourceChanges
+		}
+		summaries = append(summaries, newChangeSummary(cmpnt.name, changes))
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,51 @@
+package eks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newChangeSummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		changes     map[string]int
+		want        ChangeSummary
+		wantChanges bool
+	}{
+		{
+			name: "no changes",
+			want: ChangeSummary{Component: "vpc"},
+		},
+		{
+			name:    "unchanged",
+			changes: map[string]int{"same": 12},
+			want:    ChangeSummary{Component: "vpc", Same: 12},
+		},
+		{
+			name:        "every op",
+			changes:     map[string]int{"create": 1, "update": 2, "replace": 3, "delete": 4, "same": 5},
+			want:        ChangeSummary{Component: "vpc", Create: 1, Update: 2, Replace: 3, Delete: 4, Same: 5},
+			wantChanges: true,
+		},
+		{
+			name:    "other ops are ignored",
+			changes: map[string]int{"read": 2, "refresh": 3, "same": 1},
+			want:    ChangeSummary{Component: "vpc", Same: 1},
+		},
+		{
+			name:        "delete only",
+			changes:     map[string]int{"delete": 1},
+			want:        ChangeSummary{Component: "vpc", Delete: 1},
+			wantChanges: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newChangeSummary("vpc", tt.changes)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantChanges, got.HasChanges())
+		})
+	}
+}
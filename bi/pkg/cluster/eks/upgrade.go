@@ -0,0 +1,136 @@
+package eks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"bi/pkg/cluster/util"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"golang.org/x/mod/semver"
+)
+
+// ManagedNodeGroupLabel is the label EKS puts on the nodes of managed node
+// groups. Nodes without it (eg. launched by karpenter) aren't upgraded by
+// Upgrade.
+const ManagedNodeGroupLabel = "eks.amazonaws.com/nodegroup"
+
+var minorVersionRegexp = regexp.MustCompile(`^\d+\.\d+$`)
+
+// Upgrade moves the cluster to the next kubernetes minor version. Only the
+// cluster component is updated, pulumi orders the changes so that the
+// control plane is upgraded first, then the managed node groups are rolled
+// (EKS surges new nodes and drains the old ones) and finally the addons are
+// moved to the newest versions for the new kubernetes version.
+func (e *eks) Upgrade(ctx context.Context, to string, progressReporter *util.ProgressReporter) error {
+	pConfig, err := util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	stacks, err := e.loadStacks(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !e.exists("cluster") {
+		return errors.New("cluster component doesn't exist")
+	}
+
+	// Stacks created before the version was exported fall back to the config.
+	from, _ := e.outputs["cluster"]["version"].Value.(string)
+	if from == "" {
+		from = e.pConfig.Cluster.Version
+	}
+
+	if err := CheckUpgradeVersion(from, to); err != nil {
+		return err
+	}
+
+	e.cfg.Config["cluster:version"] = auto.ConfigValue{Value: to}
+
+	pConfig, err = util.ParsePulumiConfig(e.cfg.Config)
+	if err != nil {
+		return fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	e.pConfig = pConfig
+
+	idx := slices.IndexFunc(components, func(c component) bool { return c.name == "cluster" })
+	cmpnt := components[idx]
+	stack := stacks[cmpnt.name]
+
+	if err := e.configure(ctx, stack, cmpnt); err != nil {
+		return fmt.Errorf("failed to configure component %s: %w", cmpnt.name, err)
+	}
+
+	slog.Info("Upgrading cluster", slog.String("from", from), slog.String("to", to))
+
+	return e.up(ctx, stack, cmpnt, progressReporter)
+}
+
+// CheckUpgradeVersion checks that to is exactly one minor version after
+// from, which is the only upgrade EKS supports.
+func CheckUpgradeVersion(from, to string) error {
+	fromMajor, fromMinor, err := parseMinorVersion(from)
+	if err != nil {
+		return fmt.Errorf("invalid current version: %w", err)
+	}
+
+	toMajor, toMinor, err := parseMinorVersion(to)
+	if err != nil {
+		return fmt.Errorf("invalid target version: %w", err)
+	}
+
+	switch {
+	case from == to:
+		return fmt.Errorf("cluster is already at version %s", to)
+	case toMajor != fromMajor || toMinor < fromMinor:
+		return fmt.Errorf("can't upgrade from %s to %s, downgrades aren't supported", from, to)
+	case toMinor != fromMinor+1:
+		return fmt.Errorf("can't upgrade from %s to %s, upgrade one minor version at a time (next is %d.%d)",
+			from, to, fromMajor, fromMinor+1)
+	}
+
+	return nil
+}
+
+// CheckNodeVersions checks that every node's kubelet is at the version.
+// kubeletVersions is keyed by node name with values like v1.35.0-eks-1234.
+func CheckNodeVersions(kubeletVersions map[string]string, version string) error {
+	if len(kubeletVersions) == 0 {
+		return errors.New("no managed nodes found")
+	}
+
+	want := "v" + version
+
+	var errs []error
+	for _, node := range slices.Sorted(maps.Keys(kubeletVersions)) {
+		got := semver.MajorMinor(strings.SplitN(kubeletVersions[node], "-", 2)[0])
+		if got != want {
+			errs = append(errs, fmt.Errorf("node %s is at %s not %s", node, kubeletVersions[node], version))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func parseMinorVersion(v string) (major, minor int, err error) {
+	if !minorVersionRegexp.MatchString(v) {
+		return 0, 0, fmt.Errorf("expected a major.minor version e.g. 1.34, got %q", v)
+	}
+
+	if _, err := fmt.Sscanf(v, "%d.%d", &major, &minor); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse version %q: %w", v, err)
+	}
+
+	return major, minor, nil
+}
@@ -0,0 +1,48 @@
+package eks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckUpgradeVersion(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  string
+	}{
+		{from: "1.34", to: "1.35"},
+		{from: "1.9", to: "1.10"},
+		{from: "1.34", to: "1.34", wantErr: "already at version 1.34"},
+		{from: "1.34", to: "1.36", wantErr: "one minor version at a time (next is 1.35)"},
+		{from: "1.34", to: "1.33", wantErr: "downgrades aren't supported"},
+		{from: "1.34", to: "2.0", wantErr: "downgrades aren't supported"},
+		{from: "1.34", to: "1.35.1", wantErr: "invalid target version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			err := CheckUpgradeVersion(tt.from, tt.to)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckNodeVersions(t *testing.T) {
+	require.NoError(t, CheckNodeVersions(map[string]string{
+		"a": "v1.35.0-eks-3cfe0ce",
+		"b": "v1.35.2-eks-3cfe0ce",
+	}, "1.35"))
+
+	err := CheckNodeVersions(map[string]string{
+		"a": "v1.35.0-eks-3cfe0ce",
+		"b": "v1.34.1-eks-3cfe0ce",
+	}, "1.35")
+	require.ErrorContains(t, err, "node b is at v1.34.1-eks-3cfe0ce not 1.35")
+
+	require.Error(t, CheckNodeVersions(nil, "1.35"))
+}
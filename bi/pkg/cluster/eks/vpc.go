@@ -22,6 +22,10 @@ type vpcConfig struct {
 	baseName  string
 	cidrBlock *net.IPNet
 
+	// existingID and existingSubnetIDs are set when using an existing VPC
+	existingID        string
+	existingSubnetIDs map[string][]string
+
 	// state accumulation
 	azs     *aws.GetAvailabilityZonesResult
 	azNames []string
@@ -37,6 +41,11 @@ type vpcConfig struct {
 func (v *vpcConfig) withConfig(cfg *util.PulumiConfig) error {
 	v.baseName = cfg.Cluster.Name
 	v.cidrBlock = cfg.VPC.CIDRBlock
+	v.existingID = cfg.VPC.ID
+	v.existingSubnetIDs = map[string][]string{
+		"public":  cfg.VPC.PublicSubnetIDs,
+		"private": cfg.VPC.PrivateSubnetIDs,
+	}
 
 	return nil
 }
@@ -45,6 +54,10 @@ func (v *vpcConfig) withConfig(cfg *util.PulumiConfig) error {
 func (v *vpcConfig) withOutputs(outputs map[string]auto.OutputMap) error { return nil }
 
 func (v *vpcConfig) run(ctx *pulumi.Context) error {
+	if v.existingID != "" {
+		return v.lookupExisting(ctx)
+	}
+
 	v.subnets = make(map[string][]pulumi.IDOutput)
 
 	for _, fn := range []func(*pulumi.Context) error{
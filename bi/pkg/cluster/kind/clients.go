@@ -0,0 +1,168 @@
+package kind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"bi/pkg/wireguard"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// WireGuardClients returns the clients of the gateway, including the
+// installer.
+func (c *KindClusterProvider) WireGuardClients(_ context.Context) ([]wireguard.Client, error) {
+	if !c.gatewayEnabled {
+		return nil, errors.New("wireguard gateway is not enabled")
+	}
+
+	return c.wgGateway.Clients, nil
+}
+
+// AddWireGuardClient adds a client to the gateway, reconfigures the gateway
+// if it has been created and writes the client's config to w.
+func (c *KindClusterProvider) AddWireGuardClient(ctx context.Context, name string, w io.Writer) error {
+	if !c.gatewayEnabled {
+		return errors.New("wireguard gateway is not enabled")
+	}
+
+	client, err := c.wgGateway.NewClient(name)
+	if err != nil {
+		return fmt.Errorf("failed to create wireguard client %s: %w", name, err)
+	}
+
+	if err := c.saveWireGuardGateway(); err != nil {
+		return err
+	}
+
+	if err := c.reconfigureWireGuardGateway(ctx); err != nil {
+		return err
+	}
+
+	return c.writeClientConfig(ctx, client, w)
+}
+
+// RevokeWireGuardClient removes a client from the gateway and reconfigures
+// the gateway so it can no longer connect.
+func (c *KindClusterProvider) RevokeWireGuardClient(ctx context.Context, name string) error {
+	if !c.gatewayEnabled {
+		return errors.New("wireguard gateway is not enabled")
+	}
+
+	if name == c.wgClient.Name {
+		return fmt.Errorf("the %s client can't be revoked", name)
+	}
+
+	if err := c.wgGateway.RemoveClient(name); err != nil {
+		return err
+	}
+
+	if err := c.saveWireGuardGateway(); err != nil {
+		return err
+	}
+
+	return c.reconfigureWireGuardGateway(ctx)
+}
+
+// RotateWireGuardGateway gives the gateway a new key and reconfigures it.
+// The configs of every client need to be written again afterwards.
+func (c *KindClusterProvider) RotateWireGuardGateway(ctx context.Context) error {
+	if !c.gatewayEnabled {
+		return errors.New("wireguard gateway is not enabled")
+	}
+
+	if err := c.wgGateway.RotateKey(); err != nil {
+		return err
+	}
+
+	if err := c.saveWireGuardGateway(); err != nil {
+		return err
+	}
+
+	return c.reconfigureWireGuardGateway(ctx)
+}
+
+// RotateWireGuardClient gives a client a new key, reconfigures the gateway
+// so the old key can no longer connect and writes the client's new config
+// to w.
+func (c *KindClusterProvider) RotateWireGuardClient(ctx context.Context, name string, w io.Writer) error {
+	if !c.gatewayEnabled {
+		return errors.New("wireguard gateway is not enabled")
+	}
+
+	client, err := c.wgGateway.RotateClientKey(name)
+	if err != nil {
+		return err
+	}
+
+	if name == c.wgClient.Name {
+		c.wgClient = client
+	}
+
+	if err := c.saveWireGuardGateway(); err != nil {
+		return err
+	}
+
+	if err := c.reconfigureWireGuardGateway(ctx); err != nil {
+		return err
+	}
+
+	return c.writeClientConfig(ctx, client, w)
+}
+
+func (c *KindClusterProvider) saveWireGuardGateway() error {
+	if c.wgStatePath == "" {
+		return nil
+	}
+
+	return c.wgGateway.Save(c.wgStatePath)
+}
+
+// reconfigureWireGuardGateway updates the config of an existing gateway
+// container. A running gateway is recreated on the same host port so that
+// the configs already handed out keep working, a stopped one only has its
+// config replaced.
+func (c *KindClusterProvider) reconfigureWireGuardGateway(ctx context.Context) error {
+	containerID, err := c.getWireGuardGatewayContainer(ctx)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Picked up when the cluster is created.
+			return nil
+		}
+
+		return fmt.Errorf("failed to get wireguard gateway container: %w", err)
+	}
+
+	info, err := c.dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect wireguard gateway container: %w", err)
+	}
+
+	if !info.State.Running {
+		configArchive, err := c.createWireGuardConfigArchive()
+		if err != nil {
+			return fmt.Errorf("failed to create wireguard config archive: %w", err)
+		}
+
+		if err := c.dockerClient.CopyToContainer(ctx, containerID, "/home/nonroot/",
+			configArchive, container.CopyToContainerOptions{}); err != nil {
+			return fmt.Errorf("failed to copy wireguard config to container: %w", err)
+		}
+
+		return nil
+	}
+
+	hostPort := "0"
+	if port := info.NetworkSettings.Ports[nat.Port("51820/udp")]; len(port) > 0 {
+		hostPort = port[0].HostPort
+	}
+
+	slog.Debug("Reconfiguring wireguard gateway", slog.String("hostPort", hostPort))
+
+	return c.createWireGuardGateway(ctx, hostPort)
+}
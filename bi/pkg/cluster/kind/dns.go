@@ -0,0 +1,214 @@
+package kind
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// CoreDNSImage serves the split-horizon DNS for the wireguard clients.
+const CoreDNSImage = "registry.k8s.io/coredns/coredns:v1.11.3"
+
+const (
+	// clusterDNSAddress is the kube-dns service in kind's default service
+	// subnet.
+	clusterDNSAddress = "10.96.0.10"
+
+	// clusterDomain is resolved by the cluster's CoreDNS. The install's own
+	// hostnames embed their address and resolve through the public
+	// upstreams like any other name.
+	clusterDomain = "cluster.local"
+
+	splitDNSCorefilePath = "/etc/coredns/Corefile"
+)
+
+// publicDNSUpstreams are used by both the gateway and the split DNS server.
+var publicDNSUpstreams = []string{"8.8.8.8", "8.8.4.4"}
+
+// createSplitDNS (re)creates the DNS server that the wireguard clients use.
+// It shares the network namespace of the control plane node so that it can
+// reach the kube-dns service, and is reachable from the clients on the
+// node's address on the kind network.
+func (c *KindClusterProvider) createSplitDNS(ctx context.Context) error {
+	containerName := c.splitDNSContainerName()
+
+	slog.Debug("Creating split DNS server", slog.String("name", containerName))
+
+	_ = c.destroySplitDNS(ctx)
+
+	nodeAddress, err := c.controlPlaneAddress(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ensureImage(ctx, CoreDNSImage); err != nil {
+		return err
+	}
+
+	config := &container.Config{
+		Image: CoreDNSImage,
+		Cmd:   []string{"-conf", splitDNSCorefilePath},
+	}
+
+	hostConfig := &container.HostConfig{
+		NetworkMode: container.NetworkMode("container:" + c.controlPlaneContainerName()),
+		CapAdd:      []string{"NET_BIND_SERVICE"},
+	}
+
+	resp, err := c.dockerClient.ContainerCreate(ctx, config, hostConfig, nil, nil, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to create split DNS container: %w", err)
+	}
+
+	corefileArchive, err := createCorefileArchive(corefile(nodeAddress))
+	if err != nil {
+		return fmt.Errorf("failed to create Corefile archive: %w", err)
+	}
+
+	if err := c.dockerClient.CopyToContainer(ctx, resp.ID, "/",
+		corefileArchive, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy Corefile to container: %w", err)
+	}
+
+	if err := c.dockerClient.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start split DNS container: %w", err)
+	}
+
+	return nil
+}
+
+func (c *KindClusterProvider) destroySplitDNS(ctx context.Context) error {
+	containerID, err := c.getSplitDNSContainer(ctx)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to get split DNS container: %w", err)
+	}
+
+	if err := c.dockerClient.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+		return fmt.Errorf("failed to remove split DNS container: %w", err)
+	}
+
+	return nil
+}
+
+func (c *KindClusterProvider) getSplitDNSContainer(ctx context.Context) (string, error) {
+	containers, err := c.dockerClient.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", c.splitDNSContainerName())),
+		All:     true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	if len(containers) == 0 {
+		return "", os.ErrNotExist
+	}
+
+	return containers[0].ID, nil
+}
+
+// splitDNSAddress returns the address of the running split DNS server.
+func (c *KindClusterProvider) splitDNSAddress(ctx context.Context) (netip.Addr, error) {
+	containerID, err := c.getSplitDNSContainer(ctx)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	info, err := c.dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to inspect split DNS container: %w", err)
+	}
+
+	if !info.State.Running {
+		return netip.Addr{}, errors.New("split DNS container is not running")
+	}
+
+	return c.controlPlaneAddress(ctx)
+}
+
+// controlPlaneAddress returns the ipv4 address of the control plane node on
+// the kind network.
+func (c *KindClusterProvider) controlPlaneAddress(ctx context.Context) (netip.Addr, error) {
+	info, err := c.dockerClient.ContainerInspect(ctx, c.controlPlaneContainerName())
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to inspect control plane container: %w", err)
+	}
+
+	if info.NetworkSettings == nil || info.NetworkSettings.Networks["kind"] == nil {
+		return netip.Addr{}, errors.New("control plane container isn't on the kind network")
+	}
+
+	addr, err := netip.ParseAddr(info.NetworkSettings.Networks["kind"].IPAddress)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to parse control plane address: %w", err)
+	}
+
+	return addr, nil
+}
+
+func (c *KindClusterProvider) controlPlaneContainerName() string {
+	return c.name + "-control-plane"
+}
+
+func (c *KindClusterProvider) splitDNSContainerName() string {
+	return c.name + "-dns"
+}
+
+// corefile returns the CoreDNS config, it only listens on the node's kind
+// network address to stay out of the way of anything else on the node.
+func corefile(bindAddress netip.Addr) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s {\n\tbind %s\n\tforward . %s\n\tcache 30\n\terrors\n}\n\n",
+		clusterDomain, bindAddress, clusterDNSAddress)
+	fmt.Fprintf(&sb, ". {\n\tbind %s\n\tforward . %s\n\tcache 30\n\terrors\n}\n",
+		bindAddress, strings.Join(publicDNSUpstreams, " "))
+
+	return sb.String()
+}
+
+func createCorefileArchive(corefile string) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, dir := range []string{"etc", "etc/coredns"} {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     dir,
+			Mode:     0o755,
+			Typeflag: tar.TypeDir,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to write %s directory header: %w", dir, err)
+		}
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name: strings.TrimPrefix(splitDNSCorefilePath, "/"),
+		Mode: 0o444,
+		Size: int64(len(corefile)),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write Corefile header: %w", err)
+	}
+
+	if _, err := tw.Write([]byte(corefile)); err != nil {
+		return nil, fmt.Errorf("failed to write Corefile: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %w", err)
+	}
+
+	return &buf, nil
+}
@@ -0,0 +1,27 @@
+package kind
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_corefile(t *testing.T) {
+	got := corefile(netip.MustParseAddr("172.18.0.2"))
+
+	require.Equal(t, `cluster.local {
+	bind 172.18.0.2
+	forward . 10.96.0.10
+	cache 30
+	errors
+}
+
+. {
+	bind 172.18.0.2
+	forward . 8.8.8.8 8.8.4.4
+	cache 30
+	errors
+}
+`, got)
+}
@@ -31,7 +31,9 @@ const (
 	nonRootGID = 65532
 )
 
-func (c *KindClusterProvider) createWireGuardGateway(ctx context.Context) error {
+// createWireGuardGateway (re)creates the gateway container, publishing it on
+// hostPort or a random port if that is "0".
+func (c *KindClusterProvider) createWireGuardGateway(ctx context.Context, hostPort string) error {
 	containerName := c.name + "-gateway"
 
 	slog.Debug("Creating wireguard gateway", slog.String("name", containerName))
@@ -40,43 +42,33 @@ func (c *KindClusterProvider) createWireGuardGateway(ctx context.Context) error
 	// keys).
 	_ = c.destroyWireGuardGateway(ctx)
 
-	// Check if the NoisySockets image is already available.
-	_, err := c.dockerClient.ImageInspect(ctx, NoisySocketsImage)
-	if err != nil {
-		slog.Debug("NoisySockets image not found, pulling it")
-
-		// Pull the NoisySockets image.
-		pullProgressReader, err := c.dockerClient.ImagePull(ctx, NoisySocketsImage, image.PullOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to pull noisy sockets image: %w", err)
-		}
-		defer pullProgressReader.Close()
+	if err := c.ensureImage(ctx, NoisySocketsImage); err != nil {
+		return err
+	}
 
-		if err := displayImagePullProgress(pullProgressReader); err != nil {
-			return fmt.Errorf("failed to display noisy sockets image pull progress: %w", err)
-		}
+	cmd := []string{"up", "--log-level=debug", "--enable-dns", "--enable-router"}
+	// Use Google's DNS servers as the upstream for public DNS queries, to
+	// avoid the possibility of a DNS loop when using podman-machine.
+	for _, upstream := range publicDNSUpstreams {
+		cmd = append(cmd, "--dns-public-upstream="+upstream)
 	}
 
 	// Create the wireguard gateway container.
 	config := &container.Config{
 		Image: NoisySocketsImage,
-		Cmd: []string{"up", "--log-level=debug", "--enable-dns", "--enable-router",
-			// Use Google's DNS servers as the upstream for public DNS queries,
-			// to avoid the possibility of a DNS loop when using podman-machine.
-			"--dns-public-upstream=8.8.8.8", "--dns-public-upstream=8.8.4.4"},
-		Env: []string{"DO_NOT_TRACK=1"},
+		Cmd:   cmd,
+		Env:   []string{"DO_NOT_TRACK=1"},
 		ExposedPorts: map[nat.Port]struct{}{
 			"51820/udp": {},
 		},
 	}
 
 	hostConfig := &container.HostConfig{
-		// Use a random port on the host.
 		PortBindings: nat.PortMap{
 			nat.Port("51820/udp"): []nat.PortBinding{
 				{
 					HostIP:   "127.0.0.1",
-					HostPort: "0",
+					HostPort: hostPort,
 				},
 			},
 		},
@@ -113,6 +105,27 @@ func (c *KindClusterProvider) createWireGuardGateway(ctx context.Context) error
 	return nil
 }
 
+// ensureImage pulls the image if it isn't already available.
+func (c *KindClusterProvider) ensureImage(ctx context.Context, ref string) error {
+	if _, err := c.dockerClient.ImageInspect(ctx, ref); err == nil {
+		return nil
+	}
+
+	slog.Debug("Image not found, pulling it", slog.String("image", ref))
+
+	pullProgressReader, err := c.dockerClient.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer pullProgressReader.Close()
+
+	if err := displayImagePullProgress(pullProgressReader); err != nil {
+		return fmt.Errorf("failed to display image %s pull progress: %w", ref, err)
+	}
+
+	return nil
+}
+
 func (c *KindClusterProvider) destroyWireGuardGateway(ctx context.Context) error {
 	containerID, err := c.getWireGuardGatewayContainer(ctx)
 	if err != nil {
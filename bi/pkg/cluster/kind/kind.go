@@ -5,6 +5,7 @@ import (
 	"bi/pkg/wireguard"
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -23,44 +24,60 @@ import (
 	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
 )
 
-const (
-	// This ideally will be a pre-built image for the kind release we're using
-	// But should match the minor version of k8s that we're running in AWS
-	//
-	// Kind only does sha256 in order to attach kind binary version to the OCI image version
-	// This means that when we update kind dependency we should get the corresponding image
-	// digest from the last kind release
-	//
-	// https://github.com/kubernetes-sigs/kind/releases/latest
-	KindImage         = "kindest/node:v1.34.0@sha256:7416a61b42b1662ca6ca89f02028ac133a309a2a30ba309614e8ec94d976dc5a"
-	NoisySocketsImage = "ghcr.io/noisysockets/nsh:v0.9.3"
-)
+const NoisySocketsImage = "ghcr.io/noisysockets/nsh:v0.9.3"
 
 type KindClusterProvider struct {
 	logger         *slog.Logger
 	nodeProvider   cluster.ProviderOption
 	name           string
+	nodeImage      string
 	dockerClient   *dockerclient.Client
 	gatewayEnabled bool
-	wgGateway      *wireguard.Gateway
-	wgClient       *wireguard.Client
+	// wgStatePath is where the gateway keys and clients are saved, if empty
+	// new keys are generated every time.
+	wgStatePath string
+	wgGateway   *wireguard.Gateway
+	wgClient    *wireguard.Client
 	// GPU support fields
 	gpuAvailable        bool
 	gpuCount            int
 	nvidiaAutoDiscovery bool
+	kubernetesVersion   string
+}
+
+// ClusterProviderOptions changes how the kind cluster is created.
+type ClusterProviderOptions struct {
+	// GatewayEnabled runs a wireguard gateway so that the host can reach
+	// the cluster network.
+	GatewayEnabled bool
+	// NvidiaAutoDiscovery detects GPUs and adds them to the cluster.
+	NvidiaAutoDiscovery bool
+	// KubernetesVersion defaults to DefaultKubernetesVersion.
+	KubernetesVersion string
+	// WireGuardStatePath is where the gateway keys are saved, if empty new
+	// keys are generated every time.
+	WireGuardStatePath string
 }
 
-func NewClusterProvider(logger *slog.Logger, name string, gatewayEnabled bool, nvidiaAutoDiscovery bool) *KindClusterProvider {
+// NewClusterProvider creates a kind cluster provider.
+func NewClusterProvider(logger *slog.Logger, name string, opts ClusterProviderOptions) *KindClusterProvider {
 	return &KindClusterProvider{
 		logger:              logger,
 		name:                name,
-		gatewayEnabled:      gatewayEnabled,
-		nvidiaAutoDiscovery: nvidiaAutoDiscovery,
+		gatewayEnabled:      opts.GatewayEnabled,
+		wgStatePath:         opts.WireGuardStatePath,
+		nvidiaAutoDiscovery: opts.NvidiaAutoDiscovery,
+		kubernetesVersion:   opts.KubernetesVersion,
 	}
 }
 
 func (c *KindClusterProvider) Init(ctx context.Context) error {
 	var err error
+	c.nodeImage, err = NodeImage(c.kubernetesVersion)
+	if err != nil {
+		return err
+	}
+
 	c.nodeProvider, err = cluster.DetectNodeProvider()
 	if err != nil {
 		return fmt.Errorf("failed to detect node provider: %w", err)
@@ -93,6 +110,23 @@ func (c *KindClusterProvider) initForGateway() error {
 		return fmt.Errorf("docker client is required for gateway functionality")
 	}
 
+	if c.wgStatePath != "" {
+		gw, err := wireguard.LoadGateway(c.wgStatePath)
+		switch {
+		case err == nil:
+			installer, ok := gw.Client("installer")
+			if !ok {
+				return fmt.Errorf("wireguard gateway state %s has no installer client", c.wgStatePath)
+			}
+
+			c.wgGateway, c.wgClient = gw, installer
+
+			return nil
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+
 	// Use the same CIDR block as AWS.
 	_, gatewayCIDRBlock, err := net.ParseCIDR("100.64.250.0/24")
 	if err != nil {
@@ -109,7 +143,7 @@ func (c *KindClusterProvider) initForGateway() error {
 		return fmt.Errorf("failed to create wireguard client for installer: %w", err)
 	}
 
-	return nil
+	return c.saveWireGuardGateway()
 }
 
 func (c *KindClusterProvider) Create(ctx context.Context, progressReporter *util.ProgressReporter) error {
@@ -134,7 +168,7 @@ func (c *KindClusterProvider) Create(ctx context.Context, progressReporter *util
 		createOpts := []cluster.CreateOption{
 			// We'll need to configure the cluster here
 			// if customers need to access the docker images.
-			cluster.CreateWithNodeImage(KindImage),
+			cluster.CreateWithNodeImage(c.nodeImage),
 			cluster.CreateWithDisplayUsage(false),
 			cluster.CreateWithDisplaySalutation(false),
 			cluster.CreateWithV1Alpha4Config(clusterConfig),
@@ -142,9 +176,11 @@ func (c *KindClusterProvider) Create(ctx context.Context, progressReporter *util
 
 		if c.gpuAvailable {
 			c.logger.Info("Creating kind cluster with GPU support",
+				slog.String("image", c.nodeImage),
 				slog.Int("gpu_count", c.gpuCount))
 		} else {
-			c.logger.Info("Creating kind cluster without GPU support")
+			c.logger.Info("Creating kind cluster without GPU support",
+				slog.String("image", c.nodeImage))
 		}
 
 		kindProvider := c.kindProviderWithLogger(logger)
@@ -169,9 +205,13 @@ func (c *KindClusterProvider) Create(ctx context.Context, progressReporter *util
 
 	// Create the wireguard gateway container.
 	if c.gatewayEnabled {
-		if err := c.createWireGuardGateway(ctx); err != nil {
+		if err := c.createWireGuardGateway(ctx, "0"); err != nil {
 			return fmt.Errorf("failed to create wireguard gateway: %w", err)
 		}
+
+		if err := c.createSplitDNS(ctx); err != nil {
+			return fmt.Errorf("failed to create split DNS server: %w", err)
+		}
 	}
 
 	return nil
@@ -278,6 +318,10 @@ func (c *KindClusterProvider) Destroy(ctx context.Context, _ *util.ProgressRepor
 		if err := c.destroyWireGuardGateway(ctx); err != nil {
 			return fmt.Errorf("failed to remove wireguard gateway: %w", err)
 		}
+
+		if err := c.destroySplitDNS(ctx); err != nil {
+			return fmt.Errorf("failed to remove split DNS server: %w", err)
+		}
 	}
 
 	return nil
@@ -306,30 +350,47 @@ func (c *KindClusterProvider) WriteWireGuardConfig(ctx context.Context, w io.Wri
 		return false, nil
 	}
 
+	if err := c.writeClientConfig(ctx, c.wgClient, w); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// writeClientConfig writes the config for a client of the running gateway.
+func (c *KindClusterProvider) writeClientConfig(ctx context.Context, client *wireguard.Client, w io.Writer) error {
 	containerID, err := c.getWireGuardGatewayContainer(ctx)
 	if err != nil {
-		return true, fmt.Errorf("failed to get wireguard gateway container: %w", err)
+		return fmt.Errorf("failed to get wireguard gateway container: %w", err)
 	}
 
 	gwEndpoint, err := c.getWireGuardGatewayEndpoint(ctx, containerID)
 	if err != nil {
-		return true, fmt.Errorf("failed to get wireguard gateway address: %w", err)
+		return fmt.Errorf("failed to get wireguard gateway address: %w", err)
 	}
 
 	c.wgGateway.Endpoint = gwEndpoint
-	c.wgGateway.Nameservers = []netip.Addr{c.wgGateway.Address} // The gateway is hosting a DNS server.
+	// Prefer the split DNS server so that cluster service names resolve, the
+	// gateway also hosts a DNS server for public names.
+	nameserver := c.wgGateway.Address
+	if addr, err := c.splitDNSAddress(ctx); err == nil {
+		nameserver = addr
+	} else {
+		slog.Debug("Split DNS server not available, using the gateway", slog.Any("error", err))
+	}
+	c.wgGateway.Nameservers = []netip.Addr{nameserver}
 
 	// Get the CIDR of the `kind` network.
 	_, c.wgGateway.VPCSubnets, err = getKindNetworks(ctx)
 	if err != nil {
-		return true, err
+		return err
 	}
 
-	if err := c.wgClient.WriteConfig(w); err != nil {
-		return true, fmt.Errorf("error writing wireguard config: %w", err)
+	if err := client.WriteConfig(w); err != nil {
+		return fmt.Errorf("error writing wireguard config: %w", err)
 	}
 
-	return true, nil
+	return nil
 }
 
 func (c *KindClusterProvider) isRunning() (bool, error) {
@@ -17,7 +17,10 @@ func TestKindClusterProvider(t *testing.T) {
 	testutil.IntegrationTest(t)
 
 	t.Log("Creating kind cluster")
-	clusterProvider := kind.NewClusterProvider(slogt.New(t), "bi-test", true, true)
+	clusterProvider := kind.NewClusterProvider(slogt.New(t), "bi-test", kind.ClusterProviderOptions{
+		GatewayEnabled:      true,
+		NvidiaAutoDiscovery: true,
+	})
 
 	ctx := context.Background()
 	require.NoError(t, clusterProvider.Init(ctx))
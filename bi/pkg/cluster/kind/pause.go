@@ -0,0 +1,145 @@
+package kind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/docker/docker/api/types/container"
+	"golang.org/x/sync/errgroup"
+)
+
+// Pause stops the kind node containers and the wireguard gateway container
+// (if enabled) without removing them, so that the cluster can be resumed later.
+func (c *KindClusterProvider) Pause(ctx context.Context) error {
+	containers, err := c.pausableContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.gatewayEnabled {
+		if containerID, err := c.getSplitDNSContainer(ctx); err == nil {
+			containers = append(containers, containerID)
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, name := range containers {
+		g.Go(func() error {
+			c.logger.Debug("Stopping container", slog.String("name", name))
+
+			if err := c.dockerClient.ContainerStop(ctx, name, container.StopOptions{}); err != nil {
+				return fmt.Errorf("failed to stop container %s: %w", name, err)
+			}
+
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+// Resume starts the containers previously stopped by Pause.
+func (c *KindClusterProvider) Resume(ctx context.Context) error {
+	containers, err := c.pausableContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	for _, name := range containers {
+		g.Go(func() error {
+			c.logger.Debug("Starting container", slog.String("name", name))
+
+			if err := c.dockerClient.ContainerStart(gctx, name, container.StartOptions{}); err != nil {
+				return fmt.Errorf("failed to start container %s: %w", name, err)
+			}
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	// The split DNS server joins the control plane node's network, so it
+	// can only be started once the node is running.
+	if c.gatewayEnabled {
+		containerID, err := c.getSplitDNSContainer(ctx)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to get split DNS container: %w", err)
+			}
+
+			c.logger.Warn("Split DNS container not found")
+			return nil
+		}
+
+		if err := c.dockerClient.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+			return fmt.Errorf("failed to start split DNS container: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// WireGuardGatewayEndpoint returns the host endpoint that the running
+// wireguard gateway container is reachable on. As the gateway binds to a
+// random host port, this may change after the container is restarted.
+func (c *KindClusterProvider) WireGuardGatewayEndpoint(ctx context.Context) (string, error) {
+	if !c.gatewayEnabled {
+		return "", errors.New("wireguard gateway is not enabled")
+	}
+
+	containerID, err := c.getWireGuardGatewayContainer(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get wireguard gateway container: %w", err)
+	}
+
+	return c.getWireGuardGatewayEndpoint(ctx, containerID)
+}
+
+// pausableContainers returns the names of the containers that make up the
+// cluster: the kind nodes plus the wireguard gateway (if enabled).
+func (c *KindClusterProvider) pausableContainers(ctx context.Context) ([]string, error) {
+	if c.dockerClient == nil {
+		return nil, fmt.Errorf("docker client is required to pause or resume a cluster")
+	}
+
+	isRunning, err := c.isRunning()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if kind cluster is running: %w", err)
+	}
+
+	if !isRunning {
+		return nil, fmt.Errorf("kind cluster %s does not exist", c.name)
+	}
+
+	nodeList, err := c.kindProvider().ListNodes(c.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kind nodes: %w", err)
+	}
+
+	var containers []string
+	for _, node := range nodeList {
+		containers = append(containers, node.String())
+	}
+
+	if c.gatewayEnabled {
+		containerID, err := c.getWireGuardGatewayContainer(ctx)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to get wireguard gateway container: %w", err)
+			}
+
+			c.logger.Warn("Wireguard gateway container not found")
+		} else {
+			containers = append(containers, containerID)
+		}
+	}
+
+	return containers, nil
+}
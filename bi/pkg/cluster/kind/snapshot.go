@@ -0,0 +1,385 @@
+package kind
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+const snapshotManifestName = "manifest.json"
+
+// snapshotPaths are the paths on each node that make up a snapshot: etcd,
+// the cluster PKI and kubeconfigs, and the local-path-provisioner volumes.
+//
+// The static pod manifests are deliberately left out so that a restored
+// cluster keeps advertising its current node IPs.
+var snapshotPaths = []string{
+	"/etc/kubernetes/pki",
+	"/etc/kubernetes/admin.conf",
+	"/etc/kubernetes/super-admin.conf",
+	"/etc/kubernetes/controller-manager.conf",
+	"/etc/kubernetes/scheduler.conf",
+	"/etc/kubernetes/kubelet.conf",
+	"/var/lib/kubelet/pki",
+	"/var/lib/etcd",
+	"/var/local-path-provisioner",
+}
+
+// SnapshotManifest describes the contents of a snapshot archive.
+type SnapshotManifest struct {
+	Cluster   string    `json:"cluster"`
+	NodeImage string    `json:"node_image"`
+	Nodes     []string  `json:"nodes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaveSnapshot writes a gzipped tar archive of the cluster state to w.
+// The cluster must be paused first so that etcd isn't written to while it
+// is being copied.
+func (c *KindClusterProvider) SaveSnapshot(ctx context.Context, w io.Writer) error {
+	if c.dockerClient == nil {
+		return fmt.Errorf("docker client is required to snapshot a cluster")
+	}
+
+	nodeList, err := c.kindProvider().ListNodes(c.name)
+	if err != nil {
+		return fmt.Errorf("failed to list kind nodes: %w", err)
+	}
+
+	if len(nodeList) == 0 {
+		return fmt.Errorf("kind cluster %s does not exist", c.name)
+	}
+
+	manifest := SnapshotManifest{
+		Cluster:   c.name,
+		NodeImage: c.nodeImage,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	for _, node := range nodeList {
+		info, err := c.dockerClient.ContainerInspect(ctx, node.String())
+		if err != nil {
+			return fmt.Errorf("failed to inspect node %s: %w", node.String(), err)
+		}
+
+		if info.State.Running {
+			return fmt.Errorf("node %s is still running, pause the cluster first", node.String())
+		}
+
+		manifest.Nodes = append(manifest.Nodes, node.String())
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	manifestBytes, err := json.Marshal(&manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot manifest: %w", err)
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name: snapshotManifestName,
+		Mode: 0o600,
+		Size: int64(len(manifestBytes)),
+	}); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest header: %w", err)
+	}
+
+	if _, err := tw.Write(manifestBytes); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+
+	for _, nodeName := range manifest.Nodes {
+		for _, p := range snapshotPaths {
+			if err := c.addPathToSnapshot(ctx, tw, nodeName, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %w", err)
+	}
+
+	return gw.Close()
+}
+
+// addPathToSnapshot copies a path out of a node container and appends it to
+// the snapshot as a nested tar archive named nodes/<node><path>.tar.
+func (c *KindClusterProvider) addPathToSnapshot(ctx context.Context, tw *tar.Writer, nodeName, p string) error {
+	l := c.logger.With(slog.String("node", nodeName), slog.String("path", p))
+
+	rc, _, err := c.dockerClient.CopyFromContainer(ctx, nodeName, p)
+	if err != nil {
+		// Not every path exists on every node (eg. etcd only runs on the control plane).
+		if cerrdefs.IsNotFound(err) {
+			l.Debug("Path not found, skipping")
+			return nil
+		}
+
+		return fmt.Errorf("failed to copy %s from node %s: %w", p, nodeName, err)
+	}
+	defer rc.Close()
+
+	// The outer tar header needs the size up front so spool to a temporary file.
+	tmp, err := os.CreateTemp("", "bi-snapshot-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, rc)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s from node %s: %w", p, nodeName, err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+
+	l.Debug("Adding path to snapshot", slog.Int64("size", size))
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name: path.Join("nodes", nodeName, p) + ".tar",
+		Mode: 0o600,
+		Size: size,
+	}); err != nil {
+		return fmt.Errorf("failed to write snapshot header for %s: %w", p, err)
+	}
+
+	if _, err := io.Copy(tw, tmp); err != nil {
+		return fmt.Errorf("failed to write %s to snapshot: %w", p, err)
+	}
+
+	return nil
+}
+
+// RestoreSnapshot replaces the state of a running cluster with the contents
+// of a snapshot archive previously written by SaveSnapshot. The cluster must
+// have the same name and nodes as the one the snapshot was taken from.
+func (c *KindClusterProvider) RestoreSnapshot(ctx context.Context, r io.Reader) error {
+	if c.dockerClient == nil {
+		return fmt.Errorf("docker client is required to restore a cluster")
+	}
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	manifest, err := readSnapshotManifest(tr)
+	if err != nil {
+		return err
+	}
+
+	if manifest.Cluster != c.name {
+		return fmt.Errorf("snapshot was taken from cluster %s not %s", manifest.Cluster, c.name)
+	}
+
+	if manifest.NodeImage != c.nodeImage {
+		c.logger.Warn("Snapshot was taken with a different node image",
+			slog.String("snapshot", manifest.NodeImage),
+			slog.String("current", c.nodeImage))
+	}
+
+	nodeList, err := c.kindProvider().ListNodes(c.name)
+	if err != nil {
+		return fmt.Errorf("failed to list kind nodes: %w", err)
+	}
+
+	for _, nodeName := range manifest.Nodes {
+		if !slices.ContainsFunc(nodeList, func(n nodes.Node) bool { return n.String() == nodeName }) {
+			return fmt.Errorf("node %s from snapshot not found in cluster", nodeName)
+		}
+	}
+
+	// Stop everything on the nodes and clear out the current state so that
+	// nothing from the fresh cluster is left behind.
+	clearScript := "systemctl stop kubelet && (crictl rm --all --force >/dev/null || true) && rm -rf " +
+		strings.Join(snapshotPaths, " ")
+
+	for _, node := range nodeList {
+		c.logger.Debug("Clearing node state", slog.String("node", node.String()))
+		if err := c.runScriptOnNode(ctx, node, clearScript); err != nil {
+			return fmt.Errorf("failed to clear state on node %s: %w", node.String(), err)
+		}
+	}
+
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+
+		nodeName, p, ok := parseSnapshotEntryName(header.Name)
+		if !ok {
+			c.logger.Warn("Skipping unexpected snapshot entry", slog.String("name", header.Name))
+			continue
+		}
+
+		c.logger.Debug("Restoring path", slog.String("node", nodeName), slog.String("path", p))
+
+		// The nested archive is rooted at the base name of the path.
+		if err := c.dockerClient.CopyToContainer(ctx, nodeName, path.Dir(p), tr, container.CopyToContainerOptions{}); err != nil {
+			return fmt.Errorf("failed to restore %s on node %s: %w", p, nodeName, err)
+		}
+	}
+
+	for _, node := range nodeList {
+		c.logger.Debug("Starting kubelet", slog.String("node", node.String()))
+		if err := c.runScriptOnNode(ctx, node, "systemctl start kubelet"); err != nil {
+			return fmt.Errorf("failed to start kubelet on node %s: %w", node.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSnapshot reads the whole of a snapshot archive and checks that it
+// can be restored to this cluster, so that problems are found before the
+// current cluster is torn down.
+func (c *KindClusterProvider) ValidateSnapshot(r io.Reader) (*SnapshotManifest, error) {
+	return validateSnapshot(r, c.name)
+}
+
+func validateSnapshot(r io.Reader, clusterName string) (*SnapshotManifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	manifest, err := readSnapshotManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Cluster != clusterName {
+		return nil, fmt.Errorf("snapshot was taken from cluster %s not %s", manifest.Cluster, clusterName)
+	}
+
+	if len(manifest.Nodes) == 0 {
+		return nil, fmt.Errorf("snapshot has no nodes")
+	}
+
+	hasEtcd := false
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+
+		nodeName, p, ok := parseSnapshotEntryName(header.Name)
+		if !ok {
+			continue
+		}
+
+		if !slices.Contains(manifest.Nodes, nodeName) {
+			return nil, fmt.Errorf("snapshot entry %s is for node %s which isn't in the manifest", header.Name, nodeName)
+		}
+
+		// Read each nested archive through to make sure it isn't truncated.
+		nested := tar.NewReader(tr)
+		for {
+			_, err := nested.Next()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				return nil, fmt.Errorf("snapshot entry %s is corrupt: %w", header.Name, err)
+			}
+
+			if _, err := io.Copy(io.Discard, nested); err != nil {
+				return nil, fmt.Errorf("snapshot entry %s is corrupt: %w", header.Name, err)
+			}
+		}
+
+		if p == "/var/lib/etcd" {
+			hasEtcd = true
+		}
+	}
+
+	if !hasEtcd {
+		return nil, fmt.Errorf("snapshot has no etcd data")
+	}
+
+	return manifest, nil
+}
+
+// ReadSnapshotManifest reads just the manifest from a snapshot archive.
+func ReadSnapshotManifest(r io.Reader) (*SnapshotManifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer gr.Close()
+
+	return readSnapshotManifest(tar.NewReader(gr))
+}
+
+func readSnapshotManifest(tr *tar.Reader) (*SnapshotManifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot manifest: %w", err)
+	}
+
+	if header.Name != snapshotManifestName {
+		return nil, fmt.Errorf("unexpected first snapshot entry: %s", header.Name)
+	}
+
+	manifest := &SnapshotManifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// parseSnapshotEntryName splits nodes/<node><path>.tar into the node name and path.
+func parseSnapshotEntryName(name string) (nodeName, p string, ok bool) {
+	rest, ok := strings.CutPrefix(name, "nodes/")
+	if !ok {
+		return "", "", false
+	}
+
+	rest, ok = strings.CutSuffix(rest, ".tar")
+	if !ok {
+		return "", "", false
+	}
+
+	nodeName, p, ok = strings.Cut(rest, "/")
+	if !ok || nodeName == "" || p == "" {
+		return "", "", false
+	}
+
+	return nodeName, "/" + p, true
+}
@@ -0,0 +1,141 @@
+package kind
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSnapshotEntryName(t *testing.T) {
+	testCases := []struct {
+		name, nodeName, path string
+		ok                   bool
+	}{
+		{name: "nodes/bi-control-plane/var/lib/etcd.tar", nodeName: "bi-control-plane", path: "/var/lib/etcd", ok: true},
+		{name: "nodes/bi-worker/etc/kubernetes/kubelet.conf.tar", nodeName: "bi-worker", path: "/etc/kubernetes/kubelet.conf", ok: true},
+		{name: "manifest.json", ok: false},
+		{name: "nodes/bi-control-plane.tar", ok: false},
+		{name: "nodes/bi-control-plane/var/lib/etcd", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeName, path, ok := parseSnapshotEntryName(tc.name)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.nodeName, nodeName)
+			require.Equal(t, tc.path, path)
+		})
+	}
+}
+
+func buildSnapshot(t *testing.T, manifest SnapshotManifest, entries map[string][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	manifestBytes, err := json.Marshal(&manifest)
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: snapshotManifestName, Mode: 0o600, Size: int64(len(manifestBytes))}))
+	_, err = tw.Write(manifestBytes)
+	require.NoError(t, err)
+
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(entries[name]))}))
+		_, err = tw.Write(entries[name])
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+func nestedTar(t *testing.T, name string, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+func Test_validateSnapshot(t *testing.T) {
+	manifest := SnapshotManifest{Cluster: "bi", Nodes: []string{"bi-control-plane"}}
+	etcd := nestedTar(t, "etcd/member/snap/db", strings.Repeat("data", 256))
+	pki := nestedTar(t, "pki/ca.crt", "cert")
+
+	testCases := []struct {
+		name     string
+		manifest SnapshotManifest
+		entries  map[string][]byte
+		err      string
+	}{
+		{
+			name:     "valid",
+			manifest: manifest,
+			entries: map[string][]byte{
+				"nodes/bi-control-plane/var/lib/etcd.tar":       etcd,
+				"nodes/bi-control-plane/etc/kubernetes/pki.tar": pki,
+			},
+		},
+		{
+			name:     "other cluster",
+			manifest: SnapshotManifest{Cluster: "other", Nodes: []string{"other-control-plane"}},
+			entries:  map[string][]byte{"nodes/other-control-plane/var/lib/etcd.tar": etcd},
+			err:      "snapshot was taken from cluster other not bi",
+		},
+		{
+			name:     "unknown node",
+			manifest: manifest,
+			entries: map[string][]byte{
+				"nodes/bi-control-plane/var/lib/etcd.tar": etcd,
+				"nodes/bi-worker/var/lib/etcd.tar":        etcd,
+			},
+			err: "is for node bi-worker which isn't in the manifest",
+		},
+		{
+			name:     "truncated entry",
+			manifest: manifest,
+			entries:  map[string][]byte{"nodes/bi-control-plane/var/lib/etcd.tar": etcd[:600]},
+			err:      "snapshot entry nodes/bi-control-plane/var/lib/etcd.tar is corrupt",
+		},
+		{
+			name:     "no etcd",
+			manifest: manifest,
+			entries:  map[string][]byte{"nodes/bi-control-plane/etc/kubernetes/pki.tar": pki},
+			err:      "snapshot has no etcd data",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			snapshot := buildSnapshot(t, tc.manifest, tc.entries)
+
+			got, err := validateSnapshot(bytes.NewReader(snapshot), "bi")
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.manifest.Nodes, got.Nodes)
+		})
+	}
+
+	_, err := validateSnapshot(bytes.NewReader([]byte("not a snapshot")), "bi")
+	require.ErrorContains(t, err, "failed to read snapshot")
+}
@@ -0,0 +1,65 @@
+package kind
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// DefaultKubernetesVersion should match the minor version of k8s that we're
+// running in AWS.
+const DefaultKubernetesVersion = "1.34"
+
+// nodeImages maps the Kubernetes minor versions that the control server
+// supports to pre-built kind node images.
+//
+// Kind only does sha256 in order to attach kind binary version to the OCI image version
+// This means that when we update kind dependency we should get the corresponding image
+// digests from the last kind release
+//
+// https://github.com/kubernetes-sigs/kind/releases/latest
+var nodeImages = map[string]string{
+	"1.34": "kindest/node:v1.34.0@sha256:7416a61b42b1662ca6ca89f02028ac133a309a2a30ba309614e8ec94d976dc5a",
+	"1.33": "kindest/node:v1.33.4@sha256:25a6018e48dfcaee478f4a59af81157a437f15e6e140bf103f85a2e7cd0cbbf2",
+	"1.32": "kindest/node:v1.32.8@sha256:abd489f042d2b644e2d033f5c2d900bc707798d075e8186cb65e3f1367a9d5a1",
+	"1.31": "kindest/node:v1.31.12@sha256:0f5cc49c5e73c0c2bb6e2df56e7df189240d83cf94edfa30946482eb08ec57d2",
+}
+
+// SupportedKubernetesVersions returns the supported Kubernetes minor
+// versions, newest first.
+func SupportedKubernetesVersions() []string {
+	versions := slices.Collect(maps.Keys(nodeImages))
+	slices.SortFunc(versions, func(a, b string) int {
+		return semver.Compare("v"+b, "v"+a)
+	})
+	return versions
+}
+
+// NodeImage returns the kind node image for a Kubernetes version. The version
+// may be a minor version (1.33) or a full version (v1.33.4), in which case
+// only the minor version is used. An empty version selects the default.
+func NodeImage(version string) (string, error) {
+	if version == "" {
+		version = DefaultKubernetesVersion
+	}
+
+	v := version
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+
+	if !semver.IsValid(v) {
+		return "", fmt.Errorf("invalid kubernetes version: %q", version)
+	}
+
+	image, ok := nodeImages[strings.TrimPrefix(semver.MajorMinor(v), "v")]
+	if !ok {
+		return "", fmt.Errorf("unsupported kubernetes version %q, supported versions are: %s",
+			version, strings.Join(SupportedKubernetesVersions(), ", "))
+	}
+
+	return image, nil
+}
@@ -0,0 +1,61 @@
+package kind
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			version: "",
+			want:    nodeImages[DefaultKubernetesVersion],
+		},
+		{
+			name:    "minor version",
+			version: "1.33",
+			want:    nodeImages["1.33"],
+		},
+		{
+			name:    "full version with prefix",
+			version: "v1.32.1",
+			want:    nodeImages["1.32"],
+		},
+		{
+			name:    "unsupported version",
+			version: "1.20",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			version: "latest",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NodeImage(tt.version)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSupportedKubernetesVersions(t *testing.T) {
+	versions := SupportedKubernetesVersions()
+	require.Len(t, versions, len(nodeImages))
+	require.Equal(t, DefaultKubernetesVersion, versions[0])
+}
@@ -9,6 +9,7 @@ import (
 	"bi/pkg/cluster/eks"
 	"bi/pkg/cluster/util"
 	"bi/pkg/specs"
+	"bi/pkg/wireguard"
 
 	"github.com/adrg/xdg"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
@@ -25,6 +26,17 @@ const (
 type pulumiProvider struct {
 	initSuccessful bool
 	spec           *specs.InstallSpec
+	// userConfig is the config from bi.yaml, the spec takes precedence over it
+	userConfig map[string]any
+	backend    PulumiBackendConfig
+	passphrase string
+	// wireGuardStatePath is where issued wireguard clients are saved
+	wireGuardStatePath string
+	// deferPassphraseWrite is set when migrating, a new passphrase is used
+	// even if there is existing state and it is saved to
+	// pendingPassphrasePath once the migration succeeds.
+	deferPassphraseWrite  bool
+	pendingPassphrasePath string
 
 	cfg auto.ConfigMap
 	projectName,
@@ -35,10 +47,13 @@ type pulumiProvider struct {
 	envVars    auto.LocalWorkspaceOption
 }
 
-func NewPulumiProvider(spec *specs.InstallSpec) Provider {
+func NewPulumiProvider(spec *specs.InstallSpec, userConfig map[string]any, backend PulumiBackendConfig, wireGuardStatePath string) Provider {
 	return &pulumiProvider{
-		projectName: "bi",
-		spec:        spec,
+		projectName:        "bi",
+		spec:               spec,
+		userConfig:         userConfig,
+		backend:            backend,
+		wireGuardStatePath: wireGuardStatePath,
 	}
 }
 
@@ -55,19 +70,51 @@ func (p *pulumiProvider) Init(ctx context.Context) error {
 
 // configure sets up configuration common to all substacks
 func (p *pulumiProvider) configure(ctx context.Context) (auto.Workspace, error) {
-	stackName := auto.FullyQualifiedStackName("organization", p.projectName, p.spec.Slug)
+	cfg, err := pulumiConfig(p.projectName, p.spec, p.userConfig)
+	if err != nil {
+		return nil, err
+	}
+	p.cfg = cfg
+
+	dirs, err := p.makeDirs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create necessary directories: %w", err)
+	}
+
+	cmd, err := auto.InstallPulumiCommand(ctx, &auto.PulumiCommandOptions{Root: dirs[homeDir]})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download pulumi cli: %w", err)
+	}
+
+	p.workDirRoot = dirs[workDir]
+	p.pulumi = auto.Pulumi(cmd)
+	p.pulumiHome = auto.PulumiHome(dirs[homeDir])
+
+	passphrase, err := p.resolvePassphrase(!p.deferPassphraseWrite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pulumi passphrase: %w", err)
+	}
+	p.setPassphrase(passphrase)
+
+	return p.createWorkspace(ctx)
+}
+
+// pulumiConfig builds the config shared by all the component stacks: the
+// defaults, then bi.yaml and finally the spec overrides.
+func pulumiConfig(projectName string, spec *specs.InstallSpec, userConfig map[string]any) (auto.ConfigMap, error) {
+	stackName := auto.FullyQualifiedStackName("organization", projectName, spec.Slug)
 
 	tags, err := newTags(stackName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tags for %s: %w", stackName, err)
 	}
 
-	baseNS, err := p.spec.GetBatteryConfigField("battery_core", "base_namespace")
+	baseNS, err := spec.GetBatteryConfigField("battery_core", "base_namespace")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get base namespace: %w", err)
 	}
 
-	p.cfg = auto.ConfigMap{
+	cfg := auto.ConfigMap{
 		"aws:defaultTags":        {Value: tags},
 		"aws:region":             {Value: "us-east-2"},
 		"cluster:amiType":        {Value: "AL2023_x86_64_STANDARD"},
@@ -76,11 +123,12 @@ func (p *pulumiProvider) configure(ctx context.Context) (auto.Workspace, error)
 		"cluster:instanceType":   {Value: "t3a.medium"},
 		"cluster:maxSize":        {Value: "4"},
 		"cluster:minSize":        {Value: "2"},
-		"cluster:name":           {Value: p.spec.Slug},
+		"cluster:name":           {Value: spec.Slug},
 		"cluster:version":        {Value: "1.34"},
 		"cluster:volumeSize":     {Value: "20"},
 		"cluster:volumeType":     {Value: "gp3"},
 		"gateway:cidrBlock":      {Value: "100.64.250.0/24"},
+		"gateway:count":          {Value: "1"},
 		"gateway:generateSSHKey": {Value: "false"},
 		"gateway:instanceType":   {Value: "t3a.micro"},
 		"gateway:port":           {Value: "51820"},
@@ -91,22 +139,20 @@ func (p *pulumiProvider) configure(ctx context.Context) (auto.Workspace, error)
 		"vpc:cidrBlock":          {Value: "100.64.0.0/16"},
 	}
 
-	dirs, err := p.makeDirs()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create necessary directories: %w", err)
+	if err := applyConfigOverrides(cfg, "bi.yaml", userConfig); err != nil {
+		return nil, err
 	}
 
-	cmd, err := auto.InstallPulumiCommand(ctx, &auto.PulumiCommandOptions{Root: dirs[homeDir]})
-	if err != nil {
-		return nil, fmt.Errorf("failed to download pulumi cli: %w", err)
+	if err := applyConfigOverrides(cfg, "kube cluster", spec.KubeCluster.Config); err != nil {
+		return nil, err
 	}
 
-	p.workDirRoot = dirs[workDir]
-	p.pulumi = auto.Pulumi(cmd)
-	p.pulumiHome = auto.PulumiHome(dirs[homeDir])
-	p.envVars = auto.EnvVars(map[string]string{"PULUMI_CONFIG_PASSPHRASE": "PASSWORD"})
+	// Validate up front rather than part way through bringing up the stacks.
+	if _, err := util.ParsePulumiConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid aws config: %w", err)
+	}
 
-	return p.createWorkspace(ctx)
+	return cfg, nil
 }
 
 // makeDirs makes the necessary subdirectories under $XDG_STATE_HOME
@@ -134,7 +180,7 @@ func (p *pulumiProvider) createWorkspace(ctx context.Context) (auto.Workspace, e
 		auto.Project(workspace.Project{
 			Name:    tokens.PackageName(p.projectName),
 			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
-			Backend: &workspace.ProjectBackend{URL: fmt.Sprintf("file://%s", p.workDirRoot)},
+			Backend: &workspace.ProjectBackend{URL: p.backendURL()},
 		}),
 		auto.Program(func(ctx *pulumi.Context) error { return nil }),
 	)
@@ -188,11 +234,107 @@ func (p *pulumiProvider) WriteWireGuardConfig(ctx context.Context, w io.Writer)
 
 }
 
+// WireGuardGatewayEndpoints returns the endpoint of every gateway instance.
+func (p *pulumiProvider) WireGuardGatewayEndpoints(ctx context.Context) ([]string, error) {
+	if !p.initSuccessful {
+		return nil, fmt.Errorf("attempted to get wireguard endpoints with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).WireGuardGatewayEndpoints(ctx)
+}
+
+// WireGuardPeers returns the peers added to the gateway.
+func (p *pulumiProvider) WireGuardPeers(ctx context.Context) ([]eks.Peer, error) {
+	if !p.initSuccessful {
+		return nil, fmt.Errorf("attempted to list wireguard peers with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).Peers(ctx)
+}
+
+// AddWireGuardPeer adds a peer to the gateway.
+func (p *pulumiProvider) AddWireGuardPeer(ctx context.Context, name string, w io.Writer) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to add wireguard peer with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).AddPeer(ctx, name, w)
+}
+
+// RemoveWireGuardPeer removes a peer from the gateway.
+func (p *pulumiProvider) RemoveWireGuardPeer(ctx context.Context, name string) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to remove wireguard peer with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).RemovePeer(ctx, name)
+}
+
+// WireGuardClients returns the installer and the clients issued to people.
+func (p *pulumiProvider) WireGuardClients(ctx context.Context) ([]wireguard.Client, error) {
+	if !p.initSuccessful {
+		return nil, fmt.Errorf("attempted to list wireguard clients with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).Clients(ctx)
+}
+
+// AddWireGuardClient issues a client config for a person.
+func (p *pulumiProvider) AddWireGuardClient(ctx context.Context, name string, w io.Writer) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to add wireguard client with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).AddClient(ctx, name, w)
+}
+
+// RevokeWireGuardClient removes a client issued to a person.
+func (p *pulumiProvider) RevokeWireGuardClient(ctx context.Context, name string) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to revoke wireguard client with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).RevokeClient(ctx, name)
+}
+
+// RotateWireGuardGateway replaces the gateway's key.
+func (p *pulumiProvider) RotateWireGuardGateway(ctx context.Context) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to rotate wireguard gateway key with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).RotateGateway(ctx)
+}
+
+// RotateWireGuardClient replaces the key of a client.
+func (p *pulumiProvider) RotateWireGuardClient(ctx context.Context, name string, w io.Writer) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to rotate wireguard client key with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).RotateClient(ctx, name, w)
+}
+
+func (p *pulumiProvider) setPassphrase(passphrase string) {
+	p.passphrase = passphrase
+	p.envVars = auto.EnvVars(map[string]string{"PULUMI_CONFIG_PASSPHRASE": passphrase})
+}
+
+func (p *pulumiProvider) backendURL() string {
+	if p.backend.URL != "" {
+		return p.backend.URL
+	}
+	return fmt.Sprintf("file://%s", p.workDirRoot)
+}
+
 func (p *pulumiProvider) toEKSConfig() *eks.Config {
 	return &eks.Config{
 		ProjectBaseName: p.projectName,
 		Slug:            p.spec.Slug,
 		WorkDirRoot:     p.workDirRoot,
+		BackendURL:      p.backend.URL,
+
+		WireGuardStatePath: p.wireGuardStatePath,
 
 		Config:     p.cfg,
 		PulumiHome: p.pulumiHome,
@@ -205,3 +347,64 @@ func (p *pulumiProvider) toEKSConfig() *eks.Config {
 func (p *pulumiProvider) HasNvidiaRuntimeInstalled() bool {
 	return false
 }
+
+// Preview reports the changes that Create would make to each component.
+func (p *pulumiProvider) Preview(ctx context.Context) ([]eks.ChangeSummary, error) {
+	if !p.initSuccessful {
+		return nil, fmt.Errorf("attempted to preview with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).Preview(ctx)
+}
+
+// Refresh syncs the state of each component with AWS and reports any drift.
+func (p *pulumiProvider) Refresh(ctx context.Context) ([]eks.ChangeSummary, error) {
+	if !p.initSuccessful {
+		return nil, fmt.Errorf("attempted to refresh with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).Refresh(ctx)
+}
+
+// CreateComponents creates or updates only the named components.
+func (p *pulumiProvider) CreateComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to create with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).UpComponents(ctx, names, progressReporter)
+}
+
+// DestroyComponents destroys only the named components.
+func (p *pulumiProvider) DestroyComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to destroy with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).DestroyComponents(ctx, names, progressReporter)
+}
+
+// Upgrade moves the cluster to the next kubernetes minor version.
+func (p *pulumiProvider) Upgrade(ctx context.Context, to string, progressReporter *util.ProgressReporter) error {
+	if !p.initSuccessful {
+		return fmt.Errorf("attempted to upgrade with uninitialized provider")
+	}
+
+	return eks.New(p.toEKSConfig()).Upgrade(ctx, to, progressReporter)
+}
+
+// EstimateEKSCost prices the stacks that would be created for the spec. It
+// only needs the config so nothing is downloaded and AWS isn't contacted.
+func EstimateEKSCost(spec *specs.InstallSpec, userConfig map[string]any, prices eks.PriceTable, opts eks.EstimateOptions) ([]eks.CostEstimate, error) {
+	cfg, err := pulumiConfig("bi", spec, userConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	pConfig, err := util.ParsePulumiConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pulumi config: %w", err)
+	}
+
+	return eks.Estimate(pConfig, prices, opts)
+}
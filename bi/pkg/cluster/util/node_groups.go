@@ -0,0 +1,154 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// bootstrapNodeGroupName is the name of the node group built from the
+// cluster:* config keys when no node groups are configured.
+const bootstrapNodeGroupName = "bootstrap"
+
+var nodeGroupNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// NodeGroup is the config for an EKS managed node group
+type NodeGroup struct {
+	// Name is appended to the cluster name to name the node group resources
+	Name string `json:"name"`
+	// AmiType is the EKS AMI Type e.g. AL2023_x86_64_STANDARD or AL2023_x86_64_NVIDIA for GPUs
+	AmiType string `json:"amiType"`
+	// CapacityType determines the type of instance to use e.g. SPOT or ON_DEMAND
+	CapacityType string `json:"capacityType"`
+	// InstanceTypes are the types of instance the node group can use e.g. t3a.medium
+	InstanceTypes []string `json:"instanceTypes"`
+	DesiredSize   int      `json:"desiredSize"`
+	MaxSize       int      `json:"maxSize"`
+	MinSize       int      `json:"minSize"`
+	// VolumeSize is the size of the root volume of the instances
+	VolumeSize int `json:"volumeSize"`
+	// VolumeType is the type of the root EBS volume of the instances e.g. gp3
+	VolumeType string `json:"volumeType"`
+	// Labels are added to the kubernetes nodes
+	Labels map[string]string `json:"labels"`
+	// Taints are added to the kubernetes nodes
+	Taints []NodeGroupTaint `json:"taints"`
+}
+
+type NodeGroupTaint struct {
+	Key string `json:"key"`
+	// Value is optional
+	Value string `json:"value"`
+	// Effect is one of NO_SCHEDULE, NO_EXECUTE or PREFER_NO_SCHEDULE
+	Effect string `json:"effect"`
+}
+
+// defaultNodeGroups is the single bootstrap node group that's used if no node
+// groups are configured. It only runs critical addons, everything else is
+// scheduled onto nodes launched by karpenter.
+func defaultNodeGroups(c cluster) []NodeGroup {
+	return []NodeGroup{{
+		Name:          bootstrapNodeGroupName,
+		AmiType:       c.AmiType,
+		CapacityType:  c.CapacityType,
+		InstanceTypes: []string{c.InstanceType},
+		DesiredSize:   c.DesiredSize,
+		MaxSize:       c.MaxSize,
+		MinSize:       c.MinSize,
+		VolumeSize:    c.VolumeSize,
+		VolumeType:    c.VolumeType,
+		Taints: []NodeGroupTaint{
+			{Key: "CriticalAddonsOnly", Value: "true", Effect: "NO_SCHEDULE"},
+		},
+	}}
+}
+
+// parseNodeGroups parses the cluster:nodeGroups config (a JSON list). Unset
+// amiType, capacityType, instanceTypes, volumeSize and volumeType fields
+// default to the cluster:* config values. The sizes don't default, as 0 is a
+// valid min or desired size, so they have to be set on every node group.
+func parseNodeGroups(s string, c cluster) ([]NodeGroup, error) {
+	if s == "" {
+		return defaultNodeGroups(c), nil
+	}
+
+	var groups []NodeGroup
+	if err := json.Unmarshal([]byte(s), &groups); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node groups: %w", err)
+	}
+
+	for i := range groups {
+		ng := &groups[i]
+		if ng.AmiType == "" {
+			ng.AmiType = c.AmiType
+		}
+		if ng.CapacityType == "" {
+			ng.CapacityType = c.CapacityType
+		}
+		if len(ng.InstanceTypes) == 0 {
+			ng.InstanceTypes = []string{c.InstanceType}
+		}
+		if ng.VolumeSize == 0 {
+			ng.VolumeSize = c.VolumeSize
+		}
+		if ng.VolumeType == "" {
+			ng.VolumeType = c.VolumeType
+		}
+	}
+
+	return groups, nil
+}
+
+func validateNodeGroups(groups []NodeGroup) error {
+	if len(groups) == 0 {
+		return errors.New("at least one node group is required")
+	}
+
+	var errs []error
+	var names []string
+	for _, ng := range groups {
+		if !nodeGroupNameRegexp.MatchString(ng.Name) {
+			errs = append(errs, fmt.Errorf("node group name must be lowercase alphanumeric or '-', got %q", ng.Name))
+		}
+
+		if slices.Contains(names, ng.Name) {
+			errs = append(errs, fmt.Errorf("duplicate node group name %q", ng.Name))
+		}
+		names = append(names, ng.Name)
+
+		if !slices.Contains([]string{"ON_DEMAND", "SPOT"}, ng.CapacityType) {
+			errs = append(errs, fmt.Errorf("node group %s: capacityType must be ON_DEMAND or SPOT, got %q", ng.Name, ng.CapacityType))
+		}
+
+		if ng.AmiType == "" {
+			errs = append(errs, fmt.Errorf("node group %s: amiType must be set", ng.Name))
+		}
+
+		if slices.Contains(ng.InstanceTypes, "") {
+			errs = append(errs, fmt.Errorf("node group %s: instance types must be set", ng.Name))
+		}
+
+		if ng.MinSize < 0 || ng.MinSize > ng.DesiredSize || ng.DesiredSize > ng.MaxSize || ng.MaxSize < 1 {
+			errs = append(errs, fmt.Errorf("node group %s: sizes must satisfy 0 <= minSize <= desiredSize <= maxSize and maxSize >= 1, got %d/%d/%d",
+				ng.Name, ng.MinSize, ng.DesiredSize, ng.MaxSize))
+		}
+
+		if ng.VolumeSize < 1 {
+			errs = append(errs, fmt.Errorf("node group %s: volumeSize must be positive, got %d", ng.Name, ng.VolumeSize))
+		}
+
+		for _, t := range ng.Taints {
+			if t.Key == "" {
+				errs = append(errs, fmt.Errorf("node group %s: taint key must be set", ng.Name))
+			}
+
+			if !slices.Contains([]string{"NO_SCHEDULE", "NO_EXECUTE", "PREFER_NO_SCHEDULE"}, t.Effect) {
+				errs = append(errs, fmt.Errorf("node group %s: taint effect must be NO_SCHEDULE, NO_EXECUTE or PREFER_NO_SCHEDULE, got %q", ng.Name, t.Effect))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseNodeGroups(t *testing.T) {
+	defaults := cluster{
+		AmiType:      "AL2023_x86_64_STANDARD",
+		CapacityType: "ON_DEMAND",
+		InstanceType: "t3a.medium",
+		DesiredSize:  2,
+		MaxSize:      4,
+		MinSize:      2,
+		VolumeSize:   20,
+		VolumeType:   "gp3",
+	}
+
+	testCases := []struct {
+		name    string
+		config  string
+		want    []NodeGroup
+		wantErr bool
+	}{
+		{
+			name:   "unset uses bootstrap group",
+			config: "",
+			want:   defaultNodeGroups(defaults),
+		},
+		{
+			name: "defaults unset fields",
+			config: `[
+				{"name": "system", "desiredSize": 1, "minSize": 1, "maxSize": 2},
+				{"name": "gpu", "amiType": "AL2023_x86_64_NVIDIA", "capacityType": "SPOT", "instanceTypes": ["g5.xlarge", "g6.xlarge"],
+				 "maxSize": 2, "labels": {"gpu": "true"}, "taints": [{"key": "nvidia.com/gpu", "effect": "NO_SCHEDULE"}]}
+			]`,
+			want: []NodeGroup{
+				{
+					Name:          "system",
+					AmiType:       "AL2023_x86_64_STANDARD",
+					CapacityType:  "ON_DEMAND",
+					InstanceTypes: []string{"t3a.medium"},
+					DesiredSize:   1,
+					MaxSize:       2,
+					MinSize:       1,
+					VolumeSize:    20,
+					VolumeType:    "gp3",
+				},
+				{
+					Name:          "gpu",
+					AmiType:       "AL2023_x86_64_NVIDIA",
+					CapacityType:  "SPOT",
+					InstanceTypes: []string{"g5.xlarge", "g6.xlarge"},
+					MaxSize:       2,
+					VolumeSize:    20,
+					VolumeType:    "gp3",
+					Labels:        map[string]string{"gpu": "true"},
+					Taints:        []NodeGroupTaint{{Key: "nvidia.com/gpu", Effect: "NO_SCHEDULE"}},
+				},
+			},
+		},
+		{
+			name:    "invalid json",
+			config:  `{"name": "system"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseNodeGroups(tc.config, defaults)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+			require.NoError(t, validateNodeGroups(got))
+		})
+	}
+}
+
+func Test_validateNodeGroups(t *testing.T) {
+	valid := NodeGroup{
+		Name:          "system",
+		AmiType:       "AL2023_x86_64_STANDARD",
+		CapacityType:  "ON_DEMAND",
+		InstanceTypes: []string{"t3a.medium"},
+		DesiredSize:   1,
+		MaxSize:       1,
+		MinSize:       1,
+		VolumeSize:    20,
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(ng *NodeGroup)
+	}{
+		{name: "bad name", modify: func(ng *NodeGroup) { ng.Name = "System_1" }},
+		{name: "bad capacity type", modify: func(ng *NodeGroup) { ng.CapacityType = "RESERVED" }},
+		{name: "empty instance type", modify: func(ng *NodeGroup) { ng.InstanceTypes = []string{""} }},
+		{name: "desired above max", modify: func(ng *NodeGroup) { ng.DesiredSize = 2 }},
+		{name: "bad taint effect", modify: func(ng *NodeGroup) { ng.Taints = []NodeGroupTaint{{Key: "a", Effect: "NoSchedule"}} }},
+	}
+
+	require.NoError(t, validateNodeGroups([]NodeGroup{valid}))
+	require.Error(t, validateNodeGroups(nil))
+	require.Error(t, validateNodeGroups([]NodeGroup{valid, valid}), "duplicate names")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ng := valid
+			tc.modify(&ng)
+			require.Error(t, validateNodeGroups([]NodeGroup{ng}))
+		})
+	}
+}
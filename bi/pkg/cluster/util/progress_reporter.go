@@ -122,6 +122,19 @@ func (pr *ProgressReporter) ForInitialSync() *mpb.Bar {
 	)
 }
 
+// ForKubeCleanup creates a new progress bar for removing the kubernetes
+// resources of an install, one step at a time.
+func (pr *ProgressReporter) ForKubeCleanup(steps int) *mpb.Bar {
+	return pr.progress.AddBar(int64(steps),
+		mpb.PrependDecorators(
+			decor.Name("kube cleanup", decor.WC{C: decor.DindentRight | decor.DextraSpace}),
+		),
+		mpb.AppendDecorators(
+			decor.Percentage(),
+		),
+	)
+}
+
 // ForHealthCheck creates a new progress bar for HTTP health check operations.
 func (pr *ProgressReporter) ForHealthCheck() *mpb.Bar {
 	return pr.progress.AddBar(10, // retry attempts
@@ -2,8 +2,10 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"regexp"
 	"slices"
 	"strconv"
 
@@ -70,12 +72,19 @@ type cluster struct {
 	VolumeSize int
 	// VolumeType is the type of the root EBS volume for the bootstrap node group instances e.g. gp3
 	VolumeType string
+	// NodeGroups are the managed node groups. If none are configured, a
+	// single bootstrap node group is built from the values above, which are
+	// otherwise used as defaults for each group.
+	NodeGroups []NodeGroup
 }
 
 // wireguard gateway config
 type gateway struct {
 	// CIDRBlock is the cidr to use for the wireguard networks e.g. 100.64.250.0/24
 	CIDRBlock *net.IPNet
+	// Count is the number of gateway instances, they are spread across AZs
+	// and share the same wireguard config
+	Count int
 	// GenerateSSHKey determines whether an SSH key is created and used
 	GenerateSSHKey bool
 	// InstanceType is the type of instance to use for the wireguard bastion
@@ -96,11 +105,61 @@ type lbController struct{ Namespace string }
 type vpc struct {
 	// CIDRBlock is the cidr to use for the VPC e.g. 100.64.0.0/16
 	CIDRBlock *net.IPNet
+	// ID is an existing VPC to use instead of creating one
+	ID string
+	// PublicSubnetIDs are the existing public subnets to use, required with ID
+	PublicSubnetIDs []string
+	// PrivateSubnetIDs are the existing private subnets to use, required with ID
+	PrivateSubnetIDs []string
 }
 
+// Existing returns true if an existing VPC should be used rather than
+// creating one.
+func (v vpc) Existing() bool {
+	return v.ID != ""
+}
+
+// ConfigKeys are all the keys that ParsePulumiConfig understands.
+var ConfigKeys = []string{
+	"aws:defaultTags",
+	"aws:region",
+	"cluster:amiType",
+	"cluster:capacityType",
+	"cluster:desiredSize",
+	"cluster:instanceType",
+	"cluster:maxSize",
+	"cluster:minSize",
+	"cluster:name",
+	"cluster:nodeGroups",
+	"cluster:version",
+	"cluster:volumeSize",
+	"cluster:volumeType",
+	"gateway:cidrBlock",
+	"gateway:count",
+	"gateway:generateSSHKey",
+	"gateway:instanceType",
+	"gateway:port",
+	"gateway:volumeSize",
+	"gateway:volumeType",
+	"karpenter:namespace",
+	"lbcontroller:namespace",
+	"vpc:cidrBlock",
+	"vpc:id",
+	"vpc:privateSubnetIDs",
+	"vpc:publicSubnetIDs",
+}
+
+var clusterVersionRegexp = regexp.MustCompile(`^\d+\.\d+$`)
+
 // ParsePulumiConfig parses pulumi cfg into a single struct that can be passed
 // around instead of repeatedly parsing and formatting config
 func ParsePulumiConfig(cfg auto.ConfigMap) (*PulumiConfig, error) {
+	for key := range cfg {
+		if !slices.Contains(ConfigKeys, key) {
+			return nil, fmt.Errorf("unknown config key: %s", key)
+		}
+	}
+
 	tags, err := parseDefaultTags(cfg["aws:defaultTags"].Value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse default tags: %w", err)
@@ -111,6 +170,11 @@ func ParsePulumiConfig(cfg auto.ConfigMap) (*PulumiConfig, error) {
 		return nil, fmt.Errorf("failed to parse gateway CIDR: %w", err)
 	}
 
+	gwCount, err := strconv.Atoi(cfg["gateway:count"].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse gateway count: %w", err)
+	}
+
 	genKey, err := strconv.ParseBool(cfg["gateway:generateSSHKey"].Value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse generateSSH flag: %w", err)
@@ -170,6 +234,7 @@ func ParsePulumiConfig(cfg auto.ConfigMap) (*PulumiConfig, error) {
 		},
 		Gateway: gateway{
 			CIDRBlock:      gwCIDR,
+			Count:          gwCount,
 			GenerateSSHKey: genKey,
 			InstanceType:   cfg["gateway:instanceType"].Value,
 			Port:           port,
@@ -178,12 +243,112 @@ func ParsePulumiConfig(cfg auto.ConfigMap) (*PulumiConfig, error) {
 		},
 		Karpenter:    karpenter{Namespace: cfg["karpenter:namespace"].Value},
 		LBController: lbController{Namespace: cfg["lbcontroller:namespace"].Value},
-		VPC:          vpc{CIDRBlock: vpcCIDR},
+		VPC: vpc{
+			CIDRBlock: vpcCIDR,
+			ID:        cfg["vpc:id"].Value,
+		},
+	}
+
+	pc.VPC.PublicSubnetIDs, err = parseStringList(cfg["vpc:publicSubnetIDs"].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public subnet IDs: %w", err)
+	}
+
+	pc.VPC.PrivateSubnetIDs, err = parseStringList(cfg["vpc:privateSubnetIDs"].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private subnet IDs: %w", err)
+	}
+
+	pc.Cluster.NodeGroups, err = parseNodeGroups(cfg["cluster:nodeGroups"].Value, pc.Cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node groups: %w", err)
+	}
+
+	if err := pc.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
 	return pc, nil
 }
 
+// validate checks the parsed values make sense before we hand them to AWS.
+func (pc *PulumiConfig) validate() error {
+	var errs []error
+
+	if pc.AWS.Region == "" {
+		errs = append(errs, errors.New("aws:region must be set"))
+	}
+
+	if !clusterVersionRegexp.MatchString(pc.Cluster.Version) {
+		errs = append(errs, fmt.Errorf("cluster:version must be a major.minor version e.g. 1.34, got %q", pc.Cluster.Version))
+	}
+
+	if err := validateNodeGroups(pc.Cluster.NodeGroups); err != nil {
+		errs = append(errs, err)
+	}
+
+	if pc.Gateway.Count < 1 {
+		errs = append(errs, fmt.Errorf("gateway:count must be at least 1, got %d", pc.Gateway.Count))
+	}
+
+	if pc.Gateway.InstanceType == "" {
+		errs = append(errs, errors.New("gateway:instanceType must be set"))
+	}
+
+	if pc.Gateway.Port < 1 || pc.Gateway.Port > 65535 {
+		errs = append(errs, fmt.Errorf("gateway:port must be between 1 and 65535, got %d", pc.Gateway.Port))
+	}
+
+	if pc.Gateway.VolumeSize < 1 {
+		errs = append(errs, fmt.Errorf("gateway:volumeSize must be positive, got %d", pc.Gateway.VolumeSize))
+	}
+
+	errs = append(errs, pc.VPC.validate()...)
+
+	return errors.Join(errs...)
+}
+
+func (v vpc) validate() []error {
+	var errs []error
+
+	if !v.Existing() {
+		if len(v.PublicSubnetIDs) > 0 || len(v.PrivateSubnetIDs) > 0 {
+			errs = append(errs, errors.New("vpc:id must be set when using existing subnets"))
+		}
+		return errs
+	}
+
+	if len(v.PublicSubnetIDs) == 0 {
+		errs = append(errs, errors.New("vpc:publicSubnetIDs must be set when using an existing vpc"))
+	}
+
+	if len(v.PrivateSubnetIDs) == 0 {
+		errs = append(errs, errors.New("vpc:privateSubnetIDs must be set when using an existing vpc"))
+	}
+
+	for _, id := range v.PublicSubnetIDs {
+		if slices.Contains(v.PrivateSubnetIDs, id) {
+			errs = append(errs, fmt.Errorf("subnet %s can't be both public and private", id))
+		}
+	}
+
+	return errs
+}
+
+// parseStringList parses a JSON array of strings, an empty string is an empty list.
+func parseStringList(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var out []string
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, fmt.Errorf("expected a JSON list of strings: %w", err)
+	}
+
+	return out, nil
+}
+
 func parseDefaultTags(s string) (map[string]string, error) {
 	// don't use Tags as we want to keep the full tags and it doesn't make
 	// sense to go unmarshal and then re-marshal?
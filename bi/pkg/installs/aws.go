@@ -0,0 +1,135 @@
+package installs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"bi/pkg/cluster"
+	"bi/pkg/cluster/eks"
+	"bi/pkg/cluster/util"
+	biviper "bi/pkg/viper"
+)
+
+// PreviewKubeProvider reports what starting the install would change.
+func (env *InstallEnv) PreviewKubeProvider(ctx context.Context) ([]eks.ChangeSummary, error) {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return stackProvider.Preview(ctx)
+}
+
+// RefreshKubeProvider syncs the install state with the cloud provider and
+// reports anything that had drifted.
+func (env *InstallEnv) RefreshKubeProvider(ctx context.Context) ([]eks.ChangeSummary, error) {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return stackProvider.Refresh(ctx)
+}
+
+// CreateKubeComponents creates or updates only the named components of the
+// cluster and rewrites any local config that depends on them.
+func (env *InstallEnv) CreateKubeComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := stackProvider.CreateComponents(ctx, names, progressReporter); err != nil {
+		return fmt.Errorf("error creating components: %w", err)
+	}
+
+	// Rebuilding the gateway can change its public IPs.
+	if slices.Contains(names, "gateway") {
+		if err := env.WriteWireGuardConfig(ctx, true); err != nil {
+			return fmt.Errorf("error writing wireguard config after creating components: %w", err)
+		}
+	}
+
+	if slices.Contains(names, "cluster") {
+		if err := env.WriteKubeConfig(ctx, true); err != nil {
+			return fmt.Errorf("error writing kubeconfig after creating components: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DestroyKubeComponents destroys only the named components of the cluster.
+func (env *InstallEnv) DestroyKubeComponents(ctx context.Context, names []string, progressReporter *util.ProgressReporter) error {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	return stackProvider.DestroyComponents(ctx, names, progressReporter)
+}
+
+// EstimateKubeProvider prices the AWS resources the install would create.
+// Unlike the other stack operations this works before Init as it only needs
+// the config.
+func (env *InstallEnv) EstimateKubeProvider(prices eks.PriceTable, opts eks.EstimateOptions) ([]eks.CostEstimate, error) {
+	provider := env.Spec.KubeCluster.Provider
+	if provider != "aws" {
+		return nil, fmt.Errorf("only supported for aws installs, not provider: %s", provider)
+	}
+
+	return cluster.EstimateEKSCost(env.Spec, biviper.EKSConfig(), prices, opts)
+}
+
+func (env *InstallEnv) stackClusterProvider() (cluster.StackProvider, error) {
+	provider := env.Spec.KubeCluster.Provider
+	if provider != "aws" {
+		return nil, fmt.Errorf("only supported for aws installs, not provider: %s", provider)
+	}
+
+	stackProvider, ok := env.clusterProvider.(cluster.StackProvider)
+	if !ok {
+		return nil, errors.New("install has not been initialized with a pulumi cluster provider")
+	}
+
+	return stackProvider, nil
+}
+
+// UpgradeKubeProvider moves the cluster to the next kubernetes minor version,
+// records the new version in the spec and checks that the managed nodes were
+// upgraded.
+func (env *InstallEnv) UpgradeKubeProvider(ctx context.Context, to string, progressReporter *util.ProgressReporter) error {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := stackProvider.Upgrade(ctx, to, progressReporter); err != nil {
+		return fmt.Errorf("error upgrading cluster: %w", err)
+	}
+
+	// Otherwise the next start would try to take the cluster back down.
+	env.Spec.SetKubernetesVersion(to)
+	if err := env.WriteSpec(true); err != nil {
+		return fmt.Errorf("error writing spec after upgrade: %w", err)
+	}
+
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating kube client: %w", err)
+	}
+	defer kubeClient.Close()
+
+	versions, err := kubeClient.ListNodeVersions(ctx, eks.ManagedNodeGroupLabel)
+	if err != nil {
+		return err
+	}
+
+	if err := eks.CheckNodeVersions(versions, to); err != nil {
+		return fmt.Errorf("cluster upgraded but nodes weren't: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package installs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"bi/pkg/kube"
+)
+
+// UninstallBattery removes a battery's resources from the cluster. The
+// battery is also removed from the install's target summary, both the local
+// copy and the one in the cluster, unless the control server has its own copy
+// of the installed batteries. The summaries would then no longer match what
+// the control server reinstalls.
+func (env *InstallEnv) UninstallBattery(ctx context.Context, batteryType string, opts kube.RemoveAllOptions) error {
+	if batteryType == "battery_core" {
+		return errors.New("battery_core can't be uninstalled, stop the install instead")
+	}
+
+	battery, err := env.Spec.GetBatteryByType(batteryType)
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer kubeClient.Close()
+
+	slog.Info("Removing battery resources", slog.String("type", batteryType), slog.String("id", battery.ID))
+
+	if err := kubeClient.RemoveBattery(ctx, battery.ID, opts); err != nil {
+		return fmt.Errorf("unable to remove battery %s: %w", batteryType, err)
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	hasControlServer, err := env.hasControlServer(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	if hasControlServer {
+		slog.Warn("The control server still has the battery and reinstalls it, remove the battery with the control server to uninstall it for good",
+			slog.String("type", batteryType))
+		return nil
+	}
+
+	if err := env.Spec.RemoveBattery(batteryType); err != nil {
+		return err
+	}
+
+	if err := env.WriteSpec(true); err != nil {
+		return err
+	}
+
+	if err := env.WriteSummary(true); err != nil {
+		return err
+	}
+
+	if err := env.Spec.UpdateSummaryInKube(ctx, kubeClient); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// hasControlServer returns whether a control server, and with it a copy of
+// the installed batteries, exists for the install. In development the control
+// server runs outside of the cluster so it always has one.
+func (env *InstallEnv) hasControlServer(ctx context.Context, kubeClient kube.KubeClient) (bool, error) {
+	usage, err := env.Spec.GetCoreUsage()
+	if err != nil {
+		return false, err
+	}
+	if usage == "internal_dev" {
+		return true, nil
+	}
+
+	ns, err := env.Spec.GetCoreNamespace()
+	if err != nil {
+		return false, err
+	}
+
+	return kubeClient.ControlServerDeployed(ctx, ns)
+}
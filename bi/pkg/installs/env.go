@@ -13,6 +13,7 @@ import (
 
 	"bi/pkg/cluster"
 	"bi/pkg/cluster/kind"
+	biviper "bi/pkg/viper"
 
 	"github.com/adrg/xdg"
 )
@@ -26,6 +27,11 @@ type InstallEnv struct {
 	Spec                *specs.InstallSpec
 	source              string
 	nvidiaAutoDiscovery bool
+	// components limits starting an aws install to these components
+	components []string
+	// wireGuardEndpointChecked is set once a reachable gateway has been
+	// found for the wireguard config
+	wireGuardEndpointChecked bool
 }
 
 func (env *InstallEnv) ClusterProvider() cluster.Provider {
@@ -37,6 +43,8 @@ type envBuilder struct {
 	additionalInsecureHosts []string
 	nvidiaAutoDiscovery     bool
 	allowTestKeys           bool
+	kubernetesVersion       string
+	components              []string
 }
 
 type envBuilderOption func(*envBuilder)
@@ -65,6 +73,20 @@ func WithAllowTestKeys(enabled bool) envBuilderOption {
 	}
 }
 
+// WithKubernetesVersion overrides the kubernetes version from the spec.
+func WithKubernetesVersion(version string) envBuilderOption {
+	return func(eb *envBuilder) {
+		eb.kubernetesVersion = version
+	}
+}
+
+// WithComponents limits starting an aws install to the named components.
+func WithComponents(components []string) envBuilderOption {
+	return func(eb *envBuilder) {
+		eb.components = components
+	}
+}
+
 func NewEnvBuilder(opts ...envBuilderOption) *envBuilder {
 	eb := &envBuilder{
 		additionalInsecureHosts: []string{},
@@ -119,17 +141,56 @@ func (eb *envBuilder) readInstallEnv() (*InstallEnv, error) {
 			continue
 		}
 		l.Debug("Found install")
+
+		if eb.kubernetesVersion != "" {
+			if err := overrideKubernetesVersion(spec, p.source, eb.kubernetesVersion); err != nil {
+				return nil, err
+			}
+		}
+
 		return &InstallEnv{
 			Slug:                spec.Slug,
 			Spec:                spec,
 			source:              p.source,
 			nvidiaAutoDiscovery: eb.nvidiaAutoDiscovery,
+			components:          eb.components,
 		}, nil
 	}
 
 	return nil, errors.New("no spec found")
 }
 
+// overrideKubernetesVersion applies a requested kubernetes version to the
+// spec. An existing install's cluster was created with the version in its
+// saved spec, and the override is never written back, so there it has to
+// match.
+func overrideKubernetesVersion(spec *specs.InstallSpec, source string, version string) error {
+	if source != "file" {
+		if spec.KubeCluster.Provider == "kind" {
+			if _, err := kind.NodeImage(version); err != nil {
+				return err
+			}
+		}
+		spec.SetKubernetesVersion(version)
+		return nil
+	}
+
+	current, err := spec.GetKubernetesVersion()
+	if err != nil {
+		return fmt.Errorf("error getting kubernetes version: %w", err)
+	}
+
+	if current == "" && spec.KubeCluster.Provider == "kind" {
+		current = kind.DefaultKubernetesVersion
+	}
+
+	if current == "" || specs.KubernetesMinorVersion(current) != specs.KubernetesMinorVersion(version) {
+		return fmt.Errorf("install %s was created with a different kubernetes version, the version can only be set for new installs", spec.Slug)
+	}
+
+	return nil
+}
+
 // NeedsKubeCleanup returns true if we should remove all resources in an install
 func (env *InstallEnv) NeedsKubeCleanup() bool {
 	// Returns true if the cluster provider is in [provided, aws]
@@ -185,9 +246,20 @@ func (env *InstallEnv) init(ctx context.Context) error {
 		podman, _ := kind.IsPodmanAvailable()
 
 		gatewayEnabled := needsLocalGateway && (dockerDesktop || podman)
-		env.clusterProvider = kind.NewClusterProvider(slog.Default(), env.Slug, gatewayEnabled, env.nvidiaAutoDiscovery)
+
+		kubernetesVersion, err := env.Spec.GetKubernetesVersion()
+		if err != nil {
+			return fmt.Errorf("error getting kubernetes version: %w", err)
+		}
+
+		env.clusterProvider = kind.NewClusterProvider(slog.Default(), env.Slug, kind.ClusterProviderOptions{
+			GatewayEnabled:      gatewayEnabled,
+			NvidiaAutoDiscovery: env.nvidiaAutoDiscovery,
+			KubernetesVersion:   kubernetesVersion,
+			WireGuardStatePath:  env.WireGuardGatewayStatePath(),
+		})
 	case "aws":
-		env.clusterProvider = cluster.NewPulumiProvider(env.Spec)
+		env.clusterProvider = cluster.NewPulumiProvider(env.Spec, biviper.EKSConfig(), cluster.PulumiBackendConfig(biviper.PulumiBackend()), env.WireGuardGatewayStatePath())
 	case "provided":
 	default:
 		return fmt.Errorf("unknown provider: %s", provider)
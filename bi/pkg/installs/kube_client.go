@@ -1,17 +1,37 @@
 package installs
 
 import (
-	"bi/pkg/kube"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+
+	"bi/pkg/kube"
 )
 
-func (env *InstallEnv) NewBatteryKubeClient() (kube.KubeClient, error) {
+func (env *InstallEnv) NewBatteryKubeClient(ctx context.Context) (kube.KubeClient, error) {
 	wireGuardConfigPath := env.WireGuardConfigPath()
 
 	// Check if there is a wireguard config present.
 	if _, err := os.Stat(wireGuardConfigPath); err != nil {
+		// EKS API servers are private so there's no way in without it.
+		if env.Spec.KubeCluster.Provider == "aws" {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("wireguard config %s not found, it's needed to reach the private cluster api, run bi start to recreate it", wireGuardConfigPath)
+			}
+			return nil, fmt.Errorf("error checking wireguard config: %w", err)
+		}
+
 		wireGuardConfigPath = ""
 	}
 
+	// Probing opens a tunnel per gateway, so only do it for the first client.
+	if wireGuardConfigPath != "" && !env.wireGuardEndpointChecked {
+		if err := env.ensureHealthyWireGuardEndpoint(ctx); err != nil {
+			return nil, err
+		}
+		env.wireGuardEndpointChecked = true
+	}
+
 	return kube.NewBatteryKubeClient(env.KubeConfigPath(), wireGuardConfigPath)
 }
@@ -0,0 +1,25 @@
+package installs
+
+import (
+	"context"
+	"testing"
+
+	"bi/pkg/specs"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBatteryKubeClientWithoutWireGuard(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	env := &InstallEnv{
+		Slug: "test",
+		Spec: &specs.InstallSpec{Slug: "test", KubeCluster: specs.KubeClusterSpec{Provider: "aws"}},
+	}
+
+	_, err := env.NewBatteryKubeClient(context.Background())
+	require.ErrorContains(t, err, "wireguard.yaml not found, it's needed to reach the private cluster api")
+}
@@ -19,6 +19,10 @@ func (env *InstallEnv) StartKubeProvider(ctx context.Context, progressReporter *
 
 	provider := env.Spec.KubeCluster.Provider
 
+	if len(env.components) > 0 && provider != "aws" {
+		return fmt.Errorf("components can only be selected for aws installs, not provider: %s", provider)
+	}
+
 	switch provider {
 	case "kind":
 		err = env.startLocal(ctx, progressReporter)
@@ -79,7 +83,16 @@ func (env *InstallEnv) startLocal(ctx context.Context, progressReporter *util.Pr
 func (env *InstallEnv) startAWS(ctx context.Context, progressReporter *util.ProgressReporter) error {
 	slog.Debug("Starting aws cluster")
 
-	if err := env.clusterProvider.Create(ctx, progressReporter); err != nil {
+	if len(env.components) > 0 {
+		stackProvider, err := env.stackClusterProvider()
+		if err != nil {
+			return err
+		}
+
+		if err := stackProvider.CreateComponents(ctx, env.components, progressReporter); err != nil {
+			return fmt.Errorf("error creating aws cluster components: %w", err)
+		}
+	} else if err := env.clusterProvider.Create(ctx, progressReporter); err != nil {
 		return fmt.Errorf("error creating aws cluster: %w", err)
 	}
 
@@ -40,10 +40,32 @@ func (env *InstallEnv) WireGuardConfigPath() string {
 	return filepath.Join(xdg.StateHome, "bi", "installs", env.Slug, "wireguard.yaml")
 }
 
+// WireGuardEndpointsPath lists every gateway endpoint for installs with more
+// than one gateway, so that clients can fail over between them.
+func (env *InstallEnv) WireGuardEndpointsPath() string {
+	return filepath.Join(xdg.StateHome, "bi", "installs", env.Slug, "wireguard-endpoints.json")
+}
+
+// WireGuardGatewayStatePath holds the gateway keys and the clients issued to
+// people, including their private keys.
+func (env *InstallEnv) WireGuardGatewayStatePath() string {
+	return filepath.Join(xdg.StateHome, "bi", "installs", env.Slug, "wireguard-gateway.json")
+}
+
 func (env *InstallEnv) InstallStateHome() string {
 	return filepath.Join(xdg.StateHome, "bi", "installs", env.Slug)
 }
 
+// SnapshotsHome is kept outside of the install state home so that snapshots
+// survive the install being removed.
+func (env *InstallEnv) SnapshotsHome() string {
+	return filepath.Join(xdg.StateHome, "bi", "snapshots", env.Slug)
+}
+
+func (env *InstallEnv) SnapshotPath(name string) string {
+	return filepath.Join(env.SnapshotsHome(), name+".tar.gz")
+}
+
 func baseInstallPath() string {
 	return filepath.Join(xdg.StateHome, "bi", "installs")
 }
@@ -0,0 +1,116 @@
+package installs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"bi/pkg/cluster/kind"
+
+	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
+	noisysocketsv1alpha3 "github.com/noisysockets/noisysockets/config/v1alpha3"
+)
+
+func (env *InstallEnv) PauseKubeProvider(ctx context.Context) error {
+	slog.Debug("Pausing provider")
+
+	kindProvider, err := env.kindClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	return kindProvider.Pause(ctx)
+}
+
+func (env *InstallEnv) ResumeKubeProvider(ctx context.Context) error {
+	slog.Debug("Resuming provider")
+
+	kindProvider, err := env.kindClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := kindProvider.Resume(ctx); err != nil {
+		return err
+	}
+
+	// The gateway binds to a random host port, so it may have moved.
+	if _, err := os.Stat(env.WireGuardConfigPath()); err == nil {
+		endpoint, err := kindProvider.WireGuardGatewayEndpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting wireguard gateway endpoint: %w", err)
+		}
+
+		if err := env.updateWireGuardEndpoint(endpoint); err != nil {
+			return fmt.Errorf("error refreshing wireguard config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (env *InstallEnv) kindClusterProvider() (*kind.KindClusterProvider, error) {
+	provider := env.Spec.KubeCluster.Provider
+	if provider != "kind" {
+		return nil, fmt.Errorf("only supported for kind installs, not provider: %s", provider)
+	}
+
+	kindProvider, ok := env.clusterProvider.(*kind.KindClusterProvider)
+	if !ok {
+		return nil, errors.New("install has not been initialized with a kind cluster provider")
+	}
+
+	return kindProvider, nil
+}
+
+// updateWireGuardEndpoint rewrites the gateway peer endpoint in the stored
+// wireguard config (if it has changed). The keys are left untouched.
+func (env *InstallEnv) updateWireGuardEndpoint(endpoint string) error {
+	wireGuardConfigPath := env.WireGuardConfigPath()
+
+	wireGuardConfigBytes, err := os.ReadFile(wireGuardConfigPath)
+	if err != nil {
+		return fmt.Errorf("error reading wireguard config: %w", err)
+	}
+
+	versionedConf, err := noisysocketsconfig.FromYAML(bytes.NewReader(wireGuardConfigBytes))
+	if err != nil {
+		return fmt.Errorf("error parsing wireguard config: %w", err)
+	}
+
+	conf, ok := versionedConf.(*noisysocketsv1alpha3.Config)
+	if !ok {
+		return fmt.Errorf("unexpected wireguard config type: %T", versionedConf)
+	}
+
+	var changed bool
+	for i, peer := range conf.Peers {
+		if peer.Name == "gateway" && peer.Endpoint != endpoint {
+			slog.Info("Wireguard gateway endpoint changed",
+				slog.String("old", peer.Endpoint),
+				slog.String("new", endpoint))
+
+			conf.Peers[i].Endpoint = endpoint
+			changed = true
+		}
+	}
+
+	if !changed {
+		slog.Debug("Wireguard gateway endpoint unchanged", slog.String("endpoint", endpoint))
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := noisysocketsconfig.ToYAML(&buf, conf); err != nil {
+		return fmt.Errorf("error marshalling wireguard config: %w", err)
+	}
+
+	if err := os.WriteFile(wireGuardConfigPath, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("error writing wireguard config: %w", err)
+	}
+
+	return nil
+}
@@ -34,7 +34,7 @@ func (env *InstallEnv) NewRage(ctx context.Context) (*rage.RageReport, error) {
 	}
 
 	// Get info about all the pods
-	kubeClient, err := env.NewBatteryKubeClient()
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
 	if err != nil {
 		slog.Error("unable to create kube client", "error", err)
 		return nil, err
@@ -0,0 +1,177 @@
+package installs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"bi/pkg/cluster/kind"
+	"bi/pkg/cluster/util"
+)
+
+var snapshotNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+type SnapshotInfo struct {
+	Name      string
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// SaveSnapshot writes a snapshot of the (paused) cluster to the snapshots directory.
+func (env *InstallEnv) SaveSnapshot(ctx context.Context, name string) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	kindProvider, err := env.kindClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(env.SnapshotsHome(), 0o700); err != nil {
+		return fmt.Errorf("error creating snapshot directory: %w", err)
+	}
+
+	snapshotPath := env.SnapshotPath(name)
+
+	slog.Debug("Saving snapshot", slog.String("path", snapshotPath))
+
+	// Write to a temporary file first so a failed save doesn't clobber an existing snapshot.
+	tmpPath := snapshotPath + ".tmp"
+	snapshotFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening snapshot file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+	defer snapshotFile.Close()
+
+	if err := kindProvider.SaveSnapshot(ctx, snapshotFile); err != nil {
+		return fmt.Errorf("error saving snapshot: %w", err)
+	}
+
+	if err := snapshotFile.Close(); err != nil {
+		return fmt.Errorf("error closing snapshot file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, snapshotPath); err != nil {
+		return fmt.Errorf("error moving snapshot into place: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreSnapshot recreates the cluster and then replaces its state with the
+// contents of the named snapshot.
+func (env *InstallEnv) RestoreSnapshot(ctx context.Context, name string, progressReporter *util.ProgressReporter) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	kindProvider, err := env.kindClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	snapshotPath := env.SnapshotPath(name)
+
+	snapshotFile, err := os.Open(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("error opening snapshot %s: %w", name, err)
+	}
+	defer snapshotFile.Close()
+
+	// Check the whole snapshot first, once the cluster is removed there's
+	// nothing to go back to.
+	if _, err := kindProvider.ValidateSnapshot(snapshotFile); err != nil {
+		return fmt.Errorf("snapshot %s can't be restored: %w", name, err)
+	}
+
+	if _, err := snapshotFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error rewinding snapshot %s: %w", name, err)
+	}
+
+	slog.Info("Recreating cluster from snapshot", slog.String("path", snapshotPath))
+
+	if err := env.StopKubeProvider(ctx, progressReporter); err != nil {
+		return fmt.Errorf("error removing existing cluster: %w", err)
+	}
+
+	if err := env.StartKubeProvider(ctx, progressReporter); err != nil {
+		return fmt.Errorf("error creating cluster: %w", err)
+	}
+
+	if err := kindProvider.RestoreSnapshot(ctx, snapshotFile); err != nil {
+		return fmt.Errorf("error restoring snapshot: %w", err)
+	}
+
+	// The cluster CA comes from the snapshot so the kubeconfig needs rewriting.
+	if err := env.WriteKubeConfig(ctx, true); err != nil {
+		return fmt.Errorf("error writing kubeconfig after restore: %w", err)
+	}
+
+	return nil
+}
+
+// ListSnapshots returns all the saved snapshots for the install.
+func (env *InstallEnv) ListSnapshots() ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(env.SnapshotsHome())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error reading snapshot directory: %w", err)
+	}
+
+	var snapshots []SnapshotInfo
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".tar.gz")
+		if entry.IsDir() || !ok {
+			continue
+		}
+
+		snapshotPath := filepath.Join(env.SnapshotsHome(), entry.Name())
+		info := SnapshotInfo{Name: name, Path: snapshotPath}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error reading snapshot %s: %w", name, err)
+		}
+		info.Size = fileInfo.Size()
+		info.CreatedAt = fileInfo.ModTime()
+
+		if manifest, err := readSnapshotManifest(snapshotPath); err != nil {
+			slog.Warn("Unable to read snapshot manifest", slog.String("path", snapshotPath), slog.Any("error", err))
+		} else {
+			info.CreatedAt = manifest.CreatedAt
+		}
+
+		snapshots = append(snapshots, info)
+	}
+
+	return snapshots, nil
+}
+
+func readSnapshotManifest(snapshotPath string) (*kind.SnapshotManifest, error) {
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return kind.ReadSnapshotManifest(f)
+}
+
+func validateSnapshotName(name string) error {
+	if !snapshotNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name: %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package installs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"bi/pkg/cluster"
+	"bi/pkg/wireguard"
+)
+
+// installerClientName is the client that bi itself uses to reach the
+// cluster, its config is the install's wireguard config.
+const installerClientName = "installer"
+
+// ListWireGuardClients returns the clients of the install's gateway.
+func (env *InstallEnv) ListWireGuardClients(ctx context.Context) ([]wireguard.Client, error) {
+	clientProvider, err := env.wireGuardClientProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return clientProvider.WireGuardClients(ctx)
+}
+
+// AddWireGuardClient issues a client config for a person and writes it to w.
+func (env *InstallEnv) AddWireGuardClient(ctx context.Context, name string, w io.Writer) error {
+	clientProvider, err := env.wireGuardClientProvider()
+	if err != nil {
+		return err
+	}
+
+	return clientProvider.AddWireGuardClient(ctx, name, w)
+}
+
+// RevokeWireGuardClient stops a client from connecting to the gateway.
+func (env *InstallEnv) RevokeWireGuardClient(ctx context.Context, name string) error {
+	clientProvider, err := env.wireGuardClientProvider()
+	if err != nil {
+		return err
+	}
+
+	return clientProvider.RevokeWireGuardClient(ctx, name)
+}
+
+// RotateWireGuardGateway replaces the gateway's key and rewrites the
+// installer's config. The other clients need new configs afterwards.
+func (env *InstallEnv) RotateWireGuardGateway(ctx context.Context) error {
+	clientProvider, err := env.wireGuardClientProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := clientProvider.RotateWireGuardGateway(ctx); err != nil {
+		return err
+	}
+
+	return env.WriteWireGuardConfig(ctx, true)
+}
+
+// RotateWireGuardClient replaces a client's key and writes its new config to
+// w. Rotating the installer's key also rewrites the install's config.
+func (env *InstallEnv) RotateWireGuardClient(ctx context.Context, name string, w io.Writer) error {
+	clientProvider, err := env.wireGuardClientProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := clientProvider.RotateWireGuardClient(ctx, name, w); err != nil {
+		return err
+	}
+
+	if name != installerClientName {
+		return nil
+	}
+
+	return env.WriteWireGuardConfig(ctx, true)
+}
+
+func (env *InstallEnv) wireGuardClientProvider() (cluster.WireGuardClientProvider, error) {
+	clientProvider, ok := env.clusterProvider.(cluster.WireGuardClientProvider)
+	if !ok {
+		return nil, fmt.Errorf("wireguard clients aren't supported for provider: %s", env.Spec.KubeCluster.Provider)
+	}
+
+	return clientProvider, nil
+}
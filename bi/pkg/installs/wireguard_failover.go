@@ -0,0 +1,158 @@
+package installs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/noisysockets/noisysockets"
+	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
+	noisysocketsv1alpha3 "github.com/noisysockets/noisysockets/config/v1alpha3"
+)
+
+// wireGuardProbeTimeout is how long a gateway has to respond before trying
+// the next one.
+const wireGuardProbeTimeout = 5 * time.Second
+
+// writeWireGuardEndpoints saves the endpoints of all the gateway instances.
+// With a single gateway there is nothing to fail over to so any old list is
+// removed.
+func (env *InstallEnv) writeWireGuardEndpoints(ctx context.Context) error {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := stackProvider.WireGuardGatewayEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	path := env.WireGuardEndpointsPath()
+	if len(endpoints) < 2 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	bs, err := json.Marshal(endpoints)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, bs, 0o600)
+}
+
+// ensureHealthyWireGuardEndpoint checks the gateway in the wireguard config
+// can be reached and, if not, switches the config to the first of the other
+// gateways that can. The gateways share keys so only the endpoint changes.
+func (env *InstallEnv) ensureHealthyWireGuardEndpoint(ctx context.Context) error {
+	bs, err := os.ReadFile(env.WireGuardEndpointsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error reading wireguard endpoints: %w", err)
+	}
+
+	var endpoints []string
+	if err := json.Unmarshal(bs, &endpoints); err != nil {
+		return fmt.Errorf("error parsing wireguard endpoints: %w", err)
+	}
+
+	wireGuardConfigBytes, err := os.ReadFile(env.WireGuardConfigPath())
+	if err != nil {
+		return fmt.Errorf("error reading wireguard config: %w", err)
+	}
+
+	versionedConf, err := noisysocketsconfig.FromYAML(bytes.NewReader(wireGuardConfigBytes))
+	if err != nil {
+		return fmt.Errorf("error parsing wireguard config: %w", err)
+	}
+
+	conf, ok := versionedConf.(*noisysocketsv1alpha3.Config)
+	if !ok {
+		return fmt.Errorf("unexpected wireguard config type: %T", versionedConf)
+	}
+
+	i := slices.IndexFunc(conf.Peers, func(p noisysocketsv1alpha3.PeerConfig) bool { return p.Name == "gateway" })
+	if i == -1 {
+		return errors.New("wireguard config has no gateway peer")
+	}
+	current := conf.Peers[i].Endpoint
+
+	endpoint, err := pickWireGuardEndpoint(current, endpoints, func(endpoint string) error {
+		return probeWireGuardEndpoint(ctx, conf, i, endpoint)
+	})
+	if err != nil {
+		return err
+	}
+
+	if endpoint == current {
+		return nil
+	}
+	return env.updateWireGuardEndpoint(endpoint)
+}
+
+// pickWireGuardEndpoint returns the first endpoint that probe succeeds for,
+// trying the current gateway first and then the rest in order.
+func pickWireGuardEndpoint(current string, endpoints []string, probe func(endpoint string) error) (string, error) {
+	candidates := []string{current}
+	for _, endpoint := range endpoints {
+		if !slices.Contains(candidates, endpoint) {
+			candidates = append(candidates, endpoint)
+		}
+	}
+
+	var errs []error
+	for _, endpoint := range candidates {
+		err := probe(endpoint)
+		if err == nil {
+			return endpoint, nil
+		}
+
+		slog.Warn("Wireguard gateway unreachable", slog.String("endpoint", endpoint), slog.Any("error", err))
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+
+	return "", fmt.Errorf("no wireguard gateway reachable: %w", errors.Join(errs...))
+}
+
+// probeWireGuardEndpoint connects to the VPC resolver through the gateway at
+// endpoint, which only works if the tunnel and the gateway's forwarding are
+// up.
+func probeWireGuardEndpoint(ctx context.Context, conf *noisysocketsv1alpha3.Config, gatewayPeer int, endpoint string) error {
+	if conf.DNS == nil || len(conf.DNS.Servers) == 0 {
+		return errors.New("wireguard config has no nameservers to probe")
+	}
+
+	probeConf := *conf
+	probeConf.Peers = slices.Clone(conf.Peers)
+	probeConf.Peers[gatewayPeer].Endpoint = endpoint
+
+	network, err := noisysockets.OpenNetwork(slog.Default(), &probeConf)
+	if err != nil {
+		return fmt.Errorf("error opening wireguard network: %w", err)
+	}
+	defer network.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, wireGuardProbeTimeout)
+	defer cancel()
+
+	server := netip.AddrPort(conf.DNS.Servers[0]).Addr()
+	conn, err := network.DialContext(ctx, "tcp", net.JoinHostPort(server.String(), "53"))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
@@ -0,0 +1,78 @@
+package installs
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pickWireGuardEndpoint(t *testing.T) {
+	endpoints := []string{"203.0.113.1:51820", "203.0.113.2:51820", "203.0.113.3:51820"}
+
+	tests := []struct {
+		name       string
+		current    string
+		down       []string
+		want       string
+		wantProbed []string
+		wantErr    string
+	}{
+		{
+			name:       "current is healthy",
+			current:    "203.0.113.2:51820",
+			want:       "203.0.113.2:51820",
+			wantProbed: []string{"203.0.113.2:51820"},
+		},
+		{
+			name:       "fails over in order",
+			current:    "203.0.113.1:51820",
+			down:       []string{"203.0.113.1:51820", "203.0.113.2:51820"},
+			want:       "203.0.113.3:51820",
+			wantProbed: endpoints,
+		},
+		{
+			name:       "current is tried first",
+			current:    "203.0.113.3:51820",
+			down:       []string{"203.0.113.3:51820"},
+			want:       "203.0.113.1:51820",
+			wantProbed: []string{"203.0.113.3:51820", "203.0.113.1:51820"},
+		},
+		{
+			name:       "current is no longer a gateway",
+			current:    "198.51.100.1:51820",
+			down:       []string{"198.51.100.1:51820"},
+			want:       "203.0.113.1:51820",
+			wantProbed: []string{"198.51.100.1:51820", "203.0.113.1:51820"},
+		},
+		{
+			name:       "all down",
+			current:    "203.0.113.1:51820",
+			down:       endpoints,
+			wantProbed: endpoints,
+			wantErr:    "no wireguard gateway reachable: 203.0.113.1:51820: timeout\n203.0.113.2:51820: timeout\n203.0.113.3:51820: timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probed []string
+			got, err := pickWireGuardEndpoint(tt.current, endpoints, func(endpoint string) error {
+				probed = append(probed, endpoint)
+				if slices.Contains(tt.down, endpoint) {
+					return errors.New("timeout")
+				}
+				return nil
+			})
+
+			require.Equal(t, tt.wantProbed, probed)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
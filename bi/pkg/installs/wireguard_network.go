@@ -0,0 +1,38 @@
+package installs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/noisysockets/network"
+	"github.com/noisysockets/noisysockets"
+	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
+)
+
+// OpenWireGuardNetwork connects to the install's gateway in userspace, the
+// same way bi reaches the cluster api. The caller must close the network.
+func (env *InstallEnv) OpenWireGuardNetwork(ctx context.Context) (network.Network, error) {
+	if err := env.ensureHealthyWireGuardEndpoint(ctx); err != nil {
+		return nil, err
+	}
+
+	wireGuardConfigFile, err := os.Open(env.WireGuardConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("error opening wireguard config: %w", err)
+	}
+	defer wireGuardConfigFile.Close()
+
+	wireGuardConfig, err := noisysocketsconfig.FromYAML(wireGuardConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading wireguard config: %w", err)
+	}
+
+	net, err := noisysockets.OpenNetwork(slog.Default(), wireGuardConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error opening wireguard network: %w", err)
+	}
+
+	return net, nil
+}
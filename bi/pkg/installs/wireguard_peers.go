@@ -0,0 +1,39 @@
+package installs
+
+import (
+	"context"
+	"io"
+
+	"bi/pkg/cluster/eks"
+)
+
+// ListWireGuardPeers returns the peers added to the install's gateway.
+func (env *InstallEnv) ListWireGuardPeers(ctx context.Context) ([]eks.Peer, error) {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return stackProvider.WireGuardPeers(ctx)
+}
+
+// AddWireGuardPeer adds a peer to the install's gateway without replacing it
+// and writes the peer's config to w.
+func (env *InstallEnv) AddWireGuardPeer(ctx context.Context, name string, w io.Writer) error {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	return stackProvider.AddWireGuardPeer(ctx, name, w)
+}
+
+// RemoveWireGuardPeer removes a peer from the install's gateway.
+func (env *InstallEnv) RemoveWireGuardPeer(ctx context.Context, name string) error {
+	stackProvider, err := env.stackClusterProvider()
+	if err != nil {
+		return err
+	}
+
+	return stackProvider.RemoveWireGuardPeer(ctx, name)
+}
@@ -155,6 +155,13 @@ func (env *InstallEnv) WriteWireGuardConfig(ctx context.Context, force bool) err
 		slog.Debug("No wireguard config to write")
 		_ = wireGuardConfigFile.Close()
 		_ = os.Remove(wireGuardConfigPath)
+		return nil
+	}
+
+	if provider == "aws" {
+		if err := env.writeWireGuardEndpoints(ctx); err != nil {
+			return fmt.Errorf("error writing wireguard endpoints: %w", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,170 @@
+package kube
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// NewAPIProxyToken returns a random bearer token for an API proxy session.
+func NewAPIProxyToken() string {
+	return rand.Text()
+}
+
+// NewAPIProxyCert returns a self-signed certificate for serving an API proxy
+// session on the hosts, and the PEM encoded certificate for clients to
+// trust. Kubernetes clients only send bearer tokens over TLS.
+func NewAPIProxyCert(hosts []string) (tls.Certificate, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("error generating api proxy key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("error generating api proxy certificate serial: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "bi api proxy"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("error creating api proxy certificate: %w", err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// APIProxyHandler returns a handler that forwards requests to the API server
+// with the client's credentials and dialer, so tools that don't know about
+// the WireGuard network can reach a cluster that is only on it. Requests must
+// have the token as their bearer token, so that other local users and
+// processes can't use the install's credentials.
+func (c *batteryKubeClient) APIProxyHandler(token string) (http.Handler, error) {
+	if token == "" {
+		return nil, errors.New("api proxy token is empty")
+	}
+
+	cfg := rest.CopyConfig(c.cfg)
+	// exec, attach and port-forward upgrade the connection which can't be
+	// done over HTTP/2.
+	cfg.NextProtos = []string{"http/1.1"}
+
+	transport, err := rest.TransportFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating kubernetes transport: %w", err)
+	}
+
+	target, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing api server url: %w", err)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			// The transport adds the kubeconfig's credentials.
+			r.Out.Header.Del("Authorization")
+		},
+		Transport: transport,
+		// Stream watches and logs as they arrive.
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.Debug("Error proxying to api server", slog.String("path", r.URL.Path), slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+
+	return requireToken(token, proxy), nil
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LocalhostOnly only accepts requests for localhost, like kubectl proxy, so
+// that other sites can't reach the handler through the browser.
+func LocalhostOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		switch host {
+		case "localhost", "127.0.0.1", "::1":
+			next.ServeHTTP(w, r)
+		default:
+			http.Error(w, "forbidden host", http.StatusForbidden)
+		}
+	})
+}
+
+// WriteProxyKubeConfig writes a kubeconfig for the API proxy served at
+// server with the certificate caData. It only has the proxy's token as the
+// proxy adds the install's credentials.
+func WriteProxyKubeConfig(w io.Writer, name, server string, caData []byte, token string) error {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: caData}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	config.CurrentContext = name
+
+	bs, err := clientcmd.Write(*config)
+	if err != nil {
+		return fmt.Errorf("error marshalling kubeconfig: %w", err)
+	}
+
+	if _, err := w.Write(bs); err != nil {
+		return fmt.Errorf("error writing kubeconfig: %w", err)
+	}
+
+	return nil
+}
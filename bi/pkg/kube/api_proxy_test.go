@@ -0,0 +1,109 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestAPIProxyHandler(t *testing.T) {
+	apiServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path+" "+r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(apiServer.Close)
+
+	c := &batteryKubeClient{cfg: &rest.Config{
+		Host:            apiServer.URL,
+		BearerToken:     "secret",
+		TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+	}}
+
+	_, err := c.APIProxyHandler("")
+	require.Error(t, err)
+
+	token := NewAPIProxyToken()
+	require.NotEqual(t, token, NewAPIProxyToken())
+
+	handler, err := c.APIProxyHandler(token)
+	require.NoError(t, err)
+
+	proxy := httptest.NewServer(LocalhostOnly(handler))
+	t.Cleanup(proxy.Close)
+
+	tests := []struct {
+		name     string
+		host     string
+		auth     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "replaces token with credentials", auth: "Bearer " + token, wantCode: http.StatusOK, wantBody: "/api/v1/pods Bearer secret"},
+		{name: "localhost", host: "localhost:8001", auth: "Bearer " + token, wantCode: http.StatusOK, wantBody: "/api/v1/pods Bearer secret"},
+		{name: "no token", wantCode: http.StatusUnauthorized},
+		{name: "wrong token", auth: "Bearer other", wantCode: http.StatusUnauthorized},
+		{name: "other hosts are forbidden", host: "evil.example.com", auth: "Bearer " + token, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, proxy.URL+"/api/v1/pods", nil)
+			require.NoError(t, err)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+			if tt.wantBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantBody, string(body))
+			}
+		})
+	}
+}
+
+func TestWriteProxyKubeConfig(t *testing.T) {
+	cert, caData, err := NewAPIProxyCert([]string{"localhost", "127.0.0.1", "::1"})
+	require.NoError(t, err)
+
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	proxy.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	proxy.StartTLS()
+	t.Cleanup(proxy.Close)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteProxyKubeConfig(&buf, "bi-example", proxy.URL, caData, "token"))
+
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, proxy.URL, cfg.Host)
+
+	// Kubernetes clients only send the token over TLS, and trust the
+	// proxy's certificate.
+	client, err := rest.HTTPClientFor(cfg)
+	require.NoError(t, err)
+
+	resp, err := client.Get(proxy.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer token", string(body))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
+	kerrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -23,6 +24,41 @@ func (batteryKube *batteryKubeClient) EnsureResourceExists(ctx context.Context,
 	return nil
 }
 
+// UpdateResource creates the resource, or replaces it if it already exists.
+func (batteryKube *batteryKubeClient) UpdateResource(ctx context.Context, resource map[string]interface{}) error {
+	unstructuredResource := &unstructured.Unstructured{Object: resource}
+
+	ns := unstructuredResource.GetNamespace()
+	name := unstructuredResource.GetName()
+	gvr, err := batteryKube.getGroupVersionResource(unstructuredResource)
+	if err != nil {
+		return fmt.Errorf("failed to get gvr: %w", err)
+	}
+
+	client := batteryKube.dynamicClient.Resource(gvr)
+	getter, updater := client.Get, client.Update
+	if ns != "" {
+		getter, updater = client.Namespace(ns).Get, client.Namespace(ns).Update
+	}
+
+	existing, err := getter(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if kerrs.IsNotFound(err) {
+			return batteryKube.create(ctx, unstructuredResource)
+		}
+		return fmt.Errorf("failed to get resource: %w", err)
+	}
+
+	unstructuredResource.SetResourceVersion(existing.GetResourceVersion())
+
+	if _, err := updater(ctx, unstructuredResource, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update resource: %w", err)
+	}
+
+	slog.Debug("Resource updated", "name", name, "namespace", ns, "kind", unstructuredResource.GetKind())
+	return nil
+}
+
 func (batteryKube *batteryKubeClient) exists(ctx context.Context, unstructuredResource *unstructured.Unstructured) error {
 	ns := unstructuredResource.GetNamespace()
 	name := unstructuredResource.GetName()
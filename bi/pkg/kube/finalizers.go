@@ -0,0 +1,180 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	kerrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+)
+
+// stripFinalizersPatch removes every finalizer from a resource.
+var stripFinalizersPatch = []byte(`{"metadata":{"finalizers":null}}`)
+
+// stuckResource is a resource whose deletion is waiting on finalizers.
+type stuckResource struct {
+	ref        resourceRef
+	finalizers []string
+	// controllers are the field managers that set the finalizers.
+	controllers []string
+}
+
+func newStuckResource(ref resourceRef, obj *unstructured.Unstructured) stuckResource {
+	return stuckResource{ref: ref, finalizers: obj.GetFinalizers(), controllers: finalizerControllers(obj)}
+}
+
+// handleStuck reports the finalizers holding up the deletion of ref and the
+// controllers that left them, and strips them with Force. A namespace is
+// held up by the finalizers of the resources still in it.
+func (r *remover) handleStuck(ctx context.Context, ref resourceRef, obj *unstructured.Unstructured) error {
+	stuck := []stuckResource{newStuckResource(ref, obj)}
+
+	if ref.gvr.Group == "" && ref.gvr.Resource == "namespaces" {
+		contents, err := r.kubeClient.finalizedResources(ctx, ref.name)
+		if err != nil {
+			return fmt.Errorf("failed to list resources in namespace %s: %w", ref.name, err)
+		}
+		stuck = append(stuck, contents...)
+	}
+
+	for _, s := range stuck {
+		if len(s.finalizers) == 0 {
+			continue
+		}
+
+		l := slog.With(
+			slog.String("resource", s.ref.String()),
+			slog.Any("finalizers", s.finalizers),
+			slog.Any("controllers", s.controllers))
+
+		if !r.opts.Force {
+			l.Warn("Deletion is waiting on finalizers, force removal would strip them")
+			continue
+		}
+
+		l.Warn("Stripping finalizers")
+		if err := r.kubeClient.stripFinalizers(ctx, s.ref); err != nil {
+			return fmt.Errorf("failed to strip finalizers of %s: %w", s.ref, err)
+		}
+
+		r.mu.Lock()
+		r.finalized[s.ref.String()] = s.finalizers
+		r.mu.Unlock()
+	}
+
+	return nil
+}
+
+// reportFinalized logs, by finalizer, the resources that were left behind
+// by their controllers and had their finalizers stripped.
+func (r *remover) reportFinalized() {
+	byFinalizer := make(map[string][]string)
+	for resource, finalizers := range r.finalized {
+		for _, f := range finalizers {
+			byFinalizer[f] = append(byFinalizer[f], resource)
+		}
+	}
+
+	for _, f := range slices.Sorted(maps.Keys(byFinalizer)) {
+		resources := byFinalizer[f]
+		slices.Sort(resources)
+
+		slog.Warn("Stripped finalizers that were never cleared",
+			slog.String("finalizer", f),
+			slog.String("resources", strings.Join(resources, ", ")))
+	}
+}
+
+// finalizerControllers returns the field managers that own any of the
+// resource's finalizers, the controllers that are expected to clear them.
+func finalizerControllers(obj *unstructured.Unstructured) []string {
+	var controllers []string
+	for _, entry := range obj.GetManagedFields() {
+		if entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields struct {
+			Metadata struct {
+				Finalizers json.RawMessage `json:"f:finalizers"`
+			} `json:"f:metadata"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+
+		if fields.Metadata.Finalizers != nil && !slices.Contains(controllers, entry.Manager) {
+			controllers = append(controllers, entry.Manager)
+		}
+	}
+
+	return controllers
+}
+
+func (kubeClient *batteryKubeClient) stripFinalizers(ctx context.Context, ref resourceRef) error {
+	patcher := kubeClient.dynamicClient.Resource(ref.gvr).Patch
+	if ref.namespace != "" {
+		patcher = kubeClient.dynamicClient.Resource(ref.gvr).Namespace(ref.namespace).Patch
+	}
+
+	_, err := patcher(ctx, ref.name, types.MergePatchType, stripFinalizersPatch, metav1.PatchOptions{})
+	if err != nil && !kerrs.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// finalizedResources returns the resources in the namespace that have
+// finalizers.
+func (kubeClient *batteryKubeClient) finalizedResources(ctx context.Context, namespace string) ([]stuckResource, error) {
+	apiResourceLists, err := kubeClient.discoveryClient.ServerPreferredNamespacedResources()
+	// Some aggregated apis may be unavailable while things are torn down,
+	// what could be discovered is still worth checking.
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to get API resources: %w", err)
+	}
+
+	var gvrs []schema.GroupVersionResource
+	for _, apiResourceList := range apiResourceLists {
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, apiResource := range apiResourceList.APIResources {
+			if slices.Contains(apiResource.Verbs, "list") && slices.Contains(apiResource.Verbs, "patch") {
+				gvrs = append(gvrs, gv.WithResource(apiResource.Name))
+			}
+		}
+	}
+
+	var stuck []stuckResource
+	for _, gvr := range gvrs {
+		list, err := kubeClient.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if kerrs.IsNotFound(err) || kerrs.IsMethodNotSupported(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			if len(item.GetFinalizers()) > 0 {
+				ref := resourceRef{gvr: gvr, namespace: namespace, name: item.GetName()}
+				stuck = append(stuck, newStuckResource(ref, &item))
+			}
+		}
+	}
+
+	return stuck, nil
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"time"
 
@@ -25,21 +26,16 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/tools/portforward"
 )
 
 type KubeClient interface {
 	io.Closer
 	EnsureResourceExists(ctx context.Context, resource map[string]interface{}) error
-	PortForwardService(
-		ctx context.Context,
-		namespace string,
-		name string,
-		port int,
-		localPort int,
-		stopChannel <-chan struct{},
-		readyChannel chan struct{}) (*portforward.PortForwarder, error)
-	RemoveAll(ctx context.Context) error
+	UpdateResource(ctx context.Context, resource map[string]interface{}) error
+	ForwardService(ctx context.Context, opts ServicePortForward) error
+	RemoveAll(ctx context.Context, opts RemoveAllOptions) error
+	RemoveBattery(ctx context.Context, batteryID string, opts RemoveAllOptions) error
+	ControlServerDeployed(ctx context.Context, namespace string) (bool, error)
 	WaitForConnection(time.Duration) error
 	WatchFor(context.Context, *WatchOptions) error
 	GetAccessInfo(ctx context.Context, namespace string) (*access.AccessSpec, error)
@@ -48,14 +44,16 @@ type KubeClient interface {
 	ListHttpRoutesRage(ctx context.Context) ([]rage.HttpRouteRageInfo, error)
 	ListNodesRage(ctx context.Context) ([]rage.NodeRageInfo, error)
 	ListServicesRage(ctx context.Context) ([]rage.ServiceRageInfo, error)
+	ListNodeVersions(ctx context.Context, labelSelector string) (map[string]string, error)
 	GetDialContext() func(ctx context.Context, network, address string) (net.Conn, error)
+	APIProxyHandler(token string) (http.Handler, error)
 }
 
 type batteryKubeClient struct {
 	cfg                 *rest.Config
-	client              *kubernetes.Clientset
-	dynamicClient       *dynamic.DynamicClient
-	apiExtensionsClient *apiextensionsclientset.Clientset
+	client              kubernetes.Interface
+	dynamicClient       dynamic.Interface
+	apiExtensionsClient apiextensionsclientset.Interface
 	discoveryClient     discovery.CachedDiscoveryInterface
 	mapper              *restmapper.DeferredDiscoveryRESTMapper
 	net                 network.Network
@@ -0,0 +1,24 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListNodeVersions returns the kubelet version of each node matching the
+// label selector, keyed by node name.
+func (kubeClient *batteryKubeClient) ListNodeVersions(ctx context.Context, labelSelector string) (map[string]string, error) {
+	nodes, err := kubeClient.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	versions := make(map[string]string, len(nodes.Items))
+	for _, n := range nodes.Items {
+		versions[n.Name] = n.Status.NodeInfo.KubeletVersion
+	}
+
+	return versions, nil
+}
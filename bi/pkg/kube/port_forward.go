@@ -2,43 +2,353 @@ package kube
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	httpstreamspdy "k8s.io/apimachinery/pkg/util/httpstream/spdy"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 )
 
-func (kubeClient *batteryKubeClient) PortForwardService(
+const (
+	// endpointCheckInterval is how often the forwarded pod is checked to
+	// still be a ready endpoint of the service.
+	endpointCheckInterval = 5 * time.Second
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// PortMapping forwards a local port to a port of a service. Remote is the
+// service port's number or name.
+type PortMapping struct {
+	Local  int
+	Remote string
+}
+
+func (m PortMapping) String() string {
+	return fmt.Sprintf("%d:%s", m.Local, m.Remote)
+}
+
+// ParsePortMapping parses a mapping like kubectl port-forward does: "8080:80",
+// "8080:http", "80" (same local port) or ":80" (any free local port).
+func ParsePortMapping(s string) (PortMapping, error) {
+	local, remote, found := strings.Cut(s, ":")
+	if !found {
+		remote = local
+		if _, err := strconv.Atoi(remote); err != nil {
+			return PortMapping{}, fmt.Errorf("port mapping %q needs a local port for a named port", s)
+		}
+	}
+
+	if remote == "" {
+		return PortMapping{}, fmt.Errorf("port mapping %q has no remote port", s)
+	}
+
+	m := PortMapping{Remote: remote}
+	if local != "" {
+		port, err := strconv.Atoi(local)
+		if err != nil || port < 0 || port > 65535 {
+			return PortMapping{}, fmt.Errorf("port mapping %q has an invalid local port", s)
+		}
+		m.Local = port
+	}
+
+	return m, nil
+}
+
+// ServicePortForward is a port forward to whichever pod backs a service.
+type ServicePortForward struct {
+	Namespace string
+	Service   string
+	Ports     []PortMapping
+	// Addresses are the local addresses to listen on, localhost by default.
+	Addresses []string
+	// Ready is called each time the ports are forwarded to a (new) pod.
+	Ready func(pod string, ports []portforward.ForwardedPort)
+}
+
+// ForwardService forwards the ports to a ready pod of the service until ctx
+// is done. When the pod goes away or stops being ready the ports are
+// forwarded to another ready pod, keeping the same local ports.
+func (kubeClient *batteryKubeClient) ForwardService(ctx context.Context, opts ServicePortForward) error {
+	service, err := kubeClient.client.CoreV1().Services(opts.Namespace).Get(ctx, opts.Service, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to get service %s/%s: %w", opts.Namespace, opts.Service, err)
+	}
+
+	servicePorts, err := resolveServicePorts(service, opts.Ports)
+	if err != nil {
+		return err
+	}
+
+	addresses := opts.Addresses
+	if len(addresses) == 0 {
+		addresses = []string{"localhost"}
+	}
+
+	localPorts := make([]int, len(opts.Ports))
+	for i, m := range opts.Ports {
+		localPorts[i] = m.Local
+	}
+
+	delay := minReconnectDelay
+	connected := false
+	for {
+		pod, remotePorts, err := kubeClient.readyEndpoint(ctx, opts.Namespace, opts.Service, servicePorts)
+		if err == nil {
+			var ready bool
+			ready, err = kubeClient.forwardToPod(ctx, opts, addresses, pod, localPorts, remotePorts)
+			if ready {
+				connected = true
+				delay = minReconnectDelay
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Fail fast rather than retrying a service that never worked.
+		if !connected {
+			return err
+		}
+
+		slog.Warn("Port forward lost, reconnecting",
+			slog.String("service", opts.Service),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// forwardToPod forwards until ctx is done, the connection is lost or the pod
+// is no longer a ready endpoint. It reports whether the ports were forwarded
+// at all, and records the local ports used so reconnects reuse them.
+func (kubeClient *batteryKubeClient) forwardToPod(
+	ctx context.Context,
+	opts ServicePortForward,
+	addresses []string,
+	pod string,
+	localPorts []int,
+	remotePorts []int32) (bool, error) {
+
+	portMap := make([]string, len(localPorts))
+	for i := range localPorts {
+		portMap[i] = fmt.Sprintf("%d:%d", localPorts[i], remotePorts[i])
+	}
+
+	stopChannel := make(chan struct{})
+	readyChannel := make(chan struct{})
+
+	forwarder, err := kubeClient.portForward(opts.Namespace, pod, addresses, portMap, stopChannel, readyChannel)
+	if err != nil {
+		return false, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- forwarder.ForwardPorts() }()
+
+	ticker := time.NewTicker(endpointCheckInterval)
+	defer ticker.Stop()
+
+	ready := false
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				err = fmt.Errorf("pod %s is no longer a ready endpoint", pod)
+			}
+			return ready, err
+		case <-readyChannel:
+			readyChannel = nil
+			ready = true
+
+			ports, err := forwarder.GetPorts()
+			if err != nil {
+				close(stopChannel)
+				<-done
+				return ready, err
+			}
+
+			for i, port := range ports {
+				localPorts[i] = int(port.Local)
+			}
+
+			if opts.Ready != nil {
+				opts.Ready(pod, ports)
+			}
+		case <-ticker.C:
+			if !kubeClient.isReadyEndpoint(ctx, opts.Namespace, opts.Service, pod) {
+				close(stopChannel)
+				return ready, errors.Join(fmt.Errorf("pod %s is no longer a ready endpoint", pod), <-done)
+			}
+		case <-ctx.Done():
+			close(stopChannel)
+			return ready, <-done
+		}
+	}
+}
+
+// resolveServicePorts returns the service ports that the mappings' remote
+// ports refer to, by number or by name.
+func resolveServicePorts(service *corev1.Service, mappings []PortMapping) ([]corev1.ServicePort, error) {
+	result := make([]corev1.ServicePort, 0, len(mappings))
+
+	for _, m := range mappings {
+		idx := -1
+		for i, sp := range service.Spec.Ports {
+			if sp.Name == m.Remote || strconv.Itoa(int(sp.Port)) == m.Remote {
+				idx = i
+				break
+			}
+		}
+
+		if idx == -1 {
+			return nil, fmt.Errorf("service %s has no port %s", service.Name, m.Remote)
+		}
+
+		result = append(result, service.Spec.Ports[idx])
+	}
+
+	return result, nil
+}
+
+// readyEndpoint returns a ready pod of the service and the pod's ports for
+// the service ports.
+func (kubeClient *batteryKubeClient) readyEndpoint(
 	ctx context.Context,
 	namespace string,
-	name string,
-	port int,
-	localPort int,
-	stopChannel <-chan struct{},
-	readyChannel chan struct{}) (*portforward.PortForwarder, error) {
+	service string,
+	servicePorts []corev1.ServicePort) (string, []int32, error) {
 
-	// Port forwarding can only target a single pod, so we
-	// need to get the pod name from the service
-	podName, err := kubeClient.getPodNameFromService(ctx, namespace, name)
+	slices, err := kubeClient.listEndpointSlices(ctx, namespace, service)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get pod name from service: %w", err)
+		return "", nil, err
+	}
+
+	pod, ports, ok := pickReadyEndpoint(slices, servicePorts)
+	if !ok {
+		return "", nil, fmt.Errorf("no ready pods found for service %s", service)
+	}
+
+	slog.Debug("Found ready endpoint", slog.String("service", service), slog.String("pod", pod))
+
+	return pod, ports, nil
+}
+
+func (kubeClient *batteryKubeClient) isReadyEndpoint(ctx context.Context, namespace string, service string, pod string) bool {
+	slices, err := kubeClient.listEndpointSlices(ctx, namespace, service)
+	if err != nil {
+		// Leave it to the connection to fail if the api server is gone.
+		slog.Debug("Unable to check endpoint", slog.String("pod", pod), slog.Any("error", err))
+		return true
+	}
+
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if isReady(ep) && ep.TargetRef.Name == pod {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (kubeClient *batteryKubeClient) listEndpointSlices(ctx context.Context, namespace string, service string) ([]discoveryv1.EndpointSlice, error) {
+	list, err := kubeClient.client.DiscoveryV1().
+		EndpointSlices(namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: discoveryv1.LabelServiceName + "=" + service})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list endpoint slices for service %s: %w", service, err)
+	}
+
+	return list.Items, nil
+}
+
+// pickReadyEndpoint returns the first ready pod in the slices that has all of
+// the service ports, along with its port numbers for them.
+func pickReadyEndpoint(slices []discoveryv1.EndpointSlice, servicePorts []corev1.ServicePort) (string, []int32, bool) {
+	for _, slice := range slices {
+		ports, ok := endpointPorts(slice, servicePorts)
+		if !ok {
+			continue
+		}
+
+		for _, ep := range slice.Endpoints {
+			if isReady(ep) {
+				return ep.TargetRef.Name, ports, true
+			}
+		}
 	}
 
-	portMap := fmt.Sprintf("%d:%d", localPort, port)
-	return kubeClient.portForward(namespace, podName, []string{portMap}, stopChannel, readyChannel)
+	return "", nil, false
+}
+
+// endpointPorts maps the service ports to the slice's ports, which share the
+// service port's name.
+func endpointPorts(slice discoveryv1.EndpointSlice, servicePorts []corev1.ServicePort) ([]int32, bool) {
+	result := make([]int32, 0, len(servicePorts))
+
+	for _, sp := range servicePorts {
+		found := false
+		for _, p := range slice.Ports {
+			name := ""
+			if p.Name != nil {
+				name = *p.Name
+			}
+
+			if p.Port != nil && name == sp.Name {
+				result = append(result, *p.Port)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return result, true
+}
+
+// isReady reports whether the endpoint is a pod that can take connections.
+// An unknown ready condition means ready.
+func isReady(ep discoveryv1.Endpoint) bool {
+	if ep.TargetRef == nil || ep.TargetRef.Kind != "Pod" {
+		return false
+	}
+
+	if ep.Conditions.Terminating != nil && *ep.Conditions.Terminating {
+		return false
+	}
+
+	return ep.Conditions.Ready == nil || *ep.Conditions.Ready
 }
 
 func (kubeClient *batteryKubeClient) portForward(
 	namespace string,
 	podName string,
+	addresses []string,
 	portMap []string,
 	stopChannel <-chan struct{},
 	readyChannel chan struct{}) (*portforward.PortForwarder, error) {
@@ -75,36 +385,9 @@ func (kubeClient *batteryKubeClient) portForward(
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
 
 	slog.Debug("Starting port forward",
+		slog.String("pod", podName),
 		slog.Any("portMap", portMap),
 		slog.Any("url", url))
 
-	return portforward.NewOnAddresses(dialer, []string{"localhost"}, portMap, stopChannel, readyChannel, os.Stdout, os.Stderr)
-}
-
-func (kubeClient *batteryKubeClient) getPodNameFromService(ctx context.Context, namespace string, name string) (string, error) {
-	// Get all the running pods that are being targeted by the service
-	service, err := kubeClient.client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		return "", err
-	}
-
-	// using the selector get all the pods that are being targeted by the service
-	listOptions := metav1.ListOptions{
-		LabelSelector: labels.FormatLabels(service.Spec.Selector),
-	}
-
-	pods, err := kubeClient.client.CoreV1().
-		Pods(namespace).
-		List(ctx, listOptions)
-	if err != nil {
-		return "", err
-	}
-
-	slog.Debug("Service pods found", slog.Int("podCount", len(pods.Items)))
-
-	if len(pods.Items) == 0 {
-		return "", fmt.Errorf("no pods found for service %s", name)
-	}
-	return pods.Items[0].Name, nil
-
+	return portforward.NewOnAddresses(dialer, addresses, portMap, stopChannel, readyChannel, os.Stdout, os.Stderr)
 }
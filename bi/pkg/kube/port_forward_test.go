@@ -0,0 +1,125 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+func TestParsePortMapping(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    PortMapping
+		wantErr bool
+	}{
+		{in: "8080:80", want: PortMapping{Local: 8080, Remote: "80"}},
+		{in: "3000:http", want: PortMapping{Local: 3000, Remote: "http"}},
+		{in: "6379", want: PortMapping{Local: 6379, Remote: "6379"}},
+		{in: ":6379", want: PortMapping{Local: 0, Remote: "6379"}},
+		{in: "http", wantErr: true},
+		{in: "8080:", wantErr: true},
+		{in: "x:80", wantErr: true},
+		{in: "70000:80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePortMapping(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPickReadyEndpoint(t *testing.T) {
+	service := &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+		{Name: "http", Port: 80},
+		{Name: "metrics", Port: 9090},
+	}}}
+
+	endpoint := func(pod string, ready, terminating bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: pod},
+			Conditions: discoveryv1.EndpointConditions{
+				Ready:       ptrTo(ready),
+				Terminating: ptrTo(terminating),
+			},
+		}
+	}
+
+	slice := discoveryv1.EndpointSlice{
+		Ports: []discoveryv1.EndpointPort{
+			{Name: ptrTo("metrics"), Port: ptrTo[int32](9091)},
+			{Name: ptrTo("http"), Port: ptrTo[int32](8080)},
+		},
+		Endpoints: []discoveryv1.Endpoint{
+			endpoint("not-ready", false, false),
+			endpoint("terminating", true, true),
+			endpoint("ready", true, false),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		remote    []string
+		slices    []discoveryv1.EndpointSlice
+		wantPod   string
+		wantPorts []int32
+		wantOK    bool
+	}{
+		{
+			name:      "by number and name",
+			remote:    []string{"80", "metrics"},
+			slices:    []discoveryv1.EndpointSlice{slice},
+			wantPod:   "ready",
+			wantPorts: []int32{8080, 9091},
+			wantOK:    true,
+		},
+		{
+			name:   "no ready pods",
+			remote: []string{"http"},
+			slices: []discoveryv1.EndpointSlice{{
+				Ports:     slice.Ports,
+				Endpoints: []discoveryv1.Endpoint{endpoint("not-ready", false, false)},
+			}},
+		},
+		{
+			name:   "port missing from slice",
+			remote: []string{"metrics"},
+			slices: []discoveryv1.EndpointSlice{{
+				Ports:     slice.Ports[1:],
+				Endpoints: slice.Endpoints,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mappings []PortMapping
+			for _, r := range tt.remote {
+				mappings = append(mappings, PortMapping{Remote: r})
+			}
+
+			servicePorts, err := resolveServicePorts(service, mappings)
+			require.NoError(t, err)
+
+			pod, ports, ok := pickReadyEndpoint(tt.slices, servicePorts)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantPod, pod)
+			require.Equal(t, tt.wantPorts, ports)
+		})
+	}
+
+	_, err := resolveServicePorts(service, []PortMapping{{Remote: "grpc"}})
+	require.Error(t, err)
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
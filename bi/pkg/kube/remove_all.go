@@ -1,12 +1,20 @@
 package kube
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"bi/pkg/cluster/util"
+
+	"github.com/vbauerster/mpb/v8"
 	"golang.org/x/sync/errgroup"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -17,6 +25,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 )
 
 const (
@@ -24,303 +33,558 @@ const (
 	maxConcurrentDeletions = 10
 	// pollInterval is the interval at which we poll for resource deletion.
 	pollInterval = 5 * time.Second
+	// DefaultFinalizerTimeout is how long a resource's finalizers get before
+	// they are reported, or stripped with Force.
+	DefaultFinalizerTimeout = 5 * time.Minute
 )
 
-func (kubeClient *batteryKubeClient) RemoveAll(ctx context.Context) error {
-	// Delete all the resources in the cluster
-	// however there's an order that things need to go.
+// RemoveAllOptions changes how RemoveAll removes the resources.
+type RemoveAllOptions struct {
+	// DryRun writes what would be removed to Out without removing anything.
+	DryRun bool
+	// Out is where the dry run is written, stdout if nil.
+	Out io.Writer
+	// Force strips the finalizers of resources that are still there after
+	// FinalizerTimeout.
+	Force bool
+	// FinalizerTimeout is how long to wait on finalizers before reporting
+	// them, and then again between reports. Defaults to
+	// DefaultFinalizerTimeout.
+	FinalizerTimeout time.Duration
+	// FailAfterTimeout fails the removal once FinalizerTimeout has passed,
+	// rather than waiting on slow deletions. Ignored with Force.
+	FailAfterTimeout bool
+	// DeleteVolumes switches the persistent volumes claimed in the removed
+	// namespaces to the Delete reclaim policy, and waits for them to be
+	// released, so that their backing volumes aren't left behind.
+	DeleteVolumes bool
+	// ProgressReporter shows a bar for the steps if not nil.
+	ProgressReporter *util.ProgressReporter
+}
 
-	// 1. First stop the control server so that its reconciler doesn't try to
-	// recreate the resources we're about to delete.
-	slog.Debug("Stopping control server (if it exists)")
+// resourceRef is a resource to be removed.
+type resourceRef struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	// detail is shown in the dry run.
+	detail string
+}
 
-	if err := kubeClient.removeControlServer(ctx); err != nil {
-		return fmt.Errorf("failed to stop control server: %w", err)
+func (r resourceRef) String() string {
+	resource := r.gvr.GroupResource().String()
+	if r.namespace != "" {
+		resource += " " + r.namespace + "/" + r.name
+	} else {
+		resource += " " + r.name
 	}
 
-	// 2. Delete all PodDisruptionBudgets (as they will block draining nodes).
-	slog.Debug("Removing pod disruption budgets")
-
-	namespaces, err := kubeClient.client.CoreV1().Namespaces().List(ctx, taggedListOptions())
-	if err != nil {
-		return fmt.Errorf("failed to list namespaces: %w", err)
+	if r.detail != "" {
+		resource += " (" + r.detail + ")"
 	}
 
-	if err := kubeClient.removePodDisruptionBudgets(ctx, namespaces); err != nil {
-		return fmt.Errorf("failed to remove pod disruption budgets: %w", err)
+	return resource
+}
+
+// key identifies the resource whatever version it was listed with.
+func (r resourceRef) key() string {
+	return r.gvr.GroupResource().String() + "/" + r.namespace + "/" + r.name
+}
+
+// removalStep removes a set of resources, the steps have to run in order.
+type removalStep struct {
+	name string
+	list func(ctx context.Context) ([]resourceRef, error)
+	// noWait doesn't wait for the resources to be gone.
+	noWait bool
+	// again lists resources that an earlier step already has.
+	again bool
+	// remove replaces deleting the resources.
+	remove func(ctx context.Context, refs []resourceRef) error
+}
+
+// remover removes the resources matching listOptions. The namespaces and
+// CRDs are listed once, when first needed.
+type remover struct {
+	kubeClient  *batteryKubeClient
+	listOptions metav1.ListOptions
+	opts        RemoveAllOptions
+	// searchAll looks in every namespace, and through the instances of every
+	// CRD, rather than only the matching ones. The resources of a single
+	// battery are spread through namespaces that other batteries own.
+	searchAll bool
+
+	namespaces []string
+	crds       []apiextensionsv1.CustomResourceDefinition
+	// searchCRDs are the CRDs whose instances are removed.
+	searchCRDs []apiextensionsv1.CustomResourceDefinition
+	loaded     bool
+
+	mu sync.Mutex
+	// seen skips the resources that an earlier step already has, the
+	// cluster scoped step finds CRDs and CRs again.
+	seen map[string]bool
+	// finalized records the finalizers that were stripped with Force.
+	finalized map[string][]string
+	// volumes are the persistent volumes claimed in the namespaces.
+	volumes []volume
+}
+
+func (kubeClient *batteryKubeClient) newRemover(listOptions metav1.ListOptions, opts RemoveAllOptions) *remover {
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.FinalizerTimeout == 0 {
+		opts.FinalizerTimeout = DefaultFinalizerTimeout
 	}
 
-	crds, err := kubeClient.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().List(ctx, taggedListOptions())
-	if err != nil {
-		return fmt.Errorf("failed to list CRDs: %w", err)
+	return &remover{
+		kubeClient:  kubeClient,
+		listOptions: listOptions,
+		opts:        opts,
+		seen:        make(map[string]bool),
+		finalized:   make(map[string][]string),
 	}
+}
 
-	// 3. Delete all namespace-scoped custom resources.
-	slog.Debug("Removing namespace scoped custom resources")
+func (kubeClient *batteryKubeClient) RemoveAll(ctx context.Context, opts RemoveAllOptions) error {
+	r := kubeClient.newRemover(taggedListOptions(), opts)
 
-	if err := kubeClient.removeNamespacedCustomResources(ctx, namespaces, crds); err != nil {
-		return fmt.Errorf("failed to remove namespace-scoped custom resources: %w", err)
+	// Delete all the resources in the cluster
+	// however there's an order that things need to go.
+	steps := []removalStep{
+		// 1. First stop the control server so that its reconciler doesn't try to
+		// recreate the resources we're about to delete.
+		{name: "control server", list: r.listControlServer},
+		// 2. Delete all PodDisruptionBudgets (as they will block draining nodes).
+		{name: "pod disruption budgets", list: r.listPodDisruptionBudgets},
+		// 3. Delete all namespace-scoped custom resources.
+		{name: "namespace scoped custom resources", list: r.listNamespacedCustomResources},
+		// 4. Delete all cluster-scoped custom resources.
+		{name: "cluster scoped custom resources", list: r.listClusterScopedCustomResources},
+		// 5. Delete the CRDs themselves.
+		{name: "custom resource definitions", list: r.listCustomResourceDefinitions, noWait: true},
+		// 6. Delete loadbalancer services (that may have allocated EIPs etc).
+		{name: "loadbalancer services", list: r.listLoadBalancers},
+		// 7. Find the persistent volumes claimed in the namespaces (and switch
+		// them to be deleted with their claims). This has to happen before the
+		// claims go with the namespaces.
+		{name: "persistent volumes", list: r.listPersistentVolumes, remove: r.releasePersistentVolumes},
+		// 8. Delete all batteries included cluster-scoped resources.
+		{name: "cluster scoped resources", list: r.listClusterScopedResources},
+		// 9. Delete the batteries included namespaces (and all their containing resources).
+		{name: "namespaces", list: r.listNamespaces},
 	}
 
-	// 4. Delete all cluster-scoped custom resources.
-	slog.Debug("Removing cluster scoped custom resources")
-
-	if err := kubeClient.removeClusterScopedCustomResources(ctx, crds); err != nil {
-		return fmt.Errorf("failed to remove cluster-scoped custom resources: %w", err)
+	if opts.DeleteVolumes {
+		// 10. Wait for the volumes to be deleted along with their backing volumes.
+		steps = append(steps, removalStep{
+			name:   "persistent volume release",
+			list:   r.listReleasingVolumes,
+			again:  true,
+			remove: r.waitForVolumeRelease,
+		})
 	}
 
-	// 5. Delete the CRDs themselves.
-	slog.Debug("Removing custom resource definitions")
+	return r.run(ctx, steps)
+}
 
-	if err := kubeClient.removeCustomResourceDefinitions(ctx, crds); err != nil {
-		return fmt.Errorf("failed to remove custom resource definitions: %w", err)
+func (r *remover) run(ctx context.Context, steps []removalStep) error {
+	var bar *mpb.Bar
+	if r.opts.ProgressReporter != nil && !r.opts.DryRun {
+		bar = r.opts.ProgressReporter.ForKubeCleanup(len(steps))
 	}
 
-	// 6. Delete loadbalancer services (that may have allocated EIPs etc).
-	slog.Debug("Removing loadbalancer services")
+	for _, step := range steps {
+		slog.Debug("Removing " + step.name)
 
-	if err := kubeClient.removeLoadBalancers(ctx, namespaces); err != nil {
-		return fmt.Errorf("failed to remove loadbalancer services: %w", err)
-	}
+		refs, err := step.list(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", step.name, err)
+		}
+
+		if !step.again {
+			refs = r.unseen(refs)
+		}
 
-	// 7. Delete all batteries included cluster-scoped resources.
-	slog.Debug("Removing cluster scoped resources")
+		if r.opts.DryRun {
+			r.printStep(step, refs)
+			continue
+		}
+
+		if err := r.remove(ctx, step, refs); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", step.name, err)
+		}
 
-	if err := kubeClient.removeClusterScopedResources(ctx); err != nil {
-		return fmt.Errorf("failed to remove cluster scoped resources: %w", err)
+		util.IncrementWithMessage(bar, fmt.Sprintf("Removed %d %s", len(refs), step.name))
 	}
 
-	// 8. Delete the batteries included namespaces (and all their containing resources).
-	slog.Debug("Removing namespaces")
+	util.SetTotalAndComplete(bar)
 
-	if err := kubeClient.removeNamespaces(ctx, namespaces); err != nil {
-		return fmt.Errorf("failed to remove namespaces: %w", err)
+	if !r.opts.DryRun {
+		r.reportFinalized()
+		r.reportVolumes()
 	}
 
 	return nil
 }
 
-func (kubeClient *batteryKubeClient) removeControlServer(ctx context.Context) error {
-	gvr, err := kubeClient.getGroupVersionResource(&appsv1.Deployment{})
-	if err != nil {
-		return fmt.Errorf("failed to get GVR for Deployment: %w", err)
+func (r *remover) unseen(refs []resourceRef) []resourceRef {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]resourceRef, 0, len(refs))
+	for _, ref := range refs {
+		if !r.seen[ref.key()] {
+			r.seen[ref.key()] = true
+			result = append(result, ref)
+		}
 	}
 
-	return kubeClient.deleteAndWait(ctx, gvr, "battery-core", "controlserver")
+	slices.SortFunc(result, func(a, b resourceRef) int { return cmp.Compare(a.String(), b.String()) })
+
+	return result
+}
+
+func (r *remover) printStep(step removalStep, refs []resourceRef) {
+	fmt.Fprintf(r.opts.Out, "%s (%d):\n", step.name, len(refs))
+	for _, ref := range refs {
+		fmt.Fprintf(r.opts.Out, "  %s\n", ref)
+	}
 }
 
-func (kubeClient *batteryKubeClient) removePodDisruptionBudgets(ctx context.Context, namespaces *corev1.NamespaceList) error {
+func (r *remover) remove(ctx context.Context, step removalStep, refs []resourceRef) error {
+	if step.remove != nil {
+		return step.remove(ctx, refs)
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(maxConcurrentDeletions)
 
-	gvr, err := kubeClient.getGroupVersionResource(&policyv1.PodDisruptionBudget{})
+	for _, ref := range refs {
+		g.Go(func() error {
+			if step.noWait {
+				return r.kubeClient.delete(ctx, ref)
+			}
+			return r.deleteAndWait(ctx, ref)
+		})
+	}
+
+	return g.Wait()
+}
+
+// load lists the namespaces and CRDs, after the control server is stopped
+// so that it doesn't add to them.
+func (r *remover) load(ctx context.Context) error {
+	if r.loaded {
+		return nil
+	}
+
+	namespaces, err := r.kubeClient.client.CoreV1().Namespaces().List(ctx, r.listOptions)
 	if err != nil {
-		return fmt.Errorf("failed to get GVR for PodDisruptionBudget: %w", err)
+		return fmt.Errorf("failed to list namespaces: %w", err)
 	}
 
 	for _, ns := range namespaces.Items {
-		namespace := ns.Name
+		r.namespaces = append(r.namespaces, ns.Name)
+	}
+
+	crds, err := r.kubeClient.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().List(ctx, r.listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list CRDs: %w", err)
+	}
+
+	r.crds = crds.Items
+	r.searchCRDs = crds.Items
+
+	if r.searchAll {
+		all, err := r.kubeClient.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list CRDs: %w", err)
+		}
+		r.searchCRDs = all.Items
+	}
+
+	r.loaded = true
+
+	return nil
+}
+
+// searchNamespaces returns the namespaces to look for resources in.
+func (r *remover) searchNamespaces() []string {
+	if r.searchAll {
+		return []string{metav1.NamespaceAll}
+	}
+	return r.namespaces
+}
+
+// listEach runs list concurrently and collects the results.
+func listEach[T any](ctx context.Context, items []T, list func(ctx context.Context, item T) ([]resourceRef, error)) ([]resourceRef, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentDeletions)
+
+	var mu sync.Mutex
+	var result []resourceRef
+
+	for _, item := range items {
 		g.Go(func() error {
-			pdbs, err := kubeClient.client.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, taggedListOptions())
-			if err != nil && !kerrs.IsNotFound(err) {
+			refs, err := list(ctx, item)
+			if err != nil {
 				return err
 			}
 
-			for _, pdb := range pdbs.Items {
-				name := pdb.Name
-				g.Go(func() error {
-					return kubeClient.deleteAndWait(ctx, gvr, namespace, name)
-				})
-			}
+			mu.Lock()
+			defer mu.Unlock()
+			result = append(result, refs...)
 
 			return nil
 		})
 	}
 
-	return g.Wait()
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-func (kubeClient *batteryKubeClient) removeNamespacedCustomResources(ctx context.Context, namespaces *corev1.NamespaceList, crds *apiextensionsv1.CustomResourceDefinitionList) error {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(maxConcurrentDeletions)
+func (r *remover) listControlServer(ctx context.Context) ([]resourceRef, error) {
+	gvr, err := r.kubeClient.getGroupVersionResource(&appsv1.Deployment{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVR for Deployment: %w", err)
+	}
 
-	for _, crd := range crds.Items {
-		if crd.Spec.Scope == "Namespaced" {
-			for _, version := range crd.Spec.Versions {
-				gvr := schema.GroupVersionResource{
-					Group:    crd.Spec.Group,
-					Version:  version.Name,
-					Resource: crd.Spec.Names.Plural,
-				}
+	_, err = r.kubeClient.client.AppsV1().Deployments("battery-core").Get(ctx, "controlserver", metav1.GetOptions{})
+	if err != nil {
+		if kerrs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
 
-				for _, ns := range namespaces.Items {
-					namespace := ns.Name
-					g.Go(func() error {
-						crList, err := kubeClient.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, taggedListOptions())
-						if err != nil && !kerrs.IsNotFound(err) {
-							return err
-						}
-
-						// Delete each instance of the namespace-scoped custom resource.
-						for _, cr := range crList.Items {
-							name := cr.GetName()
-							g.Go(func() error {
-								return kubeClient.deleteAndWait(ctx, gvr, namespace, name)
-							})
-						}
-
-						return nil
-					})
-				}
+	return []resourceRef{{gvr: gvr, namespace: "battery-core", name: "controlserver"}}, nil
+}
+
+func (r *remover) listPodDisruptionBudgets(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+
+	gvr, err := r.kubeClient.getGroupVersionResource(&policyv1.PodDisruptionBudget{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVR for PodDisruptionBudget: %w", err)
+	}
+
+	return listEach(ctx, r.searchNamespaces(), func(ctx context.Context, namespace string) ([]resourceRef, error) {
+		pdbs, err := r.kubeClient.client.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, r.listOptions)
+		if err != nil {
+			if kerrs.IsNotFound(err) {
+				return nil, nil
 			}
+			return nil, err
 		}
-	}
 
-	return g.Wait()
+		var refs []resourceRef
+		for _, pdb := range pdbs.Items {
+			refs = append(refs, resourceRef{gvr: gvr, namespace: pdb.Namespace, name: pdb.Name})
+		}
+		return refs, nil
+	})
 }
 
-func (kubeClient *batteryKubeClient) removeClusterScopedCustomResources(ctx context.Context, crds *apiextensionsv1.CustomResourceDefinitionList) error {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(maxConcurrentDeletions)
+func (r *remover) listNamespacedCustomResources(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
 
-	for _, crd := range crds.Items {
-		if crd.Spec.Scope == "Cluster" {
-			for _, version := range crd.Spec.Versions {
-				gvr := schema.GroupVersionResource{
-					Group:    crd.Spec.Group,
-					Version:  version.Name,
-					Resource: crd.Spec.Names.Plural,
-				}
+	type target struct {
+		gvr       schema.GroupVersionResource
+		namespace string
+	}
 
-				g.Go(func() error {
-					crList, err := kubeClient.dynamicClient.Resource(gvr).List(ctx, taggedListOptions())
-					if err != nil && !kerrs.IsNotFound(err) {
-						return err
-					}
-
-					// Delete each instance of the cluster-scoped custom resource.
-					for _, cr := range crList.Items {
-						name := cr.GetName()
-						g.Go(func() error {
-							return kubeClient.deleteAndWait(ctx, gvr, "", name)
-						})
-					}
-
-					return nil
-				})
+	var targets []target
+	for _, crd := range r.searchCRDs {
+		if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+			for _, ns := range r.searchNamespaces() {
+				targets = append(targets, target{gvr: crdResource(crd), namespace: ns})
 			}
 		}
 	}
 
-	return g.Wait()
+	return listEach(ctx, targets, func(ctx context.Context, t target) ([]resourceRef, error) {
+		return r.kubeClient.listResources(ctx, t.gvr, t.namespace, r.listOptions)
+	})
 }
 
-func (kubeClient *batteryKubeClient) removeCustomResourceDefinitions(ctx context.Context, crds *apiextensionsv1.CustomResourceDefinitionList) error {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(maxConcurrentDeletions)
+func (r *remover) listClusterScopedCustomResources(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
 
-	for _, crd := range crds.Items {
-		name := crd.GetName()
-		g.Go(func() error {
-			err := kubeClient.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Delete(ctx, name, metav1.DeleteOptions{})
-			if err != nil && !kerrs.IsNotFound(err) {
-				return err
-			}
+	var gvrs []schema.GroupVersionResource
+	for _, crd := range r.searchCRDs {
+		if crd.Spec.Scope == apiextensionsv1.ClusterScoped {
+			gvrs = append(gvrs, crdResource(crd))
+		}
+	}
 
-			return nil
-		})
+	return listEach(ctx, gvrs, func(ctx context.Context, gvr schema.GroupVersionResource) ([]resourceRef, error) {
+		return r.kubeClient.listResources(ctx, gvr, "", r.listOptions)
+	})
+}
+
+func (r *remover) listCustomResourceDefinitions(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
 	}
 
-	return g.Wait()
+	gvr := apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions")
+
+	var refs []resourceRef
+	for _, crd := range r.crds {
+		refs = append(refs, resourceRef{gvr: gvr, name: crd.Name})
+	}
+
+	return refs, nil
 }
 
-func (kubeClient *batteryKubeClient) removeLoadBalancers(ctx context.Context, namespaces *corev1.NamespaceList) error {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(maxConcurrentDeletions)
+func (r *remover) listLoadBalancers(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
 
-	gvr, err := kubeClient.getGroupVersionResource(&corev1.Service{})
+	gvr, err := r.kubeClient.getGroupVersionResource(&corev1.Service{})
 	if err != nil {
-		return fmt.Errorf("failed to get GVR for Service: %w", err)
+		return nil, fmt.Errorf("failed to get GVR for Service: %w", err)
 	}
 
-	for _, ns := range namespaces.Items {
-		namespace := ns.Name
-		g.Go(func() error {
-			services, err := kubeClient.client.CoreV1().Services(namespace).List(ctx, taggedListOptions())
-			if err != nil {
-				return err
-			}
+	return listEach(ctx, r.searchNamespaces(), func(ctx context.Context, namespace string) ([]resourceRef, error) {
+		services, err := r.kubeClient.client.CoreV1().Services(namespace).List(ctx, r.listOptions)
+		if err != nil {
+			return nil, err
+		}
 
-			for _, service := range services.Items {
-				if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
-					name := service.Name
-					g.Go(func() error {
-						return kubeClient.deleteAndWait(ctx, gvr, namespace, name)
-					})
-				}
+		var refs []resourceRef
+		for _, service := range services.Items {
+			if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+				refs = append(refs, resourceRef{gvr: gvr, namespace: service.Namespace, name: service.Name})
 			}
+		}
+		return refs, nil
+	})
+}
 
-			return nil
-		})
+func (r *remover) listClusterScopedResources(ctx context.Context) ([]resourceRef, error) {
+	gvrs, err := r.kubeClient.getClusterScopedGVRs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster scoped GVRs: %w", err)
 	}
 
-	return g.Wait()
+	return listEach(ctx, gvrs, func(ctx context.Context, gvr schema.GroupVersionResource) ([]resourceRef, error) {
+		refs, err := r.kubeClient.listResources(ctx, gvr, "", r.listOptions)
+		// Some internal resources may not be listable/deletable.
+		// No matter as these are not created by batteries included.
+		if kerrs.IsMethodNotSupported(err) {
+			return nil, nil
+		}
+		return refs, err
+	})
 }
 
-func (kubeClient *batteryKubeClient) removeClusterScopedResources(ctx context.Context) error {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(maxConcurrentDeletions)
+func (r *remover) listNamespaces(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
 
-	gvrs, err := kubeClient.getClusterScopedGVRs()
+	gvr, err := r.kubeClient.getGroupVersionResource(&corev1.Namespace{})
 	if err != nil {
-		return fmt.Errorf("failed to get cluster scoped GVRs: %w", err)
+		return nil, fmt.Errorf("failed to get GVR for Namespace: %w", err)
 	}
 
-	for _, gvr := range gvrs {
-		gvr := gvr
-		g.Go(func() error {
-			resources, err := kubeClient.dynamicClient.Resource(gvr).List(ctx, taggedListOptions())
-			if err != nil {
-				// Some internal resources may not be listable/deletable.
-				// No matter as these are not created by batteries included.
-				if kerrs.IsNotFound(err) || kerrs.IsMethodNotSupported(err) {
-					return nil
-				}
+	var refs []resourceRef
+	for _, ns := range r.namespaces {
+		refs = append(refs, resourceRef{gvr: gvr, name: ns})
+	}
 
-				return err
-			}
+	return refs, nil
+}
 
-			for _, resource := range resources.Items {
-				name := resource.GetName()
-				g.Go(func() error {
-					return kubeClient.deleteAndWait(ctx, gvr, "", name)
-				})
-			}
+// listNamespacedResources lists every other type of namespaced resource.
+// RemoveAll leaves these to go with their namespaces.
+func (r *remover) listNamespacedResources(ctx context.Context) ([]resourceRef, error) {
+	apiResourceLists, err := r.kubeClient.discoveryClient.ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to get API resources: %w", err)
+	}
 
-			return nil
-		})
+	var gvrs []schema.GroupVersionResource
+	for _, apiResourceList := range apiResourceLists {
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, apiResource := range apiResourceList.APIResources {
+			if slices.Contains(apiResource.Verbs, "list") && slices.Contains(apiResource.Verbs, "delete") {
+				gvrs = append(gvrs, gv.WithResource(apiResource.Name))
+			}
+		}
 	}
 
-	return g.Wait()
+	return listEach(ctx, gvrs, func(ctx context.Context, gvr schema.GroupVersionResource) ([]resourceRef, error) {
+		var refs []resourceRef
+		for _, namespace := range r.searchNamespaces() {
+			nsRefs, err := r.kubeClient.listResources(ctx, gvr, namespace, r.listOptions)
+			if err != nil {
+				if kerrs.IsMethodNotSupported(err) {
+					return nil, nil
+				}
+				return nil, err
+			}
+			refs = append(refs, nsRefs...)
+		}
+		return refs, nil
+	})
 }
 
-func (kubeClient *batteryKubeClient) removeNamespaces(ctx context.Context, namespaces *corev1.NamespaceList) error {
-	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(maxConcurrentDeletions)
+// listResources lists the resources of a type, a missing type has none.
+func (kubeClient *batteryKubeClient) listResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions) ([]resourceRef, error) {
+	lister := kubeClient.dynamicClient.Resource(gvr).List
+	if namespace != "" {
+		lister = kubeClient.dynamicClient.Resource(gvr).Namespace(namespace).List
+	}
 
-	gvr, err := kubeClient.getGroupVersionResource(&corev1.Namespace{})
+	list, err := lister(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get GVR for Namespace: %w", err)
+		if kerrs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	for _, ns := range namespaces.Items {
-		namespace := ns.Name
-		g.Go(func() error {
-			return kubeClient.deleteAndWait(ctx, gvr, "", namespace)
-		})
+	refs := make([]resourceRef, 0, len(list.Items))
+	for _, item := range list.Items {
+		refs = append(refs, resourceRef{gvr: gvr, namespace: item.GetNamespace(), name: item.GetName()})
 	}
 
-	return g.Wait()
+	return refs, nil
+}
+
+// crdResource returns the resource of the CRD's storage version, every
+// served version lists the same objects.
+func crdResource(crd apiextensionsv1.CustomResourceDefinition) schema.GroupVersionResource {
+	version := crd.Spec.Versions[0].Name
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			version = v.Name
+		}
+	}
+
+	return schema.GroupVersionResource{
+		Group:    crd.Spec.Group,
+		Version:  version,
+		Resource: crd.Spec.Names.Plural,
+	}
 }
 
 func (kubeClient *batteryKubeClient) getClusterScopedGVRs() ([]schema.GroupVersionResource, error) {
@@ -372,32 +636,43 @@ func (kubeClient *batteryKubeClient) getClusterScopedGVRs() ([]schema.GroupVersi
 	return clusterScopedGVRs, nil
 }
 
-// deleteAndWait schedules a resource for deletion and waits for it to be deleted.
-func (kubeClient *batteryKubeClient) deleteAndWait(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
-	l := slog.With(slog.String("gvr", gvr.String()), slog.String("name", name))
+// delete schedules a resource for deletion.
+func (kubeClient *batteryKubeClient) delete(ctx context.Context, ref resourceRef) error {
+	l := slog.With(slog.String("gvr", ref.gvr.String()), slog.String("name", ref.name))
 
 	// Cluster scoped
-	deletor := kubeClient.dynamicClient.Resource(gvr).Delete
+	deletor := kubeClient.dynamicClient.Resource(ref.gvr).Delete
 	msg := "Deleting cluster scoped resource"
 
 	// Namespace scoped.
-	if namespace != "" {
-		l = l.With(slog.String("namespace", namespace))
+	if ref.namespace != "" {
+		l = l.With(slog.String("namespace", ref.namespace))
 		msg = "Deleting namespace scoped resource"
-		deletor = kubeClient.dynamicClient.Resource(gvr).Namespace(namespace).Delete
+		deletor = kubeClient.dynamicClient.Resource(ref.gvr).Namespace(ref.namespace).Delete
 	}
 
 	l.Debug(msg)
-	err := deletor(ctx, name, metav1.DeleteOptions{})
-	if err != nil {
-		// If the resource is not found, it's already been deleted.
-		if kerrs.IsNotFound(err) {
-			return nil
-		}
+	err := deletor(ctx, ref.name, metav1.DeleteOptions{})
+	// If the resource is not found, it's already been deleted.
+	if err != nil && !kerrs.IsNotFound(err) {
+		return err
+	}
 
+	return nil
+}
+
+// deleteAndWait schedules a resource for deletion and waits for it to be
+// deleted. Finalizers that haven't cleared by the timeout are reported, and
+// stripped with Force. Without Force it keeps waiting, unless
+// FailAfterTimeout is set.
+func (r *remover) deleteAndWait(ctx context.Context, ref resourceRef) error {
+	if err := r.kubeClient.delete(ctx, ref); err != nil {
 		return err
 	}
 
+	l := slog.With(slog.String("resource", ref.String()))
+	deadline := time.Now().Add(r.opts.FinalizerTimeout)
+
 	// Poll the resource until it's deleted, we could use a watch here but they get
 	// expensive when we have a lot of resources to delete.
 	t := time.NewTicker(pollInterval)
@@ -412,12 +687,12 @@ func (kubeClient *batteryKubeClient) deleteAndWait(ctx context.Context, gvr sche
 
 		l.Debug("Polling for deletion")
 
-		getter := kubeClient.dynamicClient.Resource(gvr).Get
-		if namespace != "" {
-			getter = kubeClient.dynamicClient.Resource(gvr).Namespace(namespace).Get
+		getter := r.kubeClient.dynamicClient.Resource(ref.gvr).Get
+		if ref.namespace != "" {
+			getter = r.kubeClient.dynamicClient.Resource(ref.gvr).Namespace(ref.namespace).Get
 		}
 
-		_, err := getter(ctx, name, metav1.GetOptions{})
+		obj, err := getter(ctx, ref.name, metav1.GetOptions{})
 		if err != nil {
 			if kerrs.IsNotFound(err) {
 				l.Debug("Resource deleted")
@@ -426,9 +701,29 @@ func (kubeClient *batteryKubeClient) deleteAndWait(ctx context.Context, gvr sche
 
 			return fmt.Errorf("failed to poll for deletion: %w", err)
 		}
+
+		if time.Now().Before(deadline) {
+			continue
+		}
+		deadline = time.Now().Add(r.opts.FinalizerTimeout)
+
+		if err := r.handleStuck(ctx, ref, obj); err != nil {
+			return err
+		}
+
+		if !r.opts.Force && r.opts.FailAfterTimeout {
+			return fmt.Errorf("%s is still being deleted after %s, force removal would strip its finalizers", ref, r.opts.FinalizerTimeout)
+		}
 	}
 }
 
 func taggedListOptions() metav1.ListOptions {
 	return metav1.ListOptions{LabelSelector: labels.Set{"battery/managed": "true"}.String()}
 }
+
+func batteryListOptions(batteryID string) metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: labels.Set{
+		"battery/managed": "true",
+		"battery/owner":   batteryID,
+	}.String()}
+}
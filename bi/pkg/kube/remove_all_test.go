@@ -0,0 +1,98 @@
+package kube
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRemoverDryRunOutput(t *testing.T) {
+	var out bytes.Buffer
+	r := (&batteryKubeClient{}).newRemover(metav1.ListOptions{}, RemoveAllOptions{DryRun: true, Out: &out})
+
+	crds := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	crdsBeta := crds
+	crdsBeta.Version = "v1beta1"
+	pdbs := schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}
+
+	step := removalStep{name: "pod disruption budgets"}
+	r.printStep(step, r.unseen([]resourceRef{
+		{gvr: pdbs, namespace: "battery-core", name: "b"},
+		{gvr: pdbs, namespace: "battery-core", name: "a"},
+	}))
+
+	step = removalStep{name: "custom resource definitions"}
+	r.printStep(step, r.unseen([]resourceRef{{gvr: crds, name: "clusters.postgresql.cnpg.io"}}))
+
+	// Found again by the cluster scoped step, with another version.
+	step = removalStep{name: "cluster scoped resources"}
+	r.printStep(step, r.unseen([]resourceRef{{gvr: crdsBeta, name: "clusters.postgresql.cnpg.io"}}))
+
+	require.Equal(t, `pod disruption budgets (2):
+  poddisruptionbudgets.policy battery-core/a
+  poddisruptionbudgets.policy battery-core/b
+custom resource definitions (1):
+  customresourcedefinitions.apiextensions.k8s.io clusters.postgresql.cnpg.io
+cluster scoped resources (0):
+`, out.String())
+}
+
+func TestCRDResource(t *testing.T) {
+	crd := apiextensionsv1.CustomResourceDefinition{Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+		Group: "example.com",
+		Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets"},
+		Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+			{Name: "v1alpha1"},
+			{Name: "v1", Storage: true},
+		},
+	}}
+
+	require.Equal(t, schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, crdResource(crd))
+}
+
+func TestVolumeSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source corev1.PersistentVolumeSource
+		want   string
+	}{
+		{
+			name:   "csi",
+			source: corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-0123"}},
+			want:   "ebs.csi.aws.com vol-0123",
+		},
+		{
+			name:   "hostpath",
+			source: corev1.PersistentVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/local-path-provisioner/pvc-1"}},
+			want:   "hostpath /var/local-path-provisioner/pvc-1",
+		},
+		{name: "unknown", want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv := &corev1.PersistentVolume{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: tt.source}}
+			require.Equal(t, tt.want, volumeSource(pv))
+		})
+	}
+}
+
+func TestFinalizerControllers(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetFinalizers([]string{"service.kubernetes.io/load-balancer-cleanup"})
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kubectl-client-side-apply", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:type":{}}}`)}},
+		{Manager: "service-controller", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:finalizers":{".":{},"v:\"service.kubernetes.io/load-balancer-cleanup\"":{}}}}`)}},
+		{Manager: "service-controller", Subresource: "status", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:finalizers":{}}}`)}},
+		{Manager: "no-fields"},
+	})
+
+	require.Equal(t, []string{"service-controller"}, finalizerControllers(obj))
+	require.Empty(t, finalizerControllers(&unstructured.Unstructured{}))
+}
@@ -0,0 +1,72 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RemoveBattery removes the resources of a single battery, those labelled
+// with its id, in the same order as RemoveAll. A running control server
+// recreates them unless the battery is removed from its state too.
+//
+// Resources the control server makes for a battery's sub-objects, like
+// postgres clusters or ferret services, are owned by the sub-object's id
+// rather than the battery's and are left behind.
+func (kubeClient *batteryKubeClient) RemoveBattery(ctx context.Context, batteryID string, opts RemoveAllOptions) error {
+	if batteryID == "" {
+		return errors.New("battery has no id to find its resources by")
+	}
+
+	r := kubeClient.newRemover(batteryListOptions(batteryID), opts)
+	r.searchAll = true
+
+	steps := []removalStep{
+		// 1. Delete the battery's PodDisruptionBudgets.
+		{name: "pod disruption budgets", list: r.listPodDisruptionBudgets},
+		// 2. Delete its custom resources, whichever battery owns their CRDs,
+		// while the controllers that clear their finalizers are still around.
+		{name: "namespace scoped custom resources", list: r.listNamespacedCustomResources},
+		{name: "cluster scoped custom resources", list: r.listClusterScopedCustomResources},
+		// 3. Delete the CRDs it owns.
+		{name: "custom resource definitions", list: r.listCustomResourceDefinitions, noWait: true},
+		// 4. Delete its loadbalancer services.
+		{name: "loadbalancer services", list: r.listLoadBalancers},
+		// 5. Find its persistent volumes before their claims go.
+		{name: "persistent volumes", list: r.listPersistentVolumes, remove: r.releasePersistentVolumes},
+		// 6. Delete the rest of its resources, they share namespaces with
+		// other batteries so can't go with a namespace.
+		{name: "namespaced resources", list: r.listNamespacedResources},
+		{name: "cluster scoped resources", list: r.listClusterScopedResources},
+		// 7. Delete the namespaces it owns.
+		{name: "namespaces", list: r.listNamespaces},
+	}
+
+	if opts.DeleteVolumes {
+		steps = append(steps, removalStep{
+			name:   "persistent volume release",
+			list:   r.listReleasingVolumes,
+			again:  true,
+			remove: r.waitForVolumeRelease,
+		})
+	}
+
+	return r.run(ctx, steps)
+}
+
+// ControlServerDeployed returns whether the control server's statefulset is
+// in the namespace, even if scaled to zero. Its database then has its own
+// copy of the installed batteries.
+func (kubeClient *batteryKubeClient) ControlServerDeployed(ctx context.Context, namespace string) (bool, error) {
+	statefulSets, err := kubeClient.client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{"battery/app": "battery-control-server"}.String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list control server statefulsets: %w", err)
+	}
+
+	return len(statefulSets.Items) > 0, nil
+}
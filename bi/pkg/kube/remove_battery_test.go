@@ -0,0 +1,206 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cacheddiscovery "k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
+)
+
+// newFakeKubeClient returns a client whose typed and dynamic clients both
+// have the objects, with discovery for the core and apps resources.
+func newFakeKubeClient(objects ...runtime.Object) *batteryKubeClient {
+	client := fake.NewClientset(objects...)
+
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "delete", "get", "create", "update"}},
+				{Name: "services", Kind: "Service", Namespaced: true, Verbs: []string{"list", "delete"}},
+				{Name: "namespaces", Kind: "Namespace", Verbs: []string{"list", "delete"}},
+				{Name: "persistentvolumes", Kind: "PersistentVolume", Verbs: []string{"list", "delete"}},
+				{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", Namespaced: true, Verbs: []string{"list", "delete"}},
+				// Can't be deleted so it isn't removed.
+				{Name: "events", Kind: "Event", Namespaced: true, Verbs: []string{"list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"list", "delete", "get"}},
+			},
+		},
+		{
+			GroupVersion: "policy/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget", Namespaced: true, Verbs: []string{"list", "delete"}},
+			},
+		},
+	}
+
+	discoveryClient := cacheddiscovery.NewMemCacheClient(client.Discovery())
+
+	listKinds := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "events"}: "EventList",
+	}
+
+	return &batteryKubeClient{
+		client:              client,
+		dynamicClient:       dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme, listKinds, objects...),
+		apiExtensionsClient: apiextensionsfake.NewClientset(),
+		discoveryClient:     discoveryClient,
+		mapper:              restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient),
+	}
+}
+
+func batteryConfigMap(namespace, name, owner string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      name,
+		Labels:    map[string]string{"battery/managed": "true", "battery/owner": owner},
+	}}
+}
+
+func controlServer(replicas int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "battery-core",
+			Name:      "controlserver",
+			Labels:    map[string]string{"battery/app": "battery-control-server"},
+		},
+		Spec: appsv1.StatefulSetSpec{Replicas: &replicas},
+	}
+}
+
+func TestRemoveBattery(t *testing.T) {
+	objects := []runtime.Object{
+		batteryConfigMap("battery-core", "grafana-config", "batt_grafana"),
+		batteryConfigMap("battery-base", "grafana-dashboards", "batt_grafana"),
+		batteryConfigMap("battery-core", "loki-config", "batt_loki"),
+		// Owned by a postgres cluster rather than its battery.
+		batteryConfigMap("battery-data", "pg-config", "batt_pg_cluster"),
+	}
+
+	tests := []struct {
+		name      string
+		batteryID string
+		wantErr   string
+	}{
+		{name: "no battery id", wantErr: "battery has no id to find its resources by"},
+		{name: "dry run", batteryID: "batt_grafana"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeKubeClient(objects...)
+
+			var out bytes.Buffer
+			err := c.RemoveBattery(context.Background(), tt.batteryID, RemoveAllOptions{DryRun: true, Out: &out})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, `pod disruption budgets (0):
+namespace scoped custom resources (0):
+cluster scoped custom resources (0):
+custom resource definitions (0):
+loadbalancer services (0):
+persistent volumes (0):
+namespaced resources (2):
+  configmaps battery-base/grafana-dashboards
+  configmaps battery-core/grafana-config
+cluster scoped resources (0):
+namespaces (0):
+`, out.String())
+		})
+	}
+}
+
+func TestControlServerDeployed(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		objects   []runtime.Object
+		want      bool
+	}{
+		{name: "missing", namespace: "battery-core"},
+		{name: "running", namespace: "battery-core", objects: []runtime.Object{controlServer(1)}, want: true},
+		{name: "scaled down", namespace: "battery-core", objects: []runtime.Object{controlServer(0)}, want: true},
+		{name: "other namespace", namespace: "battery-base", objects: []runtime.Object{controlServer(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployed, err := newFakeKubeClient(tt.objects...).ControlServerDeployed(context.Background(), tt.namespace)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, deployed)
+		})
+	}
+}
+
+func TestListNamespacedResources(t *testing.T) {
+	c := newFakeKubeClient(
+		batteryConfigMap("battery-core", "a", "batt_a"),
+		batteryConfigMap("battery-core", "b", "batt_b"),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "battery-base",
+			Name:      "a",
+			Labels:    map[string]string{"battery/managed": "true", "battery/owner": "batt_a"},
+		}},
+		// Not managed by batteries included.
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}},
+	)
+
+	r := c.newRemover(batteryListOptions("batt_a"), RemoveAllOptions{})
+	r.searchAll = true
+
+	refs, err := r.listNamespacedResources(context.Background())
+	require.NoError(t, err)
+
+	var got []string
+	for _, ref := range r.unseen(refs) {
+		got = append(got, ref.String())
+	}
+	require.Equal(t, []string{"configmaps battery-core/a", "services battery-base/a"}, got)
+}
+
+func TestUpdateResource(t *testing.T) {
+	c := newFakeKubeClient()
+	ctx := context.Background()
+
+	configMap := func(value string) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"namespace": "battery-core", "name": "summary"},
+			"data":       map[string]interface{}{"value": value},
+		}
+	}
+
+	// Created when missing.
+	require.NoError(t, c.UpdateResource(ctx, configMap("one")))
+
+	// Replaced when it exists.
+	require.NoError(t, c.UpdateResource(ctx, configMap("two")))
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	got, err := c.dynamicClient.Resource(gvr).Namespace("battery-core").Get(ctx, "summary", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"value": "two"}, got.Object["data"])
+}
@@ -0,0 +1,222 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	kerrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// deleteReclaimPolicyPatch has the volume deleted once its claim is.
+var deleteReclaimPolicyPatch = []byte(`{"spec":{"persistentVolumeReclaimPolicy":"Delete"}}`)
+
+// volume is a persistent volume claimed in one of the removed namespaces.
+type volume struct {
+	ref           resourceRef
+	claim         string
+	source        string
+	reclaimPolicy corev1.PersistentVolumeReclaimPolicy
+	// orphaned is why the backing volume is left behind, if it is.
+	orphaned string
+}
+
+func (r *remover) listPersistentVolumes(ctx context.Context) ([]resourceRef, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+
+	gvr, err := r.kubeClient.getGroupVersionResource(&corev1.PersistentVolume{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVR for PersistentVolume: %w", err)
+	}
+
+	// Claims outside of the namespaces count too when they match, eg. a
+	// battery's claims in a shared namespace.
+	claims := make(map[string]bool)
+	if r.searchAll {
+		pvcs, err := r.kubeClient.client.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, r.listOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pvc := range pvcs.Items {
+			claims[pvc.Namespace+"/"+pvc.Name] = true
+		}
+	}
+
+	pvs, err := r.kubeClient.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	r.volumes = nil
+	for _, pv := range pvs.Items {
+		claim := pv.Spec.ClaimRef
+		if claim == nil {
+			continue
+		}
+
+		if !slices.Contains(r.namespaces, claim.Namespace) && !claims[claim.Namespace+"/"+claim.Name] {
+			continue
+		}
+
+		v := volume{
+			ref:           resourceRef{gvr: gvr, name: pv.Name},
+			claim:         claim.Namespace + "/" + claim.Name,
+			source:        volumeSource(&pv),
+			reclaimPolicy: pv.Spec.PersistentVolumeReclaimPolicy,
+		}
+		v.ref.detail = fmt.Sprintf("%s, %s, claim %s", v.source, v.reclaimPolicy, v.claim)
+
+		r.volumes = append(r.volumes, v)
+	}
+
+	refs := make([]resourceRef, 0, len(r.volumes))
+	for _, v := range r.volumes {
+		refs = append(refs, v.ref)
+	}
+
+	return refs, nil
+}
+
+// listReleasingVolumes returns the volumes to wait on, which is all of them
+// once their reclaim policy is Delete.
+func (r *remover) listReleasingVolumes(_ context.Context) ([]resourceRef, error) {
+	refs := make([]resourceRef, 0, len(r.volumes))
+	for _, v := range r.volumes {
+		refs = append(refs, v.ref)
+	}
+
+	return refs, nil
+}
+
+// releasePersistentVolumes switches the retained volumes to be deleted with
+// their claims. Without DeleteVolumes nothing is changed and the retained
+// volumes are reported as orphaned.
+func (r *remover) releasePersistentVolumes(ctx context.Context, _ []resourceRef) error {
+	for i := range r.volumes {
+		v := &r.volumes[i]
+		if v.reclaimPolicy == corev1.PersistentVolumeReclaimDelete {
+			continue
+		}
+
+		if !r.opts.DeleteVolumes {
+			v.orphaned = fmt.Sprintf("reclaim policy is %s", v.reclaimPolicy)
+			continue
+		}
+
+		slog.Debug("Switching persistent volume to the Delete reclaim policy", slog.String("volume", v.ref.name))
+
+		_, err := r.kubeClient.client.CoreV1().PersistentVolumes().
+			Patch(ctx, v.ref.name, types.MergePatchType, deleteReclaimPolicyPatch, metav1.PatchOptions{})
+		if err != nil && !kerrs.IsNotFound(err) {
+			return fmt.Errorf("failed to change reclaim policy of %s: %w", v.ref.name, err)
+		}
+	}
+
+	return nil
+}
+
+// waitForVolumeRelease waits for the volumes to be deleted by their
+// provisioners. A volume that fails to be deleted or takes too long is
+// reported as orphaned rather than holding up the rest.
+func (r *remover) waitForVolumeRelease(ctx context.Context, _ []resourceRef) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentDeletions)
+
+	for i := range r.volumes {
+		v := &r.volumes[i]
+		g.Go(func() error {
+			orphaned, err := r.kubeClient.waitForVolumeDeleted(ctx, v.ref.name, r.opts.FinalizerTimeout)
+			v.orphaned = orphaned
+			return err
+		})
+	}
+
+	return g.Wait()
+}
+
+func (kubeClient *batteryKubeClient) waitForVolumeDeleted(ctx context.Context, name string, timeout time.Duration) (string, error) {
+	l := slog.With(slog.String("volume", name))
+	deadline := time.Now().Add(timeout)
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		l.Debug("Polling for volume release")
+
+		pv, err := kubeClient.client.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if kerrs.IsNotFound(err) {
+				l.Debug("Volume deleted")
+				return "", nil
+			}
+
+			return "", fmt.Errorf("failed to poll for volume release: %w", err)
+		}
+
+		if pv.Status.Phase == corev1.VolumeFailed {
+			return fmt.Sprintf("release failed: %s", pv.Status.Message), nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Sprintf("still %s after %s", pv.Status.Phase, timeout), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reportVolumes writes a summary of the volumes, with a warning for each
+// backing volume that is left behind.
+func (r *remover) reportVolumes() {
+	var orphaned []string
+	for _, v := range r.volumes {
+		if v.orphaned == "" {
+			continue
+		}
+
+		orphaned = append(orphaned, v.source)
+		slog.Warn("Persistent volume left behind",
+			slog.String("volume", v.ref.name),
+			slog.String("source", v.source),
+			slog.String("claim", v.claim),
+			slog.String("reason", v.orphaned))
+	}
+
+	slog.Debug("Persistent volume cleanup summary",
+		slog.Int("volumes", len(r.volumes)),
+		slog.Int("orphaned", len(orphaned)),
+		slog.String("orphanedVolumes", strings.Join(orphaned, ", ")))
+}
+
+// volumeSource describes what backs the volume, eg. the EBS volume id.
+func volumeSource(pv *corev1.PersistentVolume) string {
+	switch src := pv.Spec.PersistentVolumeSource; {
+	case src.CSI != nil:
+		return src.CSI.Driver + " " + src.CSI.VolumeHandle
+	case src.AWSElasticBlockStore != nil:
+		return "aws-ebs " + src.AWSElasticBlockStore.VolumeID
+	case src.HostPath != nil:
+		return "hostpath " + src.HostPath.Path
+	case src.Local != nil:
+		return "local " + src.Local.Path
+	case src.NFS != nil:
+		return "nfs " + src.NFS.Server + ":" + src.NFS.Path
+	default:
+		return "unknown"
+	}
+}
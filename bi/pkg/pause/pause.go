@@ -0,0 +1,64 @@
+package pause
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+)
+
+// PauseInstall stops the containers backing a local install without
+// destroying the cluster.
+func PauseInstall(ctx context.Context, env *installs.InstallEnv) error {
+	slog.Info("Pausing kube provider", slog.String("slug", env.Slug))
+
+	if err := env.PauseKubeProvider(ctx); err != nil {
+		return fmt.Errorf("unable to pause kube provider: %w", err)
+	}
+
+	return nil
+}
+
+// ResumeInstall restarts a paused install and waits for both the kube api
+// server and the control server to become healthy again.
+func ResumeInstall(ctx context.Context, env *installs.InstallEnv) error {
+	slog.Info("Resuming kube provider", slog.String("slug", env.Slug))
+
+	var progressReporter *util.ProgressReporter
+	if log.Level == slog.LevelWarn {
+		progressReporter = util.NewProgressReporter()
+		defer progressReporter.Shutdown()
+	}
+
+	if err := env.ResumeKubeProvider(ctx); err != nil {
+		return fmt.Errorf("unable to resume kube provider: %w", err)
+	}
+
+	return WaitForHealthy(ctx, env, progressReporter)
+}
+
+// WaitForHealthy waits for the kube api server and then the control server
+// of an already running install to become healthy.
+func WaitForHealthy(ctx context.Context, env *installs.InstallEnv, progressReporter *util.ProgressReporter) error {
+	slog.Info("Connecting to cluster")
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+	defer kubeClient.Close()
+
+	if err := kubeClient.WaitForConnection(3 * time.Minute); err != nil {
+		return fmt.Errorf("cluster did not become ready: %w", err)
+	}
+
+	slog.Info("Waiting for control server")
+	if err := env.Spec.WaitForControlServer(ctx, kubeClient, progressReporter); err != nil {
+		return fmt.Errorf("control server did not become healthy: %w", err)
+	}
+
+	return nil
+}
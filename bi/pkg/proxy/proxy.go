@@ -0,0 +1,139 @@
+// Package proxy serves local SOCKS5 and HTTP proxies that make their
+// outbound connections through another network, eg. a userspace wireguard
+// tunnel.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+
+	"github.com/noisysockets/network"
+)
+
+// Server proxies connections using dial.
+type Server struct {
+	dial network.DialContextFunc
+	// forward proxies plain http requests, sharing one transport so that
+	// upstream connections are reused.
+	forward *httputil.ReverseProxy
+}
+
+func New(dial network.DialContextFunc) *Server {
+	return &Server{
+		dial: dial,
+		forward: &httputil.ReverseProxy{
+			// The outgoing URL is already the one requested.
+			Rewrite:   func(*httputil.ProxyRequest) {},
+			Transport: &http.Transport{DialContext: dial},
+		},
+	}
+}
+
+// ServeSOCKS5 accepts SOCKS5 connections on ln until it is closed or ctx is
+// done. Only the CONNECT command without authentication is supported, which
+// is what browsers and most CLIs use.
+func (s *Server) ServeSOCKS5(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			if err := s.handleSOCKS5(ctx, conn); err != nil {
+				slog.Debug("SOCKS5 connection failed", slog.String("remote", conn.RemoteAddr().String()), slog.Any("error", err))
+			}
+		}()
+	}
+}
+
+// ServeHTTP handles HTTP CONNECT requests (used for https) by tunneling the
+// connection and forwards plain http requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		s.handleConnect(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a proxy, requests must use an absolute URL", http.StatusBadRequest)
+		return
+	}
+
+	s.forward.ServeHTTP(w, r)
+}
+
+func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
+	upstream, err := s.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		slog.Debug("HTTP CONNECT failed", slog.String("host", r.Host), slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can't be hijacked", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		slog.Debug("HTTP CONNECT hijack failed", slog.Any("error", err))
+		return
+	}
+	defer conn.Close()
+
+	// Anything the client sent after the request is already buffered.
+	if n := buf.Reader.Buffered(); n > 0 {
+		if _, err := io.CopyN(upstream, buf, int64(n)); err != nil {
+			return
+		}
+	}
+
+	pipe(conn, upstream)
+}
+
+// pipe copies in both directions until either side is done.
+func pipe(a, b net.Conn) {
+	var once sync.Once
+	done := make(chan struct{})
+	closeBoth := func() {
+		once.Do(func() {
+			a.Close()
+			b.Close()
+			close(done)
+		})
+	}
+
+	go func() {
+		_, _ = io.Copy(a, b)
+		closeBoth()
+	}()
+
+	go func() {
+		_, _ = io.Copy(b, a)
+		closeBoth()
+	}()
+
+	<-done
+}
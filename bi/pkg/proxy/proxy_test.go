@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func echoServer(t *testing.T) (string, int) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func requireEcho(t *testing.T, conn net.Conn) {
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+}
+
+func TestServeSOCKS5(t *testing.T) {
+	_, port := echoServer(t)
+
+	var d net.Dialer
+	s := New(d.DialContext)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.ServeSOCKS5(ctx, ln) }()
+
+	tests := []struct {
+		name      string
+		addr      []byte
+		wantReply byte
+	}{
+		{
+			name:      "ipv4",
+			addr:      []byte{socks5AddrIPv4, 127, 0, 0, 1},
+			wantReply: socks5ReplySucceeded,
+		},
+		{
+			name:      "domain",
+			addr:      append([]byte{socks5AddrDomain, 9}, "localhost"...),
+			wantReply: socks5ReplySucceeded,
+		},
+		{
+			name:      "unsupported address type",
+			addr:      []byte{0x05},
+			wantReply: socks5ReplyAddressUnsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte{socks5Version, 1, socks5MethodNoAuth})
+			require.NoError(t, err)
+
+			method := make([]byte, 2)
+			_, err = io.ReadFull(conn, method)
+			require.NoError(t, err)
+			require.Equal(t, []byte{socks5Version, socks5MethodNoAuth}, method)
+
+			req := append([]byte{socks5Version, socks5CmdConnect, 0x00}, tt.addr...)
+			req = binary.BigEndian.AppendUint16(req, uint16(port))
+			_, err = conn.Write(req)
+			require.NoError(t, err)
+
+			reply := make([]byte, 10)
+			_, err = io.ReadFull(conn, reply)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantReply, reply[1])
+
+			if tt.wantReply == socks5ReplySucceeded {
+				requireEcho(t, conn)
+			}
+		})
+	}
+}
+
+func TestServeHTTPConnect(t *testing.T) {
+	host, port := echoServer(t)
+
+	var d net.Dialer
+	srv := httptest.NewServer(New(d.DialContext))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	target := net.JoinHostPort(host, strconv.Itoa(port))
+	_, err = fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	requireEcho(t, conn)
+}
+
+func TestServeHTTPForward(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "yes")
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	var mu sync.Mutex
+	var dialed []string
+	var d net.Dialer
+	srv := httptest.NewServer(New(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+		return d.DialContext(ctx, network, addr)
+	}))
+	defer srv.Close()
+
+	proxyURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	for range 2 {
+		resp, err := client.Get(upstream.URL + "/hello")
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "yes", resp.Header.Get("X-Upstream"))
+		require.Equal(t, "GET /hello", string(body))
+	}
+
+	// The upstream connection is reused across requests.
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{upstream.Listener.Addr().String()}, dialed)
+
+	resp, err := http.Get(srv.URL + "/hello")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// See RFC 1928.
+const (
+	socks5Version = 0x05
+
+	socks5MethodNoAuth       = 0x00
+	socks5MethodNoAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySucceeded          = 0x00
+	socks5ReplyGeneralFailure     = 0x01
+	socks5ReplyCommandUnsupported = 0x07
+	socks5ReplyAddressUnsupported = 0x08
+)
+
+func (s *Server) handleSOCKS5(ctx context.Context, conn net.Conn) error {
+	if err := socks5Handshake(conn); err != nil {
+		return err
+	}
+
+	addr, reply, err := socks5ReadRequest(conn)
+	if err != nil {
+		_ = socks5WriteReply(conn, reply)
+		return err
+	}
+
+	upstream, err := s.dial(ctx, "tcp", addr)
+	if err != nil {
+		_ = socks5WriteReply(conn, socks5ReplyGeneralFailure)
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer upstream.Close()
+
+	if err := socks5WriteReply(conn, socks5ReplySucceeded); err != nil {
+		return err
+	}
+
+	pipe(conn, upstream)
+
+	return nil
+}
+
+// socks5Handshake agrees on no authentication.
+func socks5Handshake(conn net.Conn) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("failed to read greeting: %w", err)
+	}
+
+	if header[0] != socks5Version {
+		return fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return fmt.Errorf("failed to read auth methods: %w", err)
+	}
+
+	for _, m := range methods {
+		if m == socks5MethodNoAuth {
+			_, err := conn.Write([]byte{socks5Version, socks5MethodNoAuth})
+			return err
+		}
+	}
+
+	_, _ = conn.Write([]byte{socks5Version, socks5MethodNoAcceptable})
+
+	return errors.New("client doesn't support connecting without authentication")
+}
+
+// socks5ReadRequest returns the address to connect to or the reply code to
+// reject the request with.
+func socks5ReadRequest(conn net.Conn) (string, byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", socks5ReplyGeneralFailure, fmt.Errorf("failed to read request: %w", err)
+	}
+
+	if header[1] != socks5CmdConnect {
+		return "", socks5ReplyCommandUnsupported, fmt.Errorf("unsupported command %d", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		size := net.IPv4len
+		if header[3] == socks5AddrIPv6 {
+			size = net.IPv6len
+		}
+
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", socks5ReplyGeneralFailure, fmt.Errorf("failed to read address: %w", err)
+		}
+		host = net.IP(ip).String()
+	case socks5AddrDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", socks5ReplyGeneralFailure, fmt.Errorf("failed to read domain length: %w", err)
+		}
+
+		domain := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", socks5ReplyGeneralFailure, fmt.Errorf("failed to read domain: %w", err)
+		}
+		host = string(domain)
+	default:
+		return "", socks5ReplyAddressUnsupported, fmt.Errorf("unsupported address type %d", header[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", socks5ReplyGeneralFailure, fmt.Errorf("failed to read port: %w", err)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), 0, nil
+}
+
+// socks5WriteReply replies with an unspecified bound address, which clients
+// don't use for CONNECT.
+func socks5WriteReply(conn net.Conn, reply byte) error {
+	_, err := conn.Write([]byte{socks5Version, reply, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
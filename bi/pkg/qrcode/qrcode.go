@@ -0,0 +1,80 @@
+// Package qrcode shows QR codes in a terminal, eg. so a wireguard config can
+// be scanned by the mobile apps. The encoding is done by go-qrcode.
+package qrcode
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// Code is an encoded QR code.
+type Code struct {
+	// Size is the width and height in modules.
+	Size int
+
+	modules [][]bool
+}
+
+// Encode returns the QR code for data, using low error correction so that
+// configs fit in smaller codes.
+func Encode(data []byte) (*Code, error) {
+	qr, err := goqrcode.New(string(data), goqrcode.Low)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	// WriteTerminal draws its own quiet zone.
+	qr.DisableBorder = true
+	modules := qr.Bitmap()
+
+	return &Code{Size: len(modules), modules: modules}, nil
+}
+
+// Module returns whether the module at x, y is dark.
+func (c *Code) Module(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// WriteTerminal draws the code with two modules per character using
+// explicit black and white so that it scans on light and dark terminals.
+func (c *Code) WriteTerminal(w io.Writer) error {
+	const (
+		quietZone = 2
+		reset     = "\x1b[0m"
+	)
+
+	dark := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+			return false
+		}
+		return c.modules[y][x]
+	}
+
+	color := func(isDark bool, fg bool) string {
+		switch {
+		case isDark && fg:
+			return "30"
+		case isDark:
+			return "40"
+		case fg:
+			return "97"
+		default:
+			return "107"
+		}
+	}
+
+	var sb strings.Builder
+	for y := -quietZone; y < c.Size+quietZone; y += 2 {
+		for x := -quietZone; x < c.Size+quietZone; x++ {
+			// The upper half block is drawn in the foreground colour.
+			fmt.Fprintf(&sb, "\x1b[%s;%sm▀", color(dark(x, y), true), color(dark(x, y+1), false))
+		}
+		sb.WriteString(reset + "\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,51 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		size   int
+		err    bool
+	}{
+		{name: "version 1", length: 17, size: 21},
+		{name: "wireguard config", length: 400, size: 69},
+		{name: "too long", length: 2954, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Encode(bytes.Repeat([]byte{'a'}, tt.length))
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.size, code.Size)
+
+			// Finder pattern corners without a border around them.
+			require.True(t, code.Module(0, 0))
+			require.True(t, code.Module(code.Size-1, 0))
+			require.True(t, code.Module(0, code.Size-1))
+			require.False(t, code.Module(7, 7))
+		})
+	}
+}
+
+func TestWriteTerminal(t *testing.T) {
+	code, err := Encode([]byte("hello"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, code.WriteTerminal(&buf))
+
+	// Two rows per line plus the quiet zone.
+	require.Equal(t, (code.Size+4+1)/2, strings.Count(buf.String(), "\n"))
+}
@@ -0,0 +1,49 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"bi/pkg/cluster/util"
+	"bi/pkg/installs"
+	"bi/pkg/log"
+	"bi/pkg/pause"
+)
+
+// SaveSnapshot pauses a local install, saves a snapshot of the cluster state
+// and then resumes it again.
+func SaveSnapshot(ctx context.Context, env *installs.InstallEnv, name string) error {
+	if err := pause.PauseInstall(ctx, env); err != nil {
+		return err
+	}
+
+	slog.Info("Saving snapshot", slog.String("slug", env.Slug), slog.String("name", name))
+
+	var saveErr error
+	if err := env.SaveSnapshot(ctx, name); err != nil {
+		saveErr = fmt.Errorf("unable to save snapshot: %w", err)
+	}
+
+	// Always try to bring the install back up, even if the save failed.
+	return errors.Join(saveErr, pause.ResumeInstall(ctx, env))
+}
+
+// RestoreSnapshot recreates a local install from a previously saved snapshot
+// and waits for it to become healthy.
+func RestoreSnapshot(ctx context.Context, env *installs.InstallEnv, name string) error {
+	slog.Info("Restoring snapshot", slog.String("slug", env.Slug), slog.String("name", name))
+
+	var progressReporter *util.ProgressReporter
+	if log.Level == slog.LevelWarn {
+		progressReporter = util.NewProgressReporter()
+		defer progressReporter.Shutdown()
+	}
+
+	if err := env.RestoreSnapshot(ctx, name, progressReporter); err != nil {
+		return fmt.Errorf("unable to restore snapshot: %w", err)
+	}
+
+	return pause.WaitForHealthy(ctx, env, progressReporter)
+}
@@ -67,3 +67,20 @@ func TestGetBatteryConfigField(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveBattery(t *testing.T) {
+	data, err := os.ReadFile("testdata/install_spec.json")
+	require.NoError(t, err)
+
+	spec, err := UnmarshalJSON(data)
+	require.NoError(t, err)
+
+	count := len(spec.TargetSummary.Batteries)
+
+	require.NoError(t, spec.RemoveBattery("cert_manager"))
+	require.False(t, spec.HasBatteryType("cert_manager"))
+	require.True(t, spec.HasBatteryType("battery_core"))
+	require.Len(t, spec.TargetSummary.Batteries, count-1)
+
+	require.Error(t, spec.RemoveBattery("cert_manager"))
+}
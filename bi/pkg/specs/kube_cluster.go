@@ -0,0 +1,47 @@
+package specs
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+const kubernetesVersionField = "kubernetes_version"
+
+// GetKubernetesVersion returns the requested kubernetes version from the
+// kube cluster config, or an empty string if none was requested.
+func (s *InstallSpec) GetKubernetesVersion() (string, error) {
+	v, ok := s.KubeCluster.Config[kubernetesVersionField]
+	if !ok || v == nil {
+		return "", nil
+	}
+
+	version, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected %s to be a string, got %T", kubernetesVersionField, v)
+	}
+	return version, nil
+}
+
+// SetKubernetesVersion overrides the requested kubernetes version.
+func (s *InstallSpec) SetKubernetesVersion(version string) {
+	if s.KubeCluster.Config == nil {
+		s.KubeCluster.Config = map[string]any{}
+	}
+	s.KubeCluster.Config[kubernetesVersionField] = version
+}
+
+// KubernetesMinorVersion returns 1.33 for any of 1.33, 1.33.4 or v1.33.4.
+func KubernetesMinorVersion(version string) string {
+	v := version
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+
+	if !semver.IsValid(v) {
+		return version
+	}
+
+	return strings.TrimPrefix(semver.MajorMinor(v), "v")
+}
@@ -1,6 +1,10 @@
 package specs
 
-import "log/slog"
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
 
 type KubeClusterSpec struct {
 	Provider string         `json:"provider"`
@@ -66,3 +70,16 @@ func (s *InstallSpec) HasBatteryType(batteryType string) bool {
 	}
 	return false
 }
+
+// RemoveBattery removes the battery of the given type from the target summary.
+func (s *InstallSpec) RemoveBattery(batteryType string) error {
+	if !s.HasBatteryType(batteryType) {
+		return fmt.Errorf("no battery with type: %s", batteryType)
+	}
+
+	s.TargetSummary.Batteries = slices.DeleteFunc(s.TargetSummary.Batteries, func(b BatterySpec) bool {
+		return b.Type == batteryType
+	})
+
+	return nil
+}
@@ -66,6 +66,45 @@ func (spec *InstallSpec) WaitForBootstrap(ctx context.Context, kubeClient kube.K
 
 	util.IncrementWithMessage(bootstrapBar, "Getting access info")
 
+	if err := spec.waitForControlServerHealth(ctx, kubeClient, ns, progressReporter); err != nil {
+		return err
+	}
+
+	util.IncrementWithMessage(bootstrapBar, "Control server accessible")
+	util.IncrementWithMessage(bootstrapBar, "Bootstrap complete")
+	util.SetTotalAndComplete(bootstrapBar)
+
+	return nil
+}
+
+// WaitForControlServer waits for an already bootstrapped control server to
+// become ready and healthy again (e.g. after the cluster has been restarted).
+func (spec *InstallSpec) WaitForControlServer(ctx context.Context, kubeClient kube.KubeClient, progressReporter *util.ProgressReporter) error {
+	usage, err := spec.GetCoreUsage()
+	if err != nil {
+		return fmt.Errorf("failed to determine if control server is running in cluster: %w", err)
+	}
+
+	if usage == "internal_dev" {
+		slog.Debug("control server not running in cluster, skipping wait")
+		return nil
+	}
+
+	ns, err := spec.GetCoreNamespace()
+	if err != nil {
+		return fmt.Errorf("failed to get core namespace: %w", err)
+	}
+
+	slog.Debug("Waiting for control server to be ready...")
+	if err := kubeClient.WatchFor(ctx, controlServerStatefulSetWatchOpts(ns)); err != nil {
+		return fmt.Errorf("failed to wait for control server: %w", err)
+	}
+
+	return spec.waitForControlServerHealth(ctx, kubeClient, ns, progressReporter)
+}
+
+// waitForControlServerHealth retries the control server health check until it succeeds.
+func (spec *InstallSpec) waitForControlServerHealth(ctx context.Context, kubeClient kube.KubeClient, ns string, progressReporter *util.ProgressReporter) error {
 	httpClient := getHTTPClient(spec, kubeClient)
 
 	// Create a separate progress bar for HTTP health check
@@ -78,7 +117,7 @@ func (spec *InstallSpec) WaitForBootstrap(ctx context.Context, kubeClient kube.K
 
 	// try to get cs url and connect, 10x
 	attemptCount := 0
-	err = retry.Do(func() error {
+	err := retry.Do(func() error {
 		attemptCount++
 		// get the access-info configmap (and URL information)
 		info, err := kubeClient.GetAccessInfo(ctx, ns)
@@ -114,14 +153,7 @@ func (spec *InstallSpec) WaitForBootstrap(ctx context.Context, kubeClient kube.K
 		return err
 	}, retry.Context(ctx))
 
-	if err == nil {
-		util.SetTotalAndComplete(healthBar)
-		util.IncrementWithMessage(bootstrapBar, "Control server accessible")
-		util.IncrementWithMessage(bootstrapBar, "Bootstrap complete")
-		util.SetTotalAndComplete(bootstrapBar)
-	} else {
-		util.SetTotalAndComplete(healthBar)
-	}
+	util.SetTotalAndComplete(healthBar)
 
 	return err
 }
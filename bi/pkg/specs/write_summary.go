@@ -25,17 +25,45 @@ func (spec *InstallSpec) WriteStateSummary(path string) error {
 }
 
 func (spec *InstallSpec) WriteSummaryToKube(ctx context.Context, kubeClient kube.KubeClient) error {
+	secret, err := spec.summarySecret()
+	if err != nil {
+		return err
+	}
+
+	if err := kubeClient.EnsureResourceExists(ctx, secret); err != nil {
+		return fmt.Errorf("unable to write state summary to cluster: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSummaryInKube replaces the state summary in the cluster, eg. after a
+// battery is uninstalled.
+func (spec *InstallSpec) UpdateSummaryInKube(ctx context.Context, kubeClient kube.KubeClient) error {
+	secret, err := spec.summarySecret()
+	if err != nil {
+		return err
+	}
+
+	if err := kubeClient.UpdateResource(ctx, secret); err != nil {
+		return fmt.Errorf("unable to update state summary in cluster: %w", err)
+	}
+
+	return nil
+}
+
+func (spec *InstallSpec) summarySecret() (map[string]any, error) {
 	contents, err := json.Marshal(spec.TargetSummary)
 	if err != nil {
-		return fmt.Errorf("unable to marshal state summary: %w", err)
+		return nil, fmt.Errorf("unable to marshal state summary: %w", err)
 	}
 
 	ns, err := spec.GetCoreNamespace()
 	if err != nil {
-		return fmt.Errorf("unable to find namespace: %w", err)
+		return nil, fmt.Errorf("unable to find namespace: %w", err)
 	}
 
-	if err := kubeClient.EnsureResourceExists(ctx, map[string]any{
+	return map[string]any{
 		"apiVersion": "v1",
 		"kind":       "Secret",
 		"metadata": map[string]any{
@@ -46,9 +74,5 @@ func (spec *InstallSpec) WriteSummaryToKube(ctx context.Context, kubeClient kube
 		"data": map[string]string{
 			"summary.json": base64.StdEncoding.EncodeToString(contents),
 		},
-	}); err != nil {
-		return fmt.Errorf("unable to write state summary to cluster: %w", err)
-	}
-
-	return nil
+	}, nil
 }
@@ -30,7 +30,7 @@ func StartInstall(ctx context.Context, env *installs.InstallEnv, skipBootstrap b
 	}
 
 	slog.Info("Connecting to cluster")
-	kubeClient, err := env.NewBatteryKubeClient()
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to create kube client: %w", err)
 	}
@@ -7,10 +7,23 @@ import (
 
 	"bi/pkg/cluster/util"
 	"bi/pkg/installs"
+	"bi/pkg/kube"
 	"bi/pkg/log"
 )
 
-func StopInstall(ctx context.Context, env *installs.InstallEnv, skipCleanKube bool) error {
+// StopInstall removes the install's kubernetes resources (unless skipped),
+// stops the kube provider and removes the install. A dry run only lists the
+// resources that would be removed.
+func StopInstall(ctx context.Context, env *installs.InstallEnv, skipCleanKube bool, cleanOpts kube.RemoveAllOptions) error {
+	if cleanOpts.DryRun {
+		if err := maybeCleanKube(ctx, env, skipCleanKube, cleanOpts); err != nil {
+			return fmt.Errorf("unable to list kubernetes resources: %w", err)
+		}
+
+		slog.Info("Dry run, the kube provider and install are left as they are")
+		return nil
+	}
+
 	slog.Info("Stopping kube provider")
 
 	var progressReporter *util.ProgressReporter
@@ -19,7 +32,8 @@ func StopInstall(ctx context.Context, env *installs.InstallEnv, skipCleanKube bo
 		defer progressReporter.Shutdown()
 	}
 
-	if err := maybeCleanKube(ctx, env, skipCleanKube); err != nil {
+	cleanOpts.ProgressReporter = progressReporter
+	if err := maybeCleanKube(ctx, env, skipCleanKube, cleanOpts); err != nil {
 		return fmt.Errorf("unable to clean up kubernetes resources: %w", err)
 	}
 
@@ -37,7 +51,7 @@ func StopInstall(ctx context.Context, env *installs.InstallEnv, skipCleanKube bo
 
 // maybeCleanKube conditionally deletes all k8s resources if the env needs cleanup and we're not skipping
 // will close the client before returning to prevent wireguard log spam
-func maybeCleanKube(ctx context.Context, env *installs.InstallEnv, skip bool) error {
+func maybeCleanKube(ctx context.Context, env *installs.InstallEnv, skip bool, opts kube.RemoveAllOptions) error {
 	needsCleanup := env.NeedsKubeCleanup()
 
 	if skip || !needsCleanup {
@@ -45,11 +59,11 @@ func maybeCleanKube(ctx context.Context, env *installs.InstallEnv, skip bool) er
 		return nil
 	}
 
-	kubeClient, err := env.NewBatteryKubeClient()
+	kubeClient, err := env.NewBatteryKubeClient(ctx)
 	if err != nil {
 		return err
 	}
 	defer kubeClient.Close()
 
-	return kubeClient.RemoveAll(ctx)
+	return kubeClient.RemoveAll(ctx, opts)
 }
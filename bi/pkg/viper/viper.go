@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/adrg/xdg"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
 )
 
 func SetupConfig(cfgFile string) error {
@@ -37,6 +40,9 @@ func SetupConfig(cfgFile string) error {
 	// Bind environment variables explicitly for better control
 	viper.BindEnv("allow-test-keys", "BI_ALLOW_TEST_KEYS")
 	viper.BindEnv("nvidia-auto-discovery", "BI_NVIDIA_AUTO_DISCOVERY")
+	viper.BindEnv("pulumi.backend", "BI_PULUMI_BACKEND")
+	viper.BindEnv("pulumi.passphrase", "BI_PULUMI_PASSPHRASE")
+	viper.BindEnv("pulumi.passphrase-file", "BI_PULUMI_PASSPHRASE_FILE")
 
 	// Set defaults
 	viper.SetDefault("allow-test-keys", false)
@@ -75,3 +81,69 @@ func SafeWriteConfig() error {
 func configPath() (string, error) {
 	return xdg.ConfigFile("bi")
 }
+
+// EKSConfig returns the user supplied overrides for the EKS pulumi config
+// from the eks section of bi.yaml.
+//
+// viper lower cases every key, including the label keys of node groups, so
+// the section is read from the config file as written when possible.
+func EKSConfig() map[string]any {
+	section, err := rawConfigSection(viper.ConfigFileUsed(), "eks")
+	if err != nil {
+		slog.Warn("Unable to read the eks config as written, keys will be lower case", slog.Any("error", err))
+	}
+	if section != nil {
+		return section
+	}
+
+	return viper.GetStringMap("eks")
+}
+
+// rawConfigSection returns the top level section of a yaml (or json) config
+// file without viper's key lower casing. It returns nil if there is no file
+// or section.
+func rawConfigSection(configFile string, key string) (map[string]any, error) {
+	if !slices.Contains([]string{".yaml", ".yml", ".json"}, strings.ToLower(filepath.Ext(configFile))) {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	var cfg map[string]any
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse config file: %w", err)
+	}
+
+	for k, v := range cfg {
+		if !strings.EqualFold(k, key) {
+			continue
+		}
+
+		section, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected %s to be a map, got %T", key, v)
+		}
+		return section, nil
+	}
+
+	return nil, nil
+}
+
+// PulumiBackendSettings are the pulumi state backend settings from the
+// pulumi section of bi.yaml (or the BI_PULUMI_* environment variables).
+type PulumiBackendSettings struct {
+	URL            string
+	Passphrase     string
+	PassphraseFile string
+}
+
+func PulumiBackend() PulumiBackendSettings {
+	return PulumiBackendSettings{
+		URL:            viper.GetString("pulumi.backend"),
+		Passphrase:     viper.GetString("pulumi.passphrase"),
+		PassphraseFile: viper.GetString("pulumi.passphrase-file"),
+	}
+}
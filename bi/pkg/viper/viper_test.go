@@ -0,0 +1,51 @@
+package viper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rawConfigSection(t *testing.T) {
+	dir := t.TempDir()
+
+	configFile := filepath.Join(dir, "bi.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+EKS:
+  cluster:
+    nodeGroups:
+      - name: gpu
+        minSize: 0
+        labels:
+          Example.com/GPU-Type: A100
+`), 0o600))
+
+	section, err := rawConfigSection(configFile, "eks")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"cluster": map[string]any{
+			"nodeGroups": []any{
+				map[string]any{
+					"name":    "gpu",
+					"minSize": 0,
+					"labels":  map[string]any{"Example.com/GPU-Type": "A100"},
+				},
+			},
+		},
+	}, section)
+
+	section, err = rawConfigSection(configFile, "pulumi")
+	require.NoError(t, err)
+	require.Nil(t, section)
+
+	section, err = rawConfigSection(filepath.Join(dir, "bi.toml"), "eks")
+	require.NoError(t, err)
+	require.Nil(t, section)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("eks: [1, 2]\n"), 0o600))
+	_, err = rawConfigSection(invalid, "eks")
+	require.ErrorContains(t, err, "expected eks to be a map")
+}
@@ -0,0 +1,237 @@
+package wireguard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"bi/pkg/qrcode"
+
+	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
+	noisysocketsv1alpha3 "github.com/noisysockets/noisysockets/config/v1alpha3"
+)
+
+// Format is a file format that a client config can be written in.
+type Format string
+
+const (
+	// FormatWGQuick is the wg-quick(8) config used by most WireGuard clients.
+	FormatWGQuick Format = "wg-quick"
+	// FormatNetworkManager is a NetworkManager keyfile, it can be copied to
+	// /etc/NetworkManager/system-connections.
+	FormatNetworkManager Format = "networkmanager"
+	// FormatYAML is the noisysockets config that bi uses itself.
+	FormatYAML Format = "yaml"
+	// FormatQR is the wg-quick config as a QR code drawn in the terminal, for
+	// the WireGuard mobile apps.
+	FormatQR Format = "qr"
+)
+
+// Formats is the list of supported formats.
+var Formats = []Format{FormatWGQuick, FormatNetworkManager, FormatYAML, FormatQR}
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	if !slices.Contains(Formats, Format(s)) {
+		return "", fmt.Errorf("unknown wireguard config format %q, must be one of %v", s, Formats)
+	}
+
+	return Format(s), nil
+}
+
+const defaultPersistentKeepalive = 25 * time.Second
+
+// ConvertConfig reads a noisysockets client config from r and writes it to w
+// in the given format. The name is used for the NetworkManager connection.
+func ConvertConfig(w io.Writer, r io.Reader, format Format, name string) error {
+	versionedConf, err := noisysocketsconfig.FromYAML(r)
+	if err != nil {
+		return fmt.Errorf("failed to read wireguard config: %w", err)
+	}
+
+	conf, ok := versionedConf.(*noisysocketsv1alpha3.Config)
+	if !ok {
+		return fmt.Errorf("unexpected wireguard config type: %T", versionedConf)
+	}
+
+	switch format {
+	case FormatWGQuick:
+		return writeWGQuick(w, conf)
+	case FormatNetworkManager:
+		return writeNetworkManager(w, conf, name)
+	case FormatYAML:
+		return noisysocketsconfig.ToYAML(w, conf)
+	case FormatQR:
+		var buf bytes.Buffer
+		if err := writeWGQuick(&buf, conf); err != nil {
+			return err
+		}
+
+		code, err := qrcode.Encode(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to encode wireguard config: %w", err)
+		}
+
+		return code.WriteTerminal(w)
+	default:
+		return fmt.Errorf("unknown wireguard config format %q", format)
+	}
+}
+
+// writeWGQuick writes the config for wg-quick. Unlike noisysockets' own INI
+// output the addresses have prefixes, there is no fixed listen port and the
+// DNS search domain is kept.
+func writeWGQuick(w io.Writer, conf *noisysocketsv1alpha3.Config) error {
+	var sb strings.Builder
+
+	sb.WriteString("[Interface]\n")
+	if conf.Name != "" {
+		fmt.Fprintf(&sb, "# Name = %s\n", conf.Name)
+	}
+	fmt.Fprintf(&sb, "PrivateKey = %s\n", conf.PrivateKey)
+	fmt.Fprintf(&sb, "Address = %s\n", strings.Join(hostPrefixes(conf.IPs), ", "))
+	if dns := dnsEntries(conf); len(dns) > 0 {
+		fmt.Fprintf(&sb, "DNS = %s\n", strings.Join(dns, ", "))
+	}
+	if conf.MTU != 0 {
+		fmt.Fprintf(&sb, "MTU = %d\n", conf.MTU)
+	}
+
+	for _, peer := range conf.Peers {
+		sb.WriteString("\n[Peer]\n")
+		if peer.Name != "" {
+			fmt.Fprintf(&sb, "# Name = %s\n", peer.Name)
+		}
+		fmt.Fprintf(&sb, "PublicKey = %s\n", peer.PublicKey)
+		if peer.Endpoint != "" {
+			fmt.Fprintf(&sb, "Endpoint = %s\n", peer.Endpoint)
+		}
+		fmt.Fprintf(&sb, "AllowedIPs = %s\n", strings.Join(allowedIPs(conf, peer), ", "))
+		fmt.Fprintf(&sb, "PersistentKeepalive = %d\n", int(persistentKeepalive(peer).Seconds()))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeNetworkManager writes a NetworkManager keyfile, see
+// nm-settings-keyfile(5). The connection isn't started automatically.
+func writeNetworkManager(w io.Writer, conf *noisysocketsv1alpha3.Config, name string) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "[connection]\nid=%s\ntype=wireguard\ninterface-name=%s\nautoconnect=false\n",
+		name, interfaceName(name))
+
+	sb.WriteString("\n[wireguard]\n")
+	fmt.Fprintf(&sb, "private-key=%s\n", conf.PrivateKey)
+	if conf.MTU != 0 {
+		fmt.Fprintf(&sb, "mtu=%d\n", conf.MTU)
+	}
+
+	for _, peer := range conf.Peers {
+		fmt.Fprintf(&sb, "\n[wireguard-peer.%s]\n", peer.PublicKey)
+		if peer.Endpoint != "" {
+			fmt.Fprintf(&sb, "endpoint=%s\n", peer.Endpoint)
+		}
+		fmt.Fprintf(&sb, "persistent-keepalive=%d\n", int(persistentKeepalive(peer).Seconds()))
+		fmt.Fprintf(&sb, "allowed-ips=%s;\n", strings.Join(allowedIPs(conf, peer), ";"))
+	}
+
+	sb.WriteString("\n[ipv4]\n")
+	for i, prefix := range hostPrefixes(conf.IPs) {
+		fmt.Fprintf(&sb, "address%d=%s\n", i+1, prefix)
+	}
+	if conf.DNS != nil && len(conf.DNS.Servers) > 0 {
+		var servers []string
+		for _, server := range conf.DNS.Servers {
+			servers = append(servers, netip.AddrPort(server).Addr().String())
+		}
+		fmt.Fprintf(&sb, "dns=%s;\n", strings.Join(servers, ";"))
+		if conf.DNS.Domain != "" {
+			fmt.Fprintf(&sb, "dns-search=%s;\n", conf.DNS.Domain)
+		}
+	}
+	sb.WriteString("method=manual\n")
+
+	sb.WriteString("\n[ipv6]\nmethod=ignore\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// allowedIPs returns the routes via the peer (eg. the VPC subnets) and the
+// peer's own addresses.
+func allowedIPs(conf *noisysocketsv1alpha3.Config, peer noisysocketsv1alpha3.PeerConfig) []string {
+	var prefixes []netip.Prefix
+	for _, route := range conf.Routes {
+		if route.Via == peer.Name {
+			prefixes = append(prefixes, route.Destination)
+		}
+	}
+
+	for _, addr := range peer.IPs {
+		if !slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+
+	result := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		result = append(result, prefix.String())
+	}
+	return result
+}
+
+// dnsEntries returns the nameservers followed by the search domain, which is
+// how wg-quick tells them apart.
+func dnsEntries(conf *noisysocketsv1alpha3.Config) []string {
+	if conf.DNS == nil {
+		return nil
+	}
+
+	var entries []string
+	for _, server := range conf.DNS.Servers {
+		entries = append(entries, netip.AddrPort(server).Addr().String())
+	}
+	if conf.DNS.Domain != "" {
+		entries = append(entries, conf.DNS.Domain)
+	}
+	return entries
+}
+
+func hostPrefixes(addrs []netip.Addr) []string {
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, netip.PrefixFrom(addr, addr.BitLen()).String())
+	}
+	return result
+}
+
+func persistentKeepalive(peer noisysocketsv1alpha3.PeerConfig) time.Duration {
+	if peer.PersistentKeepalive != nil {
+		return *peer.PersistentKeepalive
+	}
+	return defaultPersistentKeepalive
+}
+
+// interfaceName returns a valid linux interface name for the connection,
+// they are limited to 15 characters.
+func interfaceName(name string) string {
+	const maxLen = 15
+
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+
+	if len(name) > maxLen {
+		name = name[:maxLen]
+	}
+	return name
+}
@@ -0,0 +1,146 @@
+package wireguard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
+	noisysocketsv1alpha3 "github.com/noisysockets/noisysockets/config/v1alpha3"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `apiVersion: noisysockets.github.com/v1alpha2
+kind: Config
+name: installer
+privateKey: 6MZ6W8Mj3HnCuO6qUGVLKlVVMb3Zyl8bTP3zKgeu0X8=
+ips:
+  - 100.64.250.2
+dns:
+  servers:
+    - 10.0.0.2
+routes:
+  - destination: 10.0.0.0/16
+    via: gateway
+peers:
+  - name: gateway
+    publicKey: Y4PhTAF5KeBeONlbPu6cI0QLLzBRrHdsRUpH5I6mMzs=
+    endpoint: 203.0.113.10:51820
+    ips:
+      - 100.64.250.1
+`
+
+func TestConvertConfig(t *testing.T) {
+	tests := []struct {
+		format   Format
+		contains []string
+	}{
+		{
+			format: FormatWGQuick,
+			contains: []string{
+				"Address = 100.64.250.2/32\n",
+				"DNS = 10.0.0.2\n",
+				"AllowedIPs = 10.0.0.0/16, 100.64.250.1/32\n",
+				"Endpoint = 203.0.113.10:51820\n",
+				"PersistentKeepalive = 25\n",
+			},
+		},
+		{
+			format: FormatNetworkManager,
+			contains: []string{
+				"id=bi-example-installer\n",
+				"interface-name=bi-example-inst\n",
+				"[wireguard-peer.Y4PhTAF5KeBeONlbPu6cI0QLLzBRrHdsRUpH5I6mMzs=]\n",
+				"allowed-ips=10.0.0.0/16;100.64.250.1/32;\n",
+				"address1=100.64.250.2/32\n",
+				"dns=10.0.0.2;\n",
+			},
+		},
+		{
+			format:   FormatYAML,
+			contains: []string{"privateKey: 6MZ6W8Mj3HnCuO6qUGVLKlVVMb3Zyl8bTP3zKgeu0X8=\n"},
+		},
+		{
+			format:   FormatQR,
+			contains: []string{"▀"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, ConvertConfig(&buf, strings.NewReader(testConfig), tt.format, "bi-example-installer"))
+
+			for _, s := range tt.contains {
+				require.Contains(t, buf.String(), s)
+			}
+		})
+	}
+
+	_, err := ParseFormat("ini")
+	require.Error(t, err)
+}
+
+// The wg-quick output replaced noisysockets' own INI output, importing
+// either must give the same config apart from the listen port and MTU,
+// which wg-quick leaves to the system.
+func TestWriteWGQuickMatchesINI(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "installer", config: testConfig},
+		{
+			name: "two peers",
+			config: `apiVersion: noisysockets.github.com/v1alpha2
+kind: Config
+name: laptop
+privateKey: 6MZ6W8Mj3HnCuO6qUGVLKlVVMb3Zyl8bTP3zKgeu0X8=
+mtu: 1380
+ips:
+  - 100.64.250.5
+dns:
+  servers:
+    - 10.0.0.2
+    - 10.0.0.3
+routes:
+  - destination: 10.0.0.0/16
+    via: gateway
+  - destination: 10.1.0.0/16
+    via: gateway
+peers:
+  - name: gateway
+    publicKey: Y4PhTAF5KeBeONlbPu6cI0QLLzBRrHdsRUpH5I6mMzs=
+    endpoint: 203.0.113.10:51820
+    ips:
+      - 100.64.250.1
+  - name: runner
+    publicKey: 2Vg6/VcWcx6wXMT4lZ3XE6c6h6nE8aA37b1aX5Oo8TQ=
+    ips:
+      - 100.64.250.3
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versionedConf, err := noisysocketsconfig.FromYAML(strings.NewReader(tt.config))
+			require.NoError(t, err)
+			conf := versionedConf.(*noisysocketsv1alpha3.Config)
+
+			var wgQuick, noisysocketsINI bytes.Buffer
+			require.NoError(t, writeWGQuick(&wgQuick, conf))
+			require.NoError(t, noisysocketsconfig.ToINI(&noisysocketsINI, conf))
+
+			fromINI := func(b *bytes.Buffer) *noisysocketsv1alpha3.Config {
+				versionedConf, err := noisysocketsconfig.FromINI(b)
+				require.NoError(t, err)
+				conf := versionedConf.(*noisysocketsv1alpha3.Config)
+				conf.ListenPort, conf.MTU = 0, 0
+				return conf
+			}
+
+			require.Equal(t, fromINI(&noisysocketsINI), fromINI(&wgQuick))
+		})
+	}
+}
@@ -1,13 +1,14 @@
 package wireguard
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/netip"
+	"slices"
 	"strings"
 
-	"github.com/apparentlymart/go-cidr/cidr"
 	noisysocketsconfig "github.com/noisysockets/noisysockets/config"
 	noisysocketsv1alpha2 "github.com/noisysockets/noisysockets/config/v1alpha2"
 	noisysocketstypes "github.com/noisysockets/noisysockets/types"
@@ -44,15 +45,21 @@ func NewGateway(listenPort uint16, subnet *net.IPNet) (*Gateway, error) {
 		return nil, fmt.Errorf("failed to generate private key: %w", err)
 	}
 
-	// First address in the subnet is reserved for the gateway.
-	firstAddress, _ := cidr.AddressRange(subnet)
+	prefix, err := subnetPrefix(subnet)
+	if err != nil {
+		return nil, err
+	}
 
-	// The zero address is reserved in ipv4.
-	if v4 := firstAddress.To4(); v4 != nil && v4[3] == 0 {
-		firstAddress = cidr.Inc(firstAddress)
+	ipam, err := NewIPAM(prefix)
+	if err != nil {
+		return nil, err
 	}
 
-	address, _ := netip.AddrFromSlice(firstAddress)
+	// First address in the subnet is reserved for the gateway.
+	address, err := ipam.First()
+	if err != nil {
+		return nil, err
+	}
 
 	return &Gateway{
 		ListenPort: listenPort,
@@ -63,25 +70,27 @@ func NewGateway(listenPort uint16, subnet *net.IPNet) (*Gateway, error) {
 }
 
 func (gw *Gateway) NewClient(name string) (*Client, error) {
+	if _, ok := gw.Client(name); ok {
+		return nil, fmt.Errorf("client %s already exists", name)
+	}
+
 	// Generate a new key pair for the client.
 	privateKey, err := noisysocketstypes.NewPrivateKey()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate private key: %w", err)
 	}
 
-	// by default, the first address after gw address
-	nextAddress := cidr.Inc(net.IP(gw.Address.AsSlice()))
-	// if there are clients, use the next address after the last client
-	if len(gw.Clients) > 0 {
-		nextAddress = cidr.Inc(net.IP(gw.Clients[len(gw.Clients)-1].Address.AsSlice()))
+	ipam, err := gw.ipam()
+	if err != nil {
+		return nil, err
 	}
 
-	if !gw.Subnet.Contains(nextAddress) {
-		return nil, fmt.Errorf("no more addresses available in subnet")
+	// The addresses of removed clients are reused.
+	address, err := ipam.Allocate(name)
+	if err != nil {
+		return nil, err
 	}
 
-	address, _ := netip.AddrFromSlice(nextAddress)
-
 	c := Client{
 		Gateway:    gw,
 		Name:       name,
@@ -94,6 +103,97 @@ func (gw *Gateway) NewClient(name string) (*Client, error) {
 	return &c, nil
 }
 
+// Client returns the client with the given name.
+func (gw *Gateway) Client(name string) (*Client, bool) {
+	idx := slices.IndexFunc(gw.Clients, func(c Client) bool { return c.Name == name })
+	if idx == -1 {
+		return nil, false
+	}
+
+	return &gw.Clients[idx], true
+}
+
+// ipam returns the allocations of the clients' addresses. The clients are
+// what is saved, so it is built from them each time.
+func (gw *Gateway) ipam() (*IPAM, error) {
+	prefix, err := subnetPrefix(gw.Subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	ipam, err := NewIPAM(prefix, gw.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range gw.Clients {
+		if err := ipam.Restore(c.Name, c.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	return ipam, nil
+}
+
+func subnetPrefix(subnet *net.IPNet) (netip.Prefix, error) {
+	if subnet == nil {
+		return netip.Prefix{}, errors.New("gateway has no subnet")
+	}
+
+	addr, ok := netip.AddrFromSlice(subnet.IP)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet %s", subnet)
+	}
+
+	ones, _ := subnet.Mask.Size()
+
+	return netip.PrefixFrom(addr.Unmap(), ones), nil
+}
+
+// RemoveClient removes the client so it can no longer connect once the
+// gateway is reconfigured.
+func (gw *Gateway) RemoveClient(name string) error {
+	idx := slices.IndexFunc(gw.Clients, func(c Client) bool { return c.Name == name })
+	if idx == -1 {
+		return fmt.Errorf("client %s not found", name)
+	}
+
+	gw.Clients = slices.Delete(gw.Clients, idx, idx+1)
+
+	return nil
+}
+
+// RotateKey replaces the gateway's private key. Every client needs a new
+// config with the new public key once the gateway is reconfigured.
+func (gw *Gateway) RotateKey() error {
+	privateKey, err := noisysocketstypes.NewPrivateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	gw.PrivateKey = privateKey.String()
+
+	return nil
+}
+
+// RotateClientKey replaces the client's private key, keeping its address.
+// The old key stops working once the gateway is reconfigured.
+func (gw *Gateway) RotateClientKey(name string) (*Client, error) {
+	client, ok := gw.Client(name)
+	if !ok {
+		return nil, fmt.Errorf("client %s not found", name)
+	}
+
+	privateKey, err := noisysocketstypes.NewPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	client.PrivateKey = privateKey.String()
+
+	return client, nil
+}
+
 func (gw *Gateway) WriteConfig(w io.Writer) error {
 	conf := &noisysocketsv1alpha2.Config{
 		Name:       "gateway",
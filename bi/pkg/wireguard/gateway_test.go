@@ -0,0 +1,73 @@
+package wireguard
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayClients(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("100.64.250.0/24")
+	require.NoError(t, err)
+
+	gw, err := NewGateway(51820, subnet)
+	require.NoError(t, err)
+
+	for _, name := range []string{"installer", "alice", "bob"} {
+		_, err := gw.NewClient(name)
+		require.NoError(t, err)
+	}
+
+	_, err = gw.NewClient("alice")
+	require.ErrorContains(t, err, "client alice already exists")
+
+	// Revoked addresses are reused.
+	require.NoError(t, gw.RemoveClient("alice"))
+	carol, err := gw.NewClient("carol")
+	require.NoError(t, err)
+	require.Equal(t, "100.64.250.3", carol.Address.String())
+
+	require.Error(t, gw.RemoveClient("alice"))
+
+	path := filepath.Join(t.TempDir(), "wireguard-gateway.json")
+	require.NoError(t, gw.Save(path))
+
+	loaded, err := LoadGateway(path)
+	require.NoError(t, err)
+	require.Equal(t, gw.PrivateKey, loaded.PrivateKey)
+	require.Equal(t, gw.Subnet.String(), loaded.Subnet.String())
+	require.Len(t, loaded.Clients, 3)
+
+	bob, ok := loaded.Client("bob")
+	require.True(t, ok)
+	require.Equal(t, "100.64.250.4", bob.Address.String())
+	require.Same(t, loaded, bob.Gateway)
+}
+
+func TestGatewayRotateKeys(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("100.64.250.0/24")
+	require.NoError(t, err)
+
+	gw, err := NewGateway(51820, subnet)
+	require.NoError(t, err)
+
+	alice, err := gw.NewClient("alice")
+	require.NoError(t, err)
+
+	oldGatewayKey := gw.PrivateKey
+	require.NoError(t, gw.RotateKey())
+	require.NotEqual(t, oldGatewayKey, gw.PrivateKey)
+
+	rotated, err := gw.RotateClientKey("alice")
+	require.NoError(t, err)
+	require.NotEqual(t, alice.PrivateKey, rotated.PrivateKey)
+	require.Equal(t, alice.Address, rotated.Address)
+
+	saved, _ := gw.Client("alice")
+	require.Equal(t, rotated.PrivateKey, saved.PrivateKey)
+
+	_, err = gw.RotateClientKey("bob")
+	require.ErrorContains(t, err, "client bob not found")
+}
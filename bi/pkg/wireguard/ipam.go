@@ -0,0 +1,133 @@
+package wireguard
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// ErrSubnetExhausted is returned when every address in the subnet is in use.
+var ErrSubnetExhausted = errors.New("no more addresses available in subnet")
+
+// IPAM allocates the addresses in a subnet by name. A name keeps its address
+// until it is released, after which the address is reused.
+type IPAM struct {
+	subnet      netip.Prefix
+	reserved    map[netip.Addr]bool
+	allocations map[string]netip.Addr
+}
+
+// NewIPAM returns an IPAM for the subnet with the reserved addresses (eg. the
+// gateway's) never allocated.
+func NewIPAM(subnet netip.Prefix, reserved ...netip.Addr) (*IPAM, error) {
+	if !subnet.IsValid() {
+		return nil, fmt.Errorf("invalid subnet %s", subnet)
+	}
+
+	ipam := &IPAM{
+		subnet:      subnet.Masked(),
+		reserved:    make(map[netip.Addr]bool),
+		allocations: make(map[string]netip.Addr),
+	}
+
+	for _, addr := range reserved {
+		if !ipam.subnet.Contains(addr) {
+			return nil, fmt.Errorf("reserved address %s is not in subnet %s", addr, ipam.subnet)
+		}
+		ipam.reserved[addr] = true
+	}
+
+	return ipam, nil
+}
+
+// First returns the first usable address in the subnet.
+func (ipam *IPAM) First() (netip.Addr, error) {
+	for addr := ipam.subnet.Addr(); ipam.subnet.Contains(addr); addr = addr.Next() {
+		if ipam.usable(addr) {
+			return addr, nil
+		}
+	}
+
+	return netip.Addr{}, fmt.Errorf("%w: %s has no usable addresses", ErrSubnetExhausted, ipam.subnet)
+}
+
+// Allocate returns the address of name, allocating the lowest free address
+// if it doesn't have one.
+func (ipam *IPAM) Allocate(name string) (netip.Addr, error) {
+	if addr, ok := ipam.allocations[name]; ok {
+		return addr, nil
+	}
+
+	used := make(map[netip.Addr]bool, len(ipam.allocations))
+	for _, addr := range ipam.allocations {
+		used[addr] = true
+	}
+
+	usable := 0
+	for addr := ipam.subnet.Addr(); ipam.subnet.Contains(addr); addr = addr.Next() {
+		if !ipam.usable(addr) || ipam.reserved[addr] {
+			continue
+		}
+		usable++
+
+		if !used[addr] {
+			ipam.allocations[name] = addr
+			return addr, nil
+		}
+	}
+
+	return netip.Addr{}, fmt.Errorf("%w: %s has %d addresses for clients and all are allocated",
+		ErrSubnetExhausted, ipam.subnet, usable)
+}
+
+// Restore records an existing allocation, eg. one loaded from saved state.
+func (ipam *IPAM) Restore(name string, addr netip.Addr) error {
+	switch {
+	case !ipam.subnet.Contains(addr) || !ipam.usable(addr):
+		return fmt.Errorf("address %s of %s is not usable in subnet %s", addr, name, ipam.subnet)
+	case ipam.reserved[addr]:
+		return fmt.Errorf("address %s of %s is reserved", addr, name)
+	}
+
+	for other, otherAddr := range ipam.allocations {
+		if otherAddr == addr && other != name {
+			return fmt.Errorf("address %s of %s is already allocated to %s", addr, name, other)
+		}
+	}
+
+	ipam.allocations[name] = addr
+
+	return nil
+}
+
+// Release frees the address of name so it can be allocated again.
+func (ipam *IPAM) Release(name string) error {
+	if _, ok := ipam.allocations[name]; !ok {
+		return fmt.Errorf("no address allocated to %s", name)
+	}
+
+	delete(ipam.allocations, name)
+
+	return nil
+}
+
+// Lookup returns the address allocated to name.
+func (ipam *IPAM) Lookup(name string) (netip.Addr, bool) {
+	addr, ok := ipam.allocations[name]
+	return addr, ok
+}
+
+// usable reports whether addr can be given out at all. The network address
+// is never used, nor is the broadcast address in ipv4, except in /31 and /32
+// subnets which don't have them (RFC 3021).
+func (ipam *IPAM) usable(addr netip.Addr) bool {
+	if ipam.subnet.Bits() >= addr.BitLen()-1 {
+		return true
+	}
+
+	if addr == ipam.subnet.Addr() {
+		return false
+	}
+
+	return !addr.Is4() || ipam.subnet.Contains(addr.Next())
+}
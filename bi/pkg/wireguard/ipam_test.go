@@ -0,0 +1,141 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAMAllocate(t *testing.T) {
+	addrs := func(ss ...string) []netip.Addr {
+		var out []netip.Addr
+		for _, s := range ss {
+			out = append(out, netip.MustParseAddr(s))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		subnet   string
+		reserved []netip.Addr
+		// allocated are restored before allocating
+		allocated map[string]string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:     "/24 after the gateway",
+			subnet:   "100.64.250.0/24",
+			reserved: addrs("100.64.250.1"),
+			want:     []string{"100.64.250.2", "100.64.250.3"},
+		},
+		{
+			name:      "/24 reuses freed addresses",
+			subnet:    "100.64.250.0/24",
+			reserved:  addrs("100.64.250.1"),
+			allocated: map[string]string{"installer": "100.64.250.2", "bob": "100.64.250.4"},
+			want:      []string{"100.64.250.3", "100.64.250.5"},
+		},
+		{
+			name:     "/24 is exhausted before the broadcast address",
+			subnet:   "100.64.250.0/24",
+			reserved: addrs("100.64.250.1"),
+			allocated: func() map[string]string {
+				m := map[string]string{}
+				for a := netip.MustParseAddr("100.64.250.2"); a.As4()[3] != 255; a = a.Next() {
+					m[a.String()] = a.String()
+				}
+				return m
+			}(),
+			wantErr: true,
+		},
+		{
+			name:   "/29 unmasked",
+			subnet: "100.64.250.13/29",
+			want:   []string{"100.64.250.9", "100.64.250.10"},
+		},
+		{
+			name:     "/29 full",
+			subnet:   "100.64.250.8/29",
+			reserved: addrs("100.64.250.9"),
+			want:     []string{"100.64.250.10", "100.64.250.11", "100.64.250.12", "100.64.250.13", "100.64.250.14"},
+			wantErr:  true,
+		},
+		{
+			name:     "/30 has room for one client",
+			subnet:   "100.64.250.4/30",
+			reserved: addrs("100.64.250.5"),
+			want:     []string{"100.64.250.6"},
+			wantErr:  true,
+		},
+		{
+			name:     "/31 has no network or broadcast address",
+			subnet:   "100.64.250.4/31",
+			reserved: addrs("100.64.250.4"),
+			want:     []string{"100.64.250.5"},
+			wantErr:  true,
+		},
+		{
+			name:     "/32 only has the gateway",
+			subnet:   "100.64.250.4/32",
+			reserved: addrs("100.64.250.4"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipam, err := NewIPAM(netip.MustParsePrefix(tt.subnet), tt.reserved...)
+			require.NoError(t, err)
+
+			for name, addr := range tt.allocated {
+				require.NoError(t, ipam.Restore(name, netip.MustParseAddr(addr)))
+			}
+
+			for i, want := range tt.want {
+				got, err := ipam.Allocate(string(rune('a' + i)))
+				require.NoError(t, err)
+				require.Equal(t, want, got.String())
+			}
+
+			_, err = ipam.Allocate("last")
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrSubnetExhausted)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIPAMByName(t *testing.T) {
+	ipam, err := NewIPAM(netip.MustParsePrefix("100.64.250.0/24"), netip.MustParseAddr("100.64.250.1"))
+	require.NoError(t, err)
+
+	alice, err := ipam.Allocate("alice")
+	require.NoError(t, err)
+
+	// Allocating again keeps the address.
+	again, err := ipam.Allocate("alice")
+	require.NoError(t, err)
+	require.Equal(t, alice, again)
+
+	require.ErrorContains(t, ipam.Restore("bob", alice), "already allocated to alice")
+	require.ErrorContains(t, ipam.Restore("bob", netip.MustParseAddr("100.64.250.1")), "is reserved")
+	require.ErrorContains(t, ipam.Restore("bob", netip.MustParseAddr("100.64.250.255")), "not usable")
+	require.ErrorContains(t, ipam.Restore("bob", netip.MustParseAddr("10.0.0.1")), "not usable")
+
+	require.NoError(t, ipam.Release("alice"))
+	require.Error(t, ipam.Release("alice"))
+	_, ok := ipam.Lookup("alice")
+	require.False(t, ok)
+
+	bob, err := ipam.Allocate("bob")
+	require.NoError(t, err)
+	require.Equal(t, alice, bob)
+
+	_, err = NewIPAM(netip.MustParsePrefix("100.64.250.0/24"), netip.MustParseAddr("10.0.0.1"))
+	require.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package wireguard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+)
+
+// gatewayState is what is saved of a gateway, the keys and addresses. The
+// rest (endpoint, nameservers, routes) depends on where the gateway is
+// running and is filled in when it is used.
+type gatewayState struct {
+	ListenPort uint16        `json:"listenPort"`
+	Address    netip.Addr    `json:"address"`
+	Subnet     string        `json:"subnet"`
+	PrivateKey string        `json:"privateKey"`
+	Clients    []clientState `json:"clients"`
+}
+
+type clientState struct {
+	Name       string     `json:"name"`
+	PrivateKey string     `json:"privateKey"`
+	Address    netip.Addr `json:"address"`
+}
+
+// LoadGateway reads a gateway saved by Save. The error wraps os.ErrNotExist
+// if it hasn't been saved.
+func LoadGateway(path string) (*Gateway, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wireguard gateway state: %w", err)
+	}
+
+	var state gatewayState
+	if err := json.Unmarshal(bs, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse wireguard gateway state %s: %w", path, err)
+	}
+
+	_, subnet, err := net.ParseCIDR(state.Subnet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse wireguard gateway subnet: %w", err)
+	}
+
+	gw := &Gateway{
+		ListenPort: state.ListenPort,
+		Address:    state.Address,
+		Subnet:     subnet,
+		PrivateKey: state.PrivateKey,
+	}
+
+	for _, c := range state.Clients {
+		gw.Clients = append(gw.Clients, Client{
+			Gateway:    gw,
+			Name:       c.Name,
+			PrivateKey: c.PrivateKey,
+			Address:    c.Address,
+		})
+	}
+
+	// Catches clients sharing an address or outside the subnet.
+	if _, err := gw.ipam(); err != nil {
+		return nil, fmt.Errorf("invalid wireguard gateway state %s: %w", path, err)
+	}
+
+	return gw, nil
+}
+
+// Save writes the gateway keys and clients, including their private keys,
+// to path so that the same configs keep working.
+func (gw *Gateway) Save(path string) error {
+	state := gatewayState{
+		ListenPort: gw.ListenPort,
+		Address:    gw.Address,
+		Subnet:     gw.Subnet.String(),
+		PrivateKey: gw.PrivateKey,
+	}
+
+	for _, c := range gw.Clients {
+		state.Clients = append(state.Clients, clientState{
+			Name:       c.Name,
+			PrivateKey: c.PrivateKey,
+			Address:    c.Address,
+		})
+	}
+
+	bs, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal wireguard gateway state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create wireguard gateway state directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, bs, 0o600); err != nil {
+		return fmt.Errorf("failed to write wireguard gateway state: %w", err)
+	}
+
+	return nil
+}